@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"car-rental/internal/config"
+	"car-rental/internal/events"
+	"car-rental/internal/logging"
+	"car-rental/internal/metrics"
+	"car-rental/internal/models"
+	"car-rental/internal/payments"
+	"car-rental/internal/repository"
+	"car-rental/internal/service"
+	"car-rental/internal/tracing"
+	"car-rental/internal/webhooks"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const tracerShutdownTimeout = 5 * time.Second
+
+// app holds the repositories and the service shared by the server and the operator commands.
+type app struct {
+	db                   *gorm.DB
+	autoRepository       *repository.AutoRepositoryImpl
+	rentalRepository     *repository.RentalRepositoryImpl
+	commissionRepository *repository.CommissionRepositoryImpl
+	outboxRepository     *repository.OutboxRepositoryImpl
+	webhookRepository    *repository.WebhookRepositoryImpl
+	discountRepository   *repository.DiscountRepositoryImpl
+	seasonRepository     *repository.SeasonRepositoryImpl
+	extraRepository      *repository.ExtraRepositoryImpl
+	insuranceRepository  *repository.InsuranceRepositoryImpl
+	invoiceService       *service.InvoiceServiceImpl
+	loyaltyService       *service.LoyaltyServiceImpl
+	transactor           *repository.GormTransactor
+	rentalService        *service.RentalServiceImpl
+	metrics              *metrics.Metrics
+	logger               *slog.Logger
+	tracerProvider       *tracing.Provider
+	// bus publishes the domain events to subscribers in the process
+	bus *events.Bus
+}
+
+func newApp(ctx context.Context, cfg config.Config) (*app, error) {
+	logger, err := newLogger(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDatabase(ctx, cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	tracerProvider, err := tracing.NewProvider(ctx, cfg.Tracing.TracerOptions())
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Use(tracing.NewGormPlugin(tracerProvider.Tracer())); err != nil {
+		return nil, err
+	}
+	autoRepository := repository.NewAutoRepositoryImpl(db)
+	rentalRepository := repository.NewRentalRepositoryImpl(db, logger)
+	commissionRepository := repository.NewCommissionRepositoryImpl(db, logger)
+	outboxRepository := repository.NewOutboxRepositoryImpl(db)
+	webhookRepository := repository.NewWebhookRepositoryImpl(db)
+	discountRepository := repository.NewDiscountRepositoryImpl(db)
+	seasonRepository := repository.NewSeasonRepositoryImpl(db)
+	extraRepository := repository.NewExtraRepositoryImpl(db)
+	insuranceRepository := repository.NewInsuranceRepositoryImpl(db)
+	transactor := repository.NewGormTransactor(db)
+	invoiceService := service.NewInvoiceServiceImpl(
+		repository.NewInvoiceRepositoryImpl(db), transactor, cfg.Invoices.Settings(), logger)
+	taxes, err := cfg.Tax.TaxRates()
+	if err != nil {
+		return nil, err
+	}
+	demand, err := cfg.Pricing.DemandLevels()
+	if err != nil {
+		return nil, err
+	}
+	program, err := cfg.Loyalty.Program()
+	if err != nil {
+		return nil, err
+	}
+	loyaltyService := service.NewLoyaltyServiceImpl(
+		repository.NewLoyaltyRepositoryImpl(db), transactor, program, logger)
+	options := []service.Option{
+		service.WithEvents(transactor, outboxRepository), service.WithInvoices(invoiceService),
+		service.WithTaxes(taxes), service.WithDiscounts(discountRepository),
+		service.WithSeasons(seasonRepository), service.WithDemand(demand), service.WithExtras(extraRepository),
+		service.WithInsurance(insuranceRepository),
+	}
+	if program.Enabled() {
+		options = append(options, service.WithLoyalty(loyaltyService))
+	}
+	if cfg.Payments.Provider == payments.ProviderMemory {
+		options = append(options, service.WithPayments(transactor, payments.NewMemory(),
+			repository.NewPaymentRepositoryImpl(db)))
+	}
+	appMetrics := metrics.New(rentalRepository.CountActiveRentsByAutoType)
+	if err = db.Use(appMetrics.GormPlugin()); err != nil {
+		return nil, err
+	}
+	return &app{
+		db:                   db,
+		autoRepository:       autoRepository,
+		rentalRepository:     rentalRepository,
+		commissionRepository: commissionRepository,
+		outboxRepository:     outboxRepository,
+		webhookRepository:    webhookRepository,
+		discountRepository:   discountRepository,
+		seasonRepository:     seasonRepository,
+		extraRepository:      extraRepository,
+		insuranceRepository:  insuranceRepository,
+		invoiceService:       invoiceService,
+		loyaltyService:       loyaltyService,
+		transactor:           transactor,
+		rentalService: service.NewRentalServiceImpl(autoRepository, rentalRepository, commissionRepository,
+			append(options, service.WithMetrics(appMetrics), service.WithLogger(logger),
+				service.WithTracer(tracerProvider.Tracer()))...),
+		metrics:        appMetrics,
+		logger:         logger,
+		tracerProvider: tracerProvider,
+		bus:            events.NewBus(),
+	}, nil
+}
+
+// newDispatcher publishes the outbox to the bus, queues the deliveries of the partner webhooks and
+// publishes to the broker and webhook of the config.
+func (a *app) newDispatcher(cfg config.EventsConfig) *events.Dispatcher {
+	sinks := []events.Sink{a.bus, webhooks.NewFanout(a.webhookRepository)}
+	if cfg.Broker == events.BrokerStdout {
+		sinks = append(sinks, events.NewBrokerSink(events.NewWriterBroker(os.Stdout), cfg.SubjectPrefix))
+	}
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, events.NewWebhookSink(cfg.WebhookURL, cfg.WebhookTimeout.Std()))
+	}
+	return events.NewDispatcher(a.transactor, a.outboxRepository, cfg.DispatcherOptions(), a.logger, sinks...)
+}
+
+// close exports the spans still buffered and closes the database.
+func (a *app) close() {
+	ctx, cancel := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+	defer cancel()
+	if err := a.tracerProvider.Shutdown(ctx); err != nil {
+		a.logger.Warn("exporting spans failed", slog.Any("error", err))
+	}
+	if sqlDB, err := a.db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+}
+
+// newLogger writes the logs to stderr, keeping stdout for the output of the operator commands.
+func newLogger(cfg config.Config) (*slog.Logger, error) {
+	return logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+}
+
+// openDatabase connects with the pool limits of the config, retrying for database.connect_timeout.
+// SQL is logged at the debug level.
+func openDatabase(ctx context.Context, cfg config.Config, logger *slog.Logger) (*gorm.DB, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg.Database.ConnectTimeout.Std())
+	defer cancel()
+	db, err := models.ConnectDatabaseWithRetry(ctx, cfg.Database.ConnectionString(), logger)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime.Std())
+
+	gin.SetMode(gin.ReleaseMode)
+	if cfg.LogLevel == config.LogLevelDebug {
+		gin.SetMode(gin.DebugMode)
+	}
+	return db, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"car-rental/internal/config"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+)
+
+const autosUsage = `usage: car-rental autos <command>
+
+commands:
+  list [flags]              list the autos with their daily price
+  add [flags]               add an auto to the fleet
+  retire <auto-id>          take an auto that isn't rented out of the fleet`
+
+func runAutos(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(autosUsage)
+	}
+	switch args[0] {
+	case "list":
+		return listAutos(ctx, cfg, args[1:])
+	case "add":
+		return addAuto(ctx, cfg, args[1:])
+	case "retire":
+		if len(args) != 2 {
+			return errors.New(autosUsage)
+		}
+		return retireAuto(ctx, cfg, args[1])
+	}
+	return errors.New(autosUsage)
+}
+
+func listAutos(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("autos list", flag.ContinueOnError)
+	query := repository.AutoQuery{}
+	flags.StringVar(&query.Type, "type", "", "only autos of the type")
+	flags.StringVar(&query.Status, "status", "", "available, rented or retired, all but retired by default")
+	flags.StringVar(&query.Location, "location", "", "only autos at the location")
+	flags.IntVar(&query.Limit, "limit", 100, "maximum number of autos to list")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	autos, next, err := app.rentalService.ListAutos(ctx, query)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tSTATUS\tLOCATION\tPLATE\tDAILY")
+	for _, auto := range autos {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+			auto.ID, auto.Name, auto.Type, auto.Status(), auto.Location, auto.Plate, auto.DailyPrice)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if next != nil {
+		fmt.Printf("more than %d autos, raise -limit to see them all\n", query.Limit)
+	}
+	return nil
+}
+
+func addAuto(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("autos add", flag.ContinueOnError)
+	auto := models.Auto{Availability: true}
+	var features string
+	flags.StringVar(&auto.ID, "id", "", "id of the auto, generated when empty")
+	flags.StringVar(&auto.Name, "name", "", "display name (required)")
+	flags.StringVar(&auto.Type, "type", "", "auto type (required)")
+	flags.StringVar(&auto.Location, "location", "", "location the auto is rented from")
+	flags.StringVar(&auto.VIN, "vin", "", "vehicle identification number")
+	flags.StringVar(&auto.Plate, "plate", "", "licence plate")
+	flags.StringVar(&auto.Make, "make", "", "manufacturer")
+	flags.StringVar(&auto.Model, "model", "", "model")
+	flags.IntVar(&auto.Year, "year", 0, "model year")
+	flags.IntVar(&auto.Seats, "seats", 0, "number of seats")
+	flags.StringVar(&auto.FuelType, "fuel", "", "petrol, diesel, electric or hybrid")
+	flags.StringVar(&auto.Transmission, "transmission", "", "manual or automatic")
+	flags.StringVar(&features, "features", "", "comma separated feature tags")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if auto.Name == "" || auto.Type == "" {
+		return errors.New("-name and -type are required")
+	}
+	fuelTypes := []string{models.FuelTypePetrol, models.FuelTypeDiesel, models.FuelTypeElectric, models.FuelTypeHybrid}
+	if auto.FuelType != "" && !slices.Contains(fuelTypes, auto.FuelType) {
+		return fmt.Errorf("-fuel must be one of %s", strings.Join(fuelTypes, ", "))
+	}
+	transmissions := []string{models.TransmissionManual, models.TransmissionAutomatic}
+	if auto.Transmission != "" && !slices.Contains(transmissions, auto.Transmission) {
+		return fmt.Errorf("-transmission must be one of %s", strings.Join(transmissions, ", "))
+	}
+	if features != "" {
+		auto.Features = strings.Split(features, ",")
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	autoTypes, err := app.rentalService.GetAutoTypes(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(autoTypes, auto.Type) {
+		return fmt.Errorf("unknown auto type %q, known types are %s", auto.Type, strings.Join(autoTypes, ", "))
+	}
+	auto, err = app.autoRepository.CreateAuto(ctx, auto)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("added auto %s (%s)\n", auto.ID, auto.Name)
+	return nil
+}
+
+func retireAuto(ctx context.Context, cfg config.Config, autoId string) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if err = app.rentalService.RetireAuto(ctx, autoId); err != nil {
+		return fmt.Errorf("can't retire auto %s: %w", autoId, err)
+	}
+	fmt.Printf("retired auto %s\n", autoId)
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"car-rental/internal/config"
+	"car-rental/internal/models"
+)
+
+const extrasUsage = `usage: car-rental extras <command>
+
+commands:
+  list                              list the extras and their stock per location
+  add [flags] <extra-id> <name>     add an extra to the catalogue, or replace it
+  stock <extra-id> <location> <N>   set the stock of an extra at a location
+
+add flags:
+  -price N                          price of the extra per rent day (required)
+  -per-rental                       charge the price once per rent instead
+  -stocked                          limit the extra to its stock at the location of the auto`
+
+func runExtras(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(extrasUsage)
+	}
+	switch args[0] {
+	case "list":
+		return listExtras(ctx, cfg)
+	case "add":
+		return addExtra(ctx, cfg, args[1:])
+	case "stock":
+		if len(args) != 4 {
+			return errors.New(extrasUsage)
+		}
+		quantity, err := strconv.Atoi(args[3])
+		if err != nil || quantity < 0 {
+			return errors.New("stock must be a number, 0 or more")
+		}
+		return setExtraStock(ctx, cfg, models.ExtraStock{ExtraID: args[1], Location: args[2], Quantity: quantity})
+	}
+	return errors.New(extrasUsage)
+}
+
+func listExtras(ctx context.Context, cfg config.Config) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	extras, err := app.extraRepository.FindExtras(ctx)
+	if err != nil {
+		return err
+	}
+	stock, err := app.extraRepository.FindStock(ctx, "")
+	if err != nil {
+		return err
+	}
+	locations := map[string][]string{}
+	for _, s := range stock {
+		locations[s.ExtraID] = append(locations[s.ExtraID], s.Location+":"+strconv.Itoa(s.Quantity))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPRICE\tSTOCK")
+	for _, extra := range extras {
+		stocked := "unlimited"
+		if extra.Stocked {
+			stocked = strings.Join(locations[extra.ID], ",")
+			if stocked == "" {
+				stocked = "none"
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d per %s\t%s\n", extra.ID, extra.Name, extra.Price, extra.Pricing, stocked)
+	}
+	return w.Flush()
+}
+
+func addExtra(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("extras add", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Println(extrasUsage) }
+	extra := models.Extra{Pricing: models.ExtraPricingDay}
+	flags.IntVar(&extra.Price, "price", 0, "price per rent day, or per rent with -per-rental")
+	perRental := flags.Bool("per-rental", false, "charge the price once per rent")
+	flags.BoolVar(&extra.Stocked, "stocked", false, "limit the extra to its stock at the location of the auto")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		return errors.New(extrasUsage)
+	}
+	if extra.Price <= 0 {
+		return errors.New("-price must be positive")
+	}
+	if *perRental {
+		extra.Pricing = models.ExtraPricingRental
+	}
+	extra.ID, extra.Name = flags.Arg(0), strings.Join(flags.Args()[1:], " ")
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if extra, err = app.extraRepository.SaveExtra(ctx, extra); err != nil {
+		return err
+	}
+	fmt.Printf("saved extra %s, %d per %s\n", extra.ID, extra.Price, extra.Pricing)
+	return nil
+}
+
+func setExtraStock(ctx context.Context, cfg config.Config, stock models.ExtraStock) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if _, err = app.extraRepository.GetExtra(ctx, stock.ExtraID); err != nil {
+		return fmt.Errorf("extra %s: %w", stock.ExtraID, err)
+	}
+	if err = app.extraRepository.SetStock(ctx, stock); err != nil {
+		return err
+	}
+	fmt.Printf("%s has %d at %s\n", stock.ExtraID, stock.Quantity, stock.Location)
+	return nil
+}
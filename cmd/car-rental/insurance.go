@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"car-rental/internal/config"
+	"car-rental/internal/models"
+)
+
+const insuranceUsage = `usage: car-rental insurance <command>
+
+commands:
+  list <auto-type>                          list the insurance packages of the type, the cheapest first
+  set [flags] <auto-type> <code> <name>     add an insurance package to the type, or replace it
+  delete <auto-type> <code>                 delete a package, the rents bound with it keep their price
+
+set flags:
+  -daily-price N                            price of the package per rent day
+  -rental-price N                           price of the package once per rent, on top of the days
+                                            (one of the prices is required)
+  -excess N                                 most of the repair cost of a damage charged, 0 covers all of it`
+
+func runInsurance(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(insuranceUsage)
+	}
+	switch args[0] {
+	case "list":
+		if len(args) != 2 {
+			return errors.New(insuranceUsage)
+		}
+		return listInsurance(ctx, cfg, args[1])
+	case "set":
+		return setInsurance(ctx, cfg, args[1:])
+	case "delete":
+		if len(args) != 3 {
+			return errors.New(insuranceUsage)
+		}
+		return deleteInsurance(ctx, cfg, args[1], args[2])
+	}
+	return errors.New(insuranceUsage)
+}
+
+func listInsurance(ctx context.Context, cfg config.Config, autoType string) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	packages, err := app.insuranceRepository.FindPackages(ctx, autoType)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CODE\tNAME\tDAILY PRICE\tRENTAL PRICE\tEXCESS")
+	for _, insurance := range packages {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\n",
+			insurance.Code, insurance.Name, insurance.DailyPrice, insurance.RentalPrice, insurance.Excess)
+	}
+	return w.Flush()
+}
+
+func setInsurance(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("insurance set", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Println(insuranceUsage) }
+	var insurance models.InsurancePackage
+	flags.IntVar(&insurance.DailyPrice, "daily-price", 0, "price of the package per rent day")
+	flags.IntVar(&insurance.RentalPrice, "rental-price", 0, "price of the package once per rent")
+	flags.IntVar(&insurance.Excess, "excess", 0, "most of the repair cost of a damage charged")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 3 {
+		return errors.New(insuranceUsage)
+	}
+	if insurance.DailyPrice < 0 || insurance.RentalPrice < 0 {
+		return errors.New("-daily-price and -rental-price must not be negative")
+	}
+	if insurance.DailyPrice == 0 && insurance.RentalPrice == 0 {
+		return errors.New("-daily-price or -rental-price must be positive")
+	}
+	if insurance.Excess < 0 {
+		return errors.New("-excess must not be negative")
+	}
+	insurance.AutoType, insurance.Code = flags.Arg(0), flags.Arg(1)
+	insurance.Name = strings.Join(flags.Args()[2:], " ")
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if err = app.insuranceRepository.SetPackage(ctx, insurance); err != nil {
+		return err
+	}
+	fmt.Printf("%s insurance of %s set to %d a day and %d a rent with an excess of %d\n",
+		insurance.Code, insurance.AutoType, insurance.DailyPrice, insurance.RentalPrice, insurance.Excess)
+	return nil
+}
+
+func deleteInsurance(ctx context.Context, cfg config.Config, autoType string, code string) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if err = app.insuranceRepository.DeletePackage(ctx, autoType, code); err != nil {
+		return fmt.Errorf("can't delete insurance %s of %s: %w", code, autoType, err)
+	}
+	fmt.Printf("deleted insurance %s of %s\n", code, autoType)
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"car-rental/internal/config"
+)
+
+const loyaltyUsage = `usage: car-rental loyalty <command>
+
+commands:
+  show <client-id>                    show the balance, tier and latest entries of a client
+  adjust <client-id> <points> <note>  add points to a client, or take them off with negative points`
+
+const loyaltyEntriesShown = 20
+
+func runLoyalty(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(loyaltyUsage)
+	}
+	switch args[0] {
+	case "show":
+		if len(args) != 2 {
+			return errors.New(loyaltyUsage)
+		}
+		return showLoyalty(ctx, cfg, args[1])
+	case "adjust":
+		if len(args) < 4 {
+			return errors.New(loyaltyUsage)
+		}
+		points, err := strconv.Atoi(args[2])
+		if err != nil || points == 0 {
+			return errors.New("points must be a number other than 0")
+		}
+		return adjustLoyalty(ctx, cfg, args[1], points, strings.Join(args[3:], " "))
+	}
+	return errors.New(loyaltyUsage)
+}
+
+func showLoyalty(ctx context.Context, cfg config.Config, clientId string) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	account, err := app.loyaltyService.Account(ctx, clientId, loyaltyEntriesShown)
+	if err != nil {
+		return err
+	}
+	tier := account.Tier.Name
+	if tier == "" {
+		tier = "none"
+	}
+	fmt.Printf("client %s: balance %d points, %d earned, tier %s\n", clientId, account.Balance, account.Earned, tier)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tKIND\tPOINTS\tRENT\tEXPIRES\tNOTE")
+	for _, entry := range account.Entries {
+		rent, expires := "-", "-"
+		if entry.RentID != nil {
+			rent = strconv.Itoa(*entry.RentID)
+		}
+		if entry.ExpiresAt != nil {
+			expires = entry.ExpiresAt.Format(time.DateOnly)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%+d\t%s\t%s\t%s\n", entry.CreatedAt.Format(time.DateOnly), entry.Kind, entry.Points,
+			rent, expires, entry.Note)
+	}
+	return w.Flush()
+}
+
+func adjustLoyalty(ctx context.Context, cfg config.Config, clientId string, points int, note string) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if _, err = app.loyaltyService.Adjust(ctx, clientId, points, note); err != nil {
+		return err
+	}
+	fmt.Printf("adjusted the points of client %s by %+d\n", clientId, points)
+	return nil
+}
@@ -1,44 +1,238 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
+	"car-rental/internal/auth"
+	"car-rental/internal/config"
 	"car-rental/internal/controller"
-	"car-rental/internal/models"
+	"car-rental/internal/health"
+	"car-rental/internal/migrations"
 	"car-rental/internal/repository"
 	"car-rental/internal/router"
 	"car-rental/internal/service"
-	"car-rental/internal/utils"
+	"car-rental/internal/webhooks"
 )
 
+const usage = `usage: car-rental [flags] [command]
+
+commands:
+  serve     start the HTTP server, the default without a command
+  migrate   apply or roll back schema migrations
+  autos     list, add and retire autos
+  pricing   show and set the commissions and discounts of an auto type
+  promo     list and add promo codes
+  seasons   list, add and delete the seasons pricing the days of a rent
+  extras    list and add the extras rented with the autos and set their stock
+  insurance list, set and delete the insurance packages of an auto type
+  loyalty   show and adjust the loyalty points of a client
+  rentals   list and close rents
+  quote     price a rent without binding the auto
+
+Run car-rental -h for the flags and car-rental <command> -h for the options of a command.`
+
 func main() {
-	dsn := utils.GetDsnFromEnv()
-	db, err := models.ConnectDatabase(dsn)
+	flags := flag.NewFlagSet("car-rental", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage+"\n\nflags:")
+		flags.PrintDefaults()
+	}
+	loader := config.NewLoader(flags)
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		exit(err)
+	}
+	command, args := "serve", flags.Args()
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	if command == "help" {
+		flags.Usage()
+		return
+	}
+	cfg, err := loader.Load()
+	if err != nil {
+		exit(err)
+	}
+	// the first SIGINT or SIGTERM cancels ctx, a second one kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	switch command {
+	case "serve":
+		err = serve(ctx, cfg)
+	case "migrate":
+		err = runMigrate(ctx, cfg, args)
+	case "autos":
+		err = runAutos(ctx, cfg, args)
+	case "pricing":
+		err = runPricing(ctx, cfg, args)
+	case "promo":
+		err = runPromo(ctx, cfg, args)
+	case "seasons":
+		err = runSeasons(ctx, cfg, args)
+	case "extras":
+		err = runExtras(ctx, cfg, args)
+	case "insurance":
+		err = runInsurance(ctx, cfg, args)
+	case "loyalty":
+		err = runLoyalty(ctx, cfg, args)
+	case "rentals":
+		err = runRentals(ctx, cfg, args)
+	case "quote":
+		err = runQuote(ctx, cfg, args)
+	default:
+		err = errors.New(usage)
+	}
+	stop()
+	exit(err)
+}
 
-	autoRepository := repository.NewAutoRepositoryImpl(db)
-	commissionRepository := repository.NewCommissionRepositoryImpl(db)
-	rentalRepository := repository.NewRentalRepositoryImpl(db)
+func exit(err error) {
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
 
-	rentalService := service.NewRentalServiceImpl(autoRepository, rentalRepository, commissionRepository)
-	rentalController := controller.NewRentalController(rentalService)
-	routes := router.NewRouter(*rentalController)
+// serve runs the HTTP server until ctx is cancelled, then fails readiness, waits http.shutdown_delay
+// and gives in-flight requests http.shutdown_timeout to finish.
+func serve(ctx context.Context, cfg config.Config) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	sqlDB, err := app.db.DB()
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.NewMigrator(app.db)
+	if err != nil {
+		return err
+	}
+	checker := health.NewChecker()
+	checker.Add("database", sqlDB.PingContext)
+	checker.Add("migrations", func(ctx context.Context) error {
+		version, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+		if version != migrator.Latest() {
+			return fmt.Errorf("schema at version %d, want %d", version, migrator.Latest())
+		}
+		return nil
+	})
 
-	port := os.Getenv("port")
-	if port == "" {
-		port = "8080"
+	if err = controller.RegisterValidations(); err != nil {
+		return err
+	}
+	idempotencyRepository := repository.NewIdempotencyRepositoryImpl(app.db)
+	rentalController := controller.NewRentalController(app.rentalService, app.logger)
+	authConfig, err := cfg.Auth.Credentials()
+	if err != nil {
+		return err
+	}
+	authenticator, err := auth.NewAuthenticator(authConfig)
+	if err != nil {
+		return err
 	}
+	webhookController := controller.NewWebhookController(
+		service.NewWebhookServiceImpl(app.webhookRepository, app.logger), app.logger)
+	routes := router.NewRouter(router.Controllers{
+		Rental:  *rentalController,
+		Health:  *controller.NewHealthController(checker),
+		Webhook: *webhookController,
+		Invoice: *controller.NewInvoiceController(app.invoiceService, app.logger),
+		Loyalty: *controller.NewLoyaltyController(app.loyaltyService, app.logger),
+	}, authenticator, idempotencyRepository, app.metrics, app.logger, app.tracerProvider.Tracer(), router.Features{
+		LegacyRentals:  cfg.Features.LegacyRentals,
+		OpenAPI:        cfg.Features.OpenAPI,
+		Metrics:        cfg.Features.Metrics,
+		IdempotencyTTL: cfg.HTTP.IdempotencyTTL.Std(),
+	})
+
 	server := &http.Server{
-		Addr:           ":" + port,
+		Addr:           cfg.HTTP.Addr,
 		Handler:        routes,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		MaxHeaderBytes: 1 << 20,
+		ReadTimeout:    cfg.HTTP.ReadTimeout.Std(),
+		WriteTimeout:   cfg.HTTP.WriteTimeout.Std(),
+		IdleTimeout:    cfg.HTTP.IdleTimeout.Std(),
+		MaxHeaderBytes: cfg.HTTP.MaxHeaderBytes,
+		ErrorLog:       slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
 	}
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		app.newDispatcher(cfg.Events).Run(workersCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		webhooks.NewDeliverer(app.webhookRepository, cfg.Webhooks.DelivererOptions(), app.logger).Run(workersCtx)
+	}()
+	go func() {
+		defer workers.Done()
+		deleteExpiredIdempotencyKeys(workersCtx, idempotencyRepository, app.logger)
+	}()
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	app.logger.Info("listening", slog.String("addr", cfg.HTTP.Addr))
 
-	err = server.ListenAndServe()
-	if err != nil {
-		panic(err)
+	select {
+	case err = <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	app.logger.Info("shutting down", slog.Duration("drain_timeout", cfg.HTTP.ShutdownTimeout.Std()))
+	checker.Drain()
+	time.Sleep(cfg.HTTP.ShutdownDelay.Std())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout.Std())
+	defer cancel()
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shutting down: %w", err)
+	}
+	// events and deliveries still pending are sent by the next start, or by another instance
+	stopWorkers()
+	workers.Wait()
+	if err = <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// deleteExpiredIdempotencyKeys deletes the expired Idempotency-Keys every hour until ctx is cancelled.
+func deleteExpiredIdempotencyKeys(ctx context.Context, repo repository.IdempotencyRepository, logger *slog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		deleted, err := repo.DeleteExpiredKeys(ctx, time.Now())
+		if err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "deleting expired idempotency keys failed", slog.Any("error", err))
+		}
+		if deleted > 0 {
+			logger.DebugContext(ctx, "expired idempotency keys deleted", slog.Int64("count", deleted))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"car-rental/internal/config"
+	"car-rental/internal/migrations"
+)
+
+const migrateUsage = `usage: car-rental migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down          roll back the last applied migration
+  status        list migrations and whether they are applied
+  to <version>  migrate up or down to the version
+  seed          insert the demo auto types, autos, commissions and thresholds`
+
+func runMigrate(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	logger, err := newLogger(cfg)
+	if err != nil {
+		return err
+	}
+	db, err := openDatabase(ctx, cfg, logger)
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down()
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("version must be a number: %w", convErr)
+		}
+		err = migrator.To(version)
+	case "seed":
+		return migrator.Seed()
+	case "status":
+		return printMigrationStatus(migrator)
+	default:
+		return errors.New(migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema is at version %d of %d\n", version, migrator.Latest())
+	return nil
+}
+
+func printMigrationStatus(migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, state)
+	}
+	return w.Flush()
+}
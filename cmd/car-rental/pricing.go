@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"car-rental/internal/config"
+	"car-rental/internal/models"
+)
+
+const pricingUsage = `usage: car-rental pricing <command>
+
+commands:
+  show <auto-type>                               list the commissions, rent days limits and discounts of the type
+  set [-min-threshold N] <auto-type> <commission> <value>
+                                                 replace a commission of the type
+  tier <auto-type> <min-days> <percent>          discount the rents booked for min-days or more, 0 removes the tier`
+
+func runPricing(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(pricingUsage)
+	}
+	switch args[0] {
+	case "show":
+		if len(args) != 2 {
+			return errors.New(pricingUsage)
+		}
+		return showPricing(ctx, cfg, args[1])
+	case "set":
+		return setPricing(ctx, cfg, args[1:])
+	case "tier":
+		if len(args) != 4 {
+			return errors.New(pricingUsage)
+		}
+		return setDiscountTier(ctx, cfg, args[1], args[2], args[3])
+	}
+	return errors.New(pricingUsage)
+}
+
+func showPricing(ctx context.Context, cfg config.Config, autoType string) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	commissions := app.commissionRepository.GetCommissionsByType(ctx, autoType)
+	if len(commissions) == 0 {
+		return fmt.Errorf("no commissions found for auto type %q", autoType)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMISSION\tVALUE\tMIN THRESHOLD")
+	for _, commission := range commissions {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", commission.Type, commission.Value, commission.MinThreshold)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	threshold, err := app.rentalRepository.GetThresholdsByAutoType(ctx, autoType)
+	if err != nil {
+		return err
+	}
+	if threshold != (models.RentThreshold{}) {
+		fmt.Printf("\nrent days: %d to %d\n", threshold.MinThreshold, threshold.MaxThreshold)
+	}
+	tiers, err := app.discountRepository.GetDiscountTiers(ctx, autoType)
+	if err != nil {
+		return err
+	}
+	if len(tiers) > 0 {
+		fmt.Println("\nlong rental discounts:")
+	}
+	for _, tier := range tiers {
+		fmt.Printf("  %d%% from %d days\n", tier.Percent, tier.MinDays)
+	}
+	return nil
+}
+
+func setPricing(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("pricing set", flag.ContinueOnError)
+	minThreshold := flags.Int("min-threshold", 0, "days the commission starts to apply from, used by penalty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 3 {
+		return errors.New(pricingUsage)
+	}
+	if flags.Arg(1) == "insurance" {
+		return errors.New("insurance is sold in packages, set them with car-rental insurance set")
+	}
+	value, err := strconv.Atoi(flags.Arg(2))
+	if err != nil {
+		return fmt.Errorf("value must be a number: %w", err)
+	}
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	commission := models.Commission{
+		AutoType:     flags.Arg(0),
+		Type:         flags.Arg(1),
+		Value:        value,
+		MinThreshold: *minThreshold,
+	}
+	if err = app.rentalService.SetCommission(ctx, commission); err != nil {
+		return err
+	}
+	fmt.Printf("%s commission of %s set to %d\n", commission.Type, commission.AutoType, commission.Value)
+	return nil
+}
+
+func setDiscountTier(ctx context.Context, cfg config.Config, autoType string, minDays string, percent string) error {
+	tier := models.DiscountTier{AutoType: autoType}
+	var err error
+	if tier.MinDays, err = strconv.Atoi(minDays); err != nil || tier.MinDays <= 0 {
+		return errors.New("min-days must be a positive number")
+	}
+	if tier.Percent, err = strconv.Atoi(percent); err != nil || tier.Percent < 0 || tier.Percent > 100 {
+		return errors.New("percent must be a number from 0 to 100")
+	}
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if err = app.discountRepository.SetDiscountTier(ctx, tier); err != nil {
+		return err
+	}
+	if tier.Percent == 0 {
+		fmt.Printf("discount of %s from %d days removed\n", tier.AutoType, tier.MinDays)
+		return nil
+	}
+	fmt.Printf("%d%% off %s from %d days\n", tier.Percent, tier.AutoType, tier.MinDays)
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"car-rental/internal/config"
+	"car-rental/internal/models"
+)
+
+const promoUsage = `usage: car-rental promo <command>
+
+commands:
+  list                      list the promo codes and their uses
+  add [flags] <code>        add a promo code
+
+add flags:
+  -percent N                take N percent off the rent days
+  -fixed N                  take N off the rent days
+  -from YYYY-MM-DD          first day the code is valid, valid right away by default
+  -until YYYY-MM-DD         day the code expires, valid indefinitely by default
+  -max-uses N               rents the code applies to, unlimited by default
+  -auto-types a,b           auto types the code is valid for, every type by default`
+
+func runPromo(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(promoUsage)
+	}
+	switch args[0] {
+	case "list":
+		return listPromoCodes(ctx, cfg)
+	case "add":
+		return addPromoCode(ctx, cfg, args[1:])
+	}
+	return errors.New(promoUsage)
+}
+
+func listPromoCodes(ctx context.Context, cfg config.Config) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	promos, err := app.discountRepository.FindPromoCodes(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CODE\tDISCOUNT\tFROM\tEXPIRES\tUSES\tAUTO TYPES")
+	for _, promo := range promos {
+		discount := strconv.Itoa(promo.Value)
+		if promo.Kind == models.PromoKindPercent {
+			discount += "%"
+		}
+		uses := strconv.Itoa(promo.Uses)
+		if promo.MaxUses > 0 {
+			uses += "/" + strconv.Itoa(promo.MaxUses)
+		}
+		autoTypes := strings.Join(promo.AutoTypes, ",")
+		if autoTypes == "" {
+			autoTypes = "all"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", promo.Code, discount, formatPromoDate(promo.ValidFrom),
+			formatPromoDate(promo.ValidUntil), uses, autoTypes)
+	}
+	return w.Flush()
+}
+
+func formatPromoDate(date *time.Time) string {
+	if date == nil {
+		return "-"
+	}
+	return date.Format(time.DateOnly)
+}
+
+func addPromoCode(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("promo add", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Println(promoUsage) }
+	percent := flags.Int("percent", 0, "percent taken off the rent days")
+	fixed := flags.Int("fixed", 0, "amount taken off the rent days")
+	from := flags.String("from", "", "first day the code is valid")
+	until := flags.String("until", "", "day the code expires")
+	promo := models.PromoCode{}
+	flags.IntVar(&promo.MaxUses, "max-uses", 0, "rents the code applies to, 0 doesn't limit")
+	autoTypes := flags.String("auto-types", "", "comma separated auto types the code is valid for")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(promoUsage)
+	}
+	promo.Code = flags.Arg(0)
+	switch {
+	case (*percent == 0) == (*fixed == 0):
+		return errors.New("one of -percent and -fixed is required")
+	case *percent != 0:
+		if *percent < 0 || *percent > 100 {
+			return errors.New("-percent must be from 1 to 100")
+		}
+		promo.Kind, promo.Value = models.PromoKindPercent, *percent
+	default:
+		if *fixed < 0 {
+			return errors.New("-fixed must be positive")
+		}
+		promo.Kind, promo.Value = models.PromoKindFixed, *fixed
+	}
+	if promo.MaxUses < 0 {
+		return errors.New("-max-uses must not be negative")
+	}
+	var err error
+	if promo.ValidFrom, err = parsePromoDate("-from", *from); err != nil {
+		return err
+	}
+	if promo.ValidUntil, err = parsePromoDate("-until", *until); err != nil {
+		return err
+	}
+	if promo.ValidFrom != nil && promo.ValidUntil != nil && !promo.ValidUntil.After(*promo.ValidFrom) {
+		return errors.New("-until must be after -from")
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if *autoTypes != "" {
+		promo.AutoTypes = strings.Split(*autoTypes, ",")
+		known, err := app.rentalService.GetAutoTypes(ctx)
+		if err != nil {
+			return err
+		}
+		for _, autoType := range promo.AutoTypes {
+			if !slices.Contains(known, autoType) {
+				return fmt.Errorf("unknown auto type %q, known types are %s", autoType, strings.Join(known, ", "))
+			}
+		}
+	}
+	if promo, err = app.discountRepository.CreatePromoCode(ctx, promo); err != nil {
+		return err
+	}
+	fmt.Printf("added promo code %s\n", promo.Code)
+	return nil
+}
+
+// parsePromoDate returns nil for an empty flag.
+func parsePromoDate(flagName string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be YYYY-MM-DD: %w", flagName, err)
+	}
+	return &date, nil
+}
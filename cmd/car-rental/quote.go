@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"car-rental/internal/config"
+	"car-rental/internal/service"
+)
+
+const quoteUsage = `usage: car-rental quote [flags] <auto-id> <days>
+
+Prices a rent of the auto without binding it.
+
+flags:
+  -start YYYY-MM-DD    first day of the rent, today by default
+  -return YYYY-MM-DD   day the auto is returned, the last day of the rent by default
+  -promo CODE          promo code to apply, checked as of the first day of the rent
+  -points N            loyalty points to redeem, not checked against a balance
+  -extras id:N,id:N    extras to rent with the auto, not reserved
+  -insurance CODE      insurance package of the auto type, included in the checkout`
+
+func runQuote(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("quote", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Println(quoteUsage) }
+	start := flags.String("start", "", "first day of the rent")
+	returned := flags.String("return", "", "day the auto is returned")
+	options := service.RentalOptions{}
+	flags.StringVar(&options.PromoCode, "promo", "", "promo code to apply")
+	flags.IntVar(&options.RedeemPoints, "points", 0, "loyalty points to redeem")
+	extras := flags.String("extras", "", "comma separated id:quantity of the extras to rent")
+	flags.StringVar(&options.Insurance, "insurance", "", "insurance package of the auto type")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New(quoteUsage)
+	}
+	days, err := strconv.Atoi(flags.Arg(1))
+	if err != nil || days <= 0 {
+		return errors.New("days must be a positive number")
+	}
+	if options.RedeemPoints < 0 {
+		return errors.New("-points must not be negative")
+	}
+	if *extras != "" {
+		options.Extras = map[string]int{}
+		for _, entry := range strings.Split(*extras, ",") {
+			id, quantity, _ := strings.Cut(entry, ":")
+			if options.Extras[id], err = strconv.Atoi(quantity); err != nil {
+				return fmt.Errorf("-extras must be id:quantity entries, got %q", entry)
+			}
+		}
+	}
+	startDate := time.Now()
+	if *start != "" {
+		if startDate, err = time.Parse(time.DateOnly, *start); err != nil {
+			return fmt.Errorf("-start must be YYYY-MM-DD: %w", err)
+		}
+	}
+	returnDate := startDate.AddDate(0, 0, days-1)
+	if *returned != "" {
+		if returnDate, err = time.Parse(time.DateOnly, *returned); err != nil {
+			return fmt.Errorf("-return must be YYYY-MM-DD: %w", err)
+		}
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	checkout, insurance, err := app.rentalService.Quote(ctx, flags.Arg(0), days, startDate, returnDate, options)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("auto %s, %d days from %s returned %s\n",
+		flags.Arg(0), days, startDate.Format(time.DateOnly), returnDate.Format(time.DateOnly))
+	fmt.Printf("checkout:  %d\ninsurance: %d\n", checkout, insurance)
+	return nil
+}
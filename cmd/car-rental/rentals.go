@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"car-rental/internal/config"
+	"car-rental/internal/repository"
+	"car-rental/internal/service"
+)
+
+const rentalsUsage = `usage: car-rental rentals <command>
+
+commands:
+  list [flags]               list rents, newest first
+  close [flags] <rent-id>    return the auto of an active rent and print the checkout`
+
+func runRentals(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(rentalsUsage)
+	}
+	switch args[0] {
+	case "list":
+		return listRentals(ctx, cfg, args[1:])
+	case "close":
+		return closeRental(ctx, cfg, args[1:])
+	}
+	return errors.New(rentalsUsage)
+}
+
+func listRentals(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("rentals list", flag.ContinueOnError)
+	query := repository.RentQuery{}
+	flags.StringVar(&query.Status, "status", "", "active or closed")
+	flags.StringVar(&query.AutoID, "auto", "", "only rents of the auto")
+	flags.StringVar(&query.ClientID, "client", "", "only rents of the client")
+	flags.IntVar(&query.Limit, "limit", 50, "maximum number of rents to list")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	rents, err := app.rentalRepository.FindRents(ctx, query)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tAUTO\tCLIENT\tSTATUS\tSTART\tEND\tRETURNED\tCHECKOUT")
+	for _, rent := range rents {
+		returned := "-"
+		if rent.ReturnedAt != nil {
+			returned = rent.ReturnedAt.Format(time.DateOnly)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n", rent.ID, rent.AutoID, rent.ClientID, rent.Status,
+			rent.StartDate.Format(time.DateOnly), rent.EndDate.Format(time.DateOnly), returned, rent.Checkout)
+	}
+	return w.Flush()
+}
+
+func closeRental(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("rentals close", flag.ContinueOnError)
+	date := flags.String("date", "", "return date as YYYY-MM-DD, today by default")
+	rentalReturn := service.RentalReturn{ReturnDate: time.Now()}
+	flags.IntVar(&rentalReturn.Odometer, "odometer", 0, "odometer reading at return")
+	flags.StringVar(&rentalReturn.Notes, "notes", "", "notes on the return")
+	flags.IntVar(&rentalReturn.Damage, "damage", 0, "repair cost of the damage found, charged up to the insurance excess")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if rentalReturn.Damage < 0 {
+		return errors.New("-damage must not be negative")
+	}
+	if flags.NArg() != 1 {
+		return errors.New(rentalsUsage)
+	}
+	rentId, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("rent id must be a number: %w", err)
+	}
+	if *date != "" {
+		rentalReturn.ReturnDate, err = time.Parse(time.DateOnly, *date)
+		if err != nil {
+			return fmt.Errorf("-date must be YYYY-MM-DD: %w", err)
+		}
+	}
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	rent, err := app.rentalService.ReturnRent(ctx, rentId, rentalReturn)
+	if err != nil {
+		return fmt.Errorf("can't close rent %d: %w", rentId, err)
+	}
+	fmt.Printf("closed rent %d of auto %s, checkout %d after a discount of %d, tax %d\n",
+		rent.ID, rent.AutoID, rent.Checkout, rent.Discount, rent.CheckoutTax)
+	return nil
+}
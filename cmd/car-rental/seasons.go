@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"car-rental/internal/config"
+	"car-rental/internal/models"
+)
+
+const seasonsUsage = `usage: car-rental seasons <command>
+
+commands:
+  list                      list the seasons, the earliest first
+  add [flags] <name>        add a season
+  delete <id>               delete a season, the rents not returned yet are priced without it
+
+add flags:
+  -from YYYY-MM-DD          first day of the season (required)
+  -to YYYY-MM-DD            last day of the season (required)
+  -percent N                percent of the daily price charged on the days of the season (required)
+  -auto-types a,b           auto types the season prices, every type by default`
+
+func runSeasons(ctx context.Context, cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(seasonsUsage)
+	}
+	switch args[0] {
+	case "list":
+		return listSeasons(ctx, cfg)
+	case "add":
+		return addSeason(ctx, cfg, args[1:])
+	case "delete":
+		if len(args) != 2 {
+			return errors.New(seasonsUsage)
+		}
+		return deleteSeason(ctx, cfg, args[1])
+	}
+	return errors.New(seasonsUsage)
+}
+
+func listSeasons(ctx context.Context, cfg config.Config) error {
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	seasons, err := app.seasonRepository.FindSeasons(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tFROM\tTO\tPERCENT\tAUTO TYPES")
+	for _, season := range seasons {
+		autoTypes := strings.Join(season.AutoTypes, ",")
+		if autoTypes == "" {
+			autoTypes = "all"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d%%\t%s\n", season.ID, season.Name, season.StartDate.Format(time.DateOnly),
+			season.EndDate.Format(time.DateOnly), season.Percent, autoTypes)
+	}
+	return w.Flush()
+}
+
+func addSeason(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("seasons add", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Println(seasonsUsage) }
+	from := flags.String("from", "", "first day of the season")
+	to := flags.String("to", "", "last day of the season")
+	season := models.Season{}
+	flags.IntVar(&season.Percent, "percent", 0, "percent of the daily price charged on the days of the season")
+	autoTypes := flags.String("auto-types", "", "comma separated auto types the season prices")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(seasonsUsage)
+	}
+	season.Name = flags.Arg(0)
+	if *from == "" || *to == "" || season.Percent == 0 {
+		return errors.New("-from, -to and -percent are required")
+	}
+	if season.Percent < 0 {
+		return errors.New("-percent must be positive")
+	}
+	var err error
+	if season.StartDate, err = time.Parse(time.DateOnly, *from); err != nil {
+		return fmt.Errorf("-from must be YYYY-MM-DD: %w", err)
+	}
+	if season.EndDate, err = time.Parse(time.DateOnly, *to); err != nil {
+		return fmt.Errorf("-to must be YYYY-MM-DD: %w", err)
+	}
+	if season.EndDate.Before(season.StartDate) {
+		return errors.New("-to must not be before -from")
+	}
+
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if *autoTypes != "" {
+		season.AutoTypes = strings.Split(*autoTypes, ",")
+		known, err := app.rentalService.GetAutoTypes(ctx)
+		if err != nil {
+			return err
+		}
+		for _, autoType := range season.AutoTypes {
+			if !slices.Contains(known, autoType) {
+				return fmt.Errorf("unknown auto type %q, known types are %s", autoType, strings.Join(known, ", "))
+			}
+		}
+	}
+	if season, err = app.seasonRepository.CreateSeason(ctx, season); err != nil {
+		return err
+	}
+	fmt.Printf("added season %d (%s)\n", season.ID, season.Name)
+	return nil
+}
+
+func deleteSeason(ctx context.Context, cfg config.Config, id string) error {
+	seasonId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("season id must be a number: %w", err)
+	}
+	app, err := newApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer app.close()
+	if err = app.seasonRepository.DeleteSeason(ctx, seasonId); err != nil {
+		return fmt.Errorf("can't delete season %d: %w", seasonId, err)
+	}
+	fmt.Printf("deleted season %d\n", seasonId)
+	return nil
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	bearerPrefix = "Bearer "
+)
+
+var (
+	ErrNoCredentials      = errors.New("no credentials provided")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+type APIKey struct {
+	Key       string
+	Principal Principal
+}
+
+type Config struct {
+	APIKeys []APIKey
+	// HS256Secret enables HS256 signed JWTs when not empty.
+	HS256Secret []byte
+	// RS256PublicKey enables RS256 signed JWTs when not nil.
+	RS256PublicKey *rsa.PublicKey
+}
+
+type Authenticator struct {
+	apiKeys     map[[sha256.Size]byte]Principal
+	hs256Secret []byte
+	rs256Key    *rsa.PublicKey
+}
+
+type claims struct {
+	Role Role `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func NewAuthenticator(cfg Config) (*Authenticator, error) {
+	a := &Authenticator{
+		apiKeys:     make(map[[sha256.Size]byte]Principal, len(cfg.APIKeys)),
+		hs256Secret: cfg.HS256Secret,
+		rs256Key:    cfg.RS256PublicKey,
+	}
+	for _, key := range cfg.APIKeys {
+		if key.Key == "" || key.Principal.Subject == "" {
+			return nil, errors.New("api key and subject must not be empty")
+		}
+		if !key.Principal.Role.Valid() {
+			return nil, fmt.Errorf("api key for %q has unknown role %q", key.Principal.Subject, key.Principal.Role)
+		}
+		// keys are stored hashed, so lookups don't compare raw secrets
+		a.apiKeys[sha256.Sum256([]byte(key.Key))] = key.Principal
+	}
+	return a, nil
+}
+
+// Authenticate resolves the caller from the X-API-Key header or a bearer JWT.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		principal, ok := a.apiKeys[sha256.Sum256([]byte(key))]
+		if !ok {
+			return Principal{}, ErrInvalidCredentials
+		}
+		return principal, nil
+	}
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, bearerPrefix) {
+		return a.verifyToken(strings.TrimPrefix(header, bearerPrefix))
+	}
+	return Principal{}, ErrNoCredentials
+}
+
+func (a *Authenticator) verifyToken(raw string) (Principal, error) {
+	var methods []string
+	if len(a.hs256Secret) != 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if a.rs256Key != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return Principal{}, ErrInvalidCredentials
+	}
+	var c claims
+	_, err := jwt.ParseWithClaims(raw, &c, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return a.hs256Secret, nil
+		case jwt.SigningMethodRS256.Alg():
+			return a.rs256Key, nil
+		}
+		return nil, ErrInvalidCredentials
+	}, jwt.WithValidMethods(methods), jwt.WithExpirationRequired())
+	if err != nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if c.Subject == "" || !c.Role.Valid() {
+		return Principal{}, ErrInvalidCredentials
+	}
+	return Principal{Subject: c.Subject, Role: c.Role}, nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	a, err := NewAuthenticator(Config{APIKeys: []APIKey{
+		{Key: "secret", Principal: Principal{Subject: "ops", Role: RoleAdmin}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(APIKeyHeader, "secret")
+	principal, err := a.Authenticate(req)
+	if err != nil {
+		t.Error(err)
+	}
+	if principal != (Principal{Subject: "ops", Role: RoleAdmin}) {
+		t.Errorf("unexpected principal %v", principal)
+	}
+
+	req.Header.Set(APIKeyHeader, "wrong")
+	if _, err = a.Authenticate(req); err != ErrInvalidCredentials {
+		t.Errorf("want %v, got %v", ErrInvalidCredentials, err)
+	}
+	if _, err = a.Authenticate(httptest.NewRequest("GET", "/", nil)); err != ErrNoCredentials {
+		t.Errorf("want %v, got %v", ErrNoCredentials, err)
+	}
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsSecret := []byte("hs-secret")
+	a, err := NewAuthenticator(Config{HS256Secret: hsSecret, RS256PublicKey: &rsaKey.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := claims{Role: RoleCustomer, RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "client-1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	unknownRole := valid
+	unknownRole.Role = "owner"
+
+	hs := func(c claims) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(hsSecret)
+		return token
+	}
+	rs := func(c claims) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, c).SignedString(rsaKey)
+		return token
+	}
+	wrongSecret, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, valid).SignedString([]byte("other"))
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"hs256", hs(valid), true},
+		{"rs256", rs(valid), true},
+		{"expired", hs(expired), false},
+		{"unknown role", rs(unknownRole), false},
+		{"wrong secret", wrongSecret, false},
+		{"garbage", "not.a.token", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		principal, err := a.Authenticate(req)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+		if tt.ok && principal != (Principal{Subject: "client-1", Role: RoleCustomer}) {
+			t.Errorf("%s: unexpected principal %v", tt.name, principal)
+		}
+	}
+}
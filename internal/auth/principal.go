@@ -0,0 +1,33 @@
+package auth
+
+import "context"
+
+type Role string
+
+const (
+	RoleCustomer Role = "customer"
+	RoleAgent    Role = "agent"
+	RoleAdmin    Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleCustomer || r == RoleAgent || r == RoleAdmin
+}
+
+// Principal is the authenticated caller. For customers Subject is the client id
+// the rents are owned by.
+type Principal struct {
+	Subject string
+	Role    Role
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
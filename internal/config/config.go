@@ -0,0 +1,606 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/events"
+	"car-rental/internal/invoicing"
+	"car-rental/internal/loyalty"
+	"car-rental/internal/payments"
+	"car-rental/internal/pricing"
+	"car-rental/internal/tax"
+	"car-rental/internal/tracing"
+	"car-rental/internal/webhooks"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	prefixPattern   = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+)
+
+// Config is everything the binary needs to run. It is built by Loader from the defaults,
+// a YAML or TOML file, the environment and the command line flags.
+type Config struct {
+	LogLevel string `yaml:"log_level" toml:"log_level"`
+	// LogFormat is json for log collectors or text for reading in a terminal
+	LogFormat string         `yaml:"log_format" toml:"log_format"`
+	HTTP      HTTPConfig     `yaml:"http" toml:"http"`
+	Database  DatabaseConfig `yaml:"database" toml:"database"`
+	Auth      AuthConfig     `yaml:"auth" toml:"auth"`
+	Features  FeaturesConfig `yaml:"features" toml:"features"`
+	Tracing   TracingConfig  `yaml:"tracing" toml:"tracing"`
+	Events    EventsConfig   `yaml:"events" toml:"events"`
+	Webhooks  WebhooksConfig `yaml:"webhooks" toml:"webhooks"`
+	Invoices  InvoicesConfig `yaml:"invoices" toml:"invoices"`
+	Tax       TaxConfig      `yaml:"tax" toml:"tax"`
+	Payments  PaymentsConfig `yaml:"payments" toml:"payments"`
+	Pricing   PricingConfig  `yaml:"pricing" toml:"pricing"`
+	Loyalty   LoyaltyConfig  `yaml:"loyalty" toml:"loyalty"`
+}
+
+type HTTPConfig struct {
+	Addr           string   `yaml:"addr" toml:"addr"`
+	ReadTimeout    Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout   Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout    Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	MaxHeaderBytes int      `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	// ShutdownDelay keeps serving after SIGTERM with /readyz failing, so load balancers stop routing first
+	ShutdownDelay Duration `yaml:"shutdown_delay" toml:"shutdown_delay"`
+	// ShutdownTimeout is how long in-flight requests get to finish
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// IdempotencyTTL is how long an Idempotency-Key replays its response before it may be reused
+	IdempotencyTTL Duration `yaml:"idempotency_ttl" toml:"idempotency_ttl"`
+}
+
+// DatabaseConfig builds the postgres connection string from its parts unless DSN is set.
+type DatabaseConfig struct {
+	DSN             string   `yaml:"dsn" toml:"dsn"`
+	Host            string   `yaml:"host" toml:"host"`
+	Port            int      `yaml:"port" toml:"port"`
+	User            string   `yaml:"user" toml:"user"`
+	Password        string   `yaml:"password" toml:"password"`
+	Name            string   `yaml:"name" toml:"name"`
+	SSLMode         string   `yaml:"sslmode" toml:"sslmode"`
+	MaxOpenConns    int      `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	// ConnectTimeout is how long connecting is retried at startup
+	ConnectTimeout Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+}
+
+type AuthConfig struct {
+	// APIKeys are key:role:subject entries
+	APIKeys               []string `yaml:"api_keys" toml:"api_keys"`
+	JWTHS256Secret        string   `yaml:"jwt_hs256_secret" toml:"jwt_hs256_secret"`
+	JWTRS256PublicKeyFile string   `yaml:"jwt_rs256_public_key_file" toml:"jwt_rs256_public_key_file"`
+}
+
+type FeaturesConfig struct {
+	// LegacyRentals serves the deprecated v1 bind and release endpoints
+	LegacyRentals bool `yaml:"legacy_rentals" toml:"legacy_rentals"`
+	// OpenAPI serves the OpenAPI document
+	OpenAPI bool `yaml:"openapi" toml:"openapi"`
+	// Metrics serves the Prometheus metrics
+	Metrics bool `yaml:"metrics" toml:"metrics"`
+}
+
+type TracingConfig struct {
+	// Exporter is none, stdout for local debugging, or otlp to send spans to a collector
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// OTLPEndpoint is host:port of the collector's OTLP/HTTP receiver
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	// OTLPInsecure sends spans over plain HTTP
+	OTLPInsecure bool `yaml:"otlp_insecure" toml:"otlp_insecure"`
+	// SampleRatio is the share of traces recorded, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// EventsConfig tells the dispatcher where to publish the domain events of the outbox.
+type EventsConfig struct {
+	// DispatchInterval is how often the outbox is polled for events to publish
+	DispatchInterval Duration `yaml:"dispatch_interval" toml:"dispatch_interval"`
+	BatchSize        int      `yaml:"batch_size" toml:"batch_size"`
+	// Retention is how long published events stay in the outbox, 0 keeps them
+	Retention Duration `yaml:"retention" toml:"retention"`
+	// Broker is none, or stdout standing in for a NATS or Kafka broker in development
+	Broker string `yaml:"broker" toml:"broker"`
+	// SubjectPrefix is put before the event type to name the broker subject
+	SubjectPrefix string `yaml:"subject_prefix" toml:"subject_prefix"`
+	// WebhookURL receives every event as a POST when set
+	WebhookURL     string   `yaml:"webhook_url" toml:"webhook_url"`
+	WebhookTimeout Duration `yaml:"webhook_timeout" toml:"webhook_timeout"`
+}
+
+// WebhooksConfig tunes the delivery of the events to the webhooks partners subscribe through the API.
+type WebhooksConfig struct {
+	// DeliveryInterval is how often due deliveries are looked up
+	DeliveryInterval Duration `yaml:"delivery_interval" toml:"delivery_interval"`
+	BatchSize        int      `yaml:"batch_size" toml:"batch_size"`
+	// MaxAttempts is how often a delivery is tried before it is dead
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts"`
+	// RetryDelay is the delay of the first retry, doubling with every further one up to 6h
+	RetryDelay Duration `yaml:"retry_delay" toml:"retry_delay"`
+	Timeout    Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// InvoicesConfig holds the issuer details and the tax printed on the invoices of closed rents.
+type InvoicesConfig struct {
+	IssuerName    string `yaml:"issuer_name" toml:"issuer_name"`
+	IssuerAddress string `yaml:"issuer_address" toml:"issuer_address"`
+	IssuerTaxID   string `yaml:"issuer_tax_id" toml:"issuer_tax_id"`
+	// Currency is the ISO 4217 code of the checkout amounts
+	Currency         string `yaml:"currency" toml:"currency"`
+	NumberPrefix     string `yaml:"number_prefix" toml:"number_prefix"`
+	CreditNotePrefix string `yaml:"credit_note_prefix" toml:"credit_note_prefix"`
+	TaxName          string `yaml:"tax_name" toml:"tax_name"`
+}
+
+// TaxConfig holds the tax rates added to the commissions, per jurisdiction and commission type.
+// The jurisdiction of a rent is the location of its auto.
+type TaxConfig struct {
+	// DefaultRate is the percentage charged where no rate matches
+	DefaultRate float64 `yaml:"default_rate" toml:"default_rate"`
+	// Rates are jurisdiction:commission_type:percent entries, * matching any jurisdiction or commission type
+	Rates []string `yaml:"rates" toml:"rates"`
+}
+
+// PricingConfig holds the demand levels raising the daily prices of an auto type at bind,
+// by the share of its autos rented.
+type PricingConfig struct {
+	// Demand are share:percent entries, the daily prices are charged at percent from share percent rented
+	Demand []string `yaml:"demand" toml:"demand"`
+}
+
+// LoyaltyConfig holds the loyalty programme, clients earn points on their checkouts while EarnPercent is set.
+type LoyaltyConfig struct {
+	EarnPercent int `yaml:"earn_percent" toml:"earn_percent"`
+	// PointValue is what a redeemed point takes off the checkout
+	PointValue  int      `yaml:"point_value" toml:"point_value"`
+	ExpireAfter Duration `yaml:"expire_after" toml:"expire_after"`
+	// Tiers are name:points[:commission+commission] entries, reached with the points earned over all rentals
+	Tiers []string `yaml:"tiers" toml:"tiers"`
+}
+
+type PaymentsConfig struct {
+	// Provider is none, or memory standing in for a payment gateway in development
+	Provider string `yaml:"provider" toml:"provider"`
+}
+
+func Default() Config {
+	return Config{
+		LogLevel:  LogLevelInfo,
+		LogFormat: LogFormatJSON,
+		HTTP: HTTPConfig{
+			Addr:            ":8080",
+			ReadTimeout:     Duration(10 * time.Second),
+			WriteTimeout:    Duration(10 * time.Second),
+			IdleTimeout:     Duration(60 * time.Second),
+			MaxHeaderBytes:  1 << 20,
+			ShutdownTimeout: Duration(20 * time.Second),
+			IdempotencyTTL:  Duration(24 * time.Hour),
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            5432,
+			User:            "postgres",
+			Password:        "postgres",
+			Name:            "postgres",
+			SSLMode:         "disable",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			ConnectTimeout:  Duration(30 * time.Second),
+		},
+		Features: FeaturesConfig{
+			LegacyRentals: true,
+			OpenAPI:       true,
+			Metrics:       true,
+		},
+		Tracing: TracingConfig{
+			Exporter:     tracing.ExporterNone,
+			OTLPEndpoint: "localhost:4318",
+			OTLPInsecure: true,
+			SampleRatio:  1,
+		},
+		Events: EventsConfig{
+			DispatchInterval: Duration(time.Second),
+			BatchSize:        100,
+			Retention:        Duration(7 * 24 * time.Hour),
+			Broker:           events.BrokerNone,
+			SubjectPrefix:    "car-rental",
+			WebhookTimeout:   Duration(5 * time.Second),
+		},
+		Webhooks: WebhooksConfig{
+			DeliveryInterval: Duration(time.Second),
+			BatchSize:        20,
+			MaxAttempts:      10,
+			RetryDelay:       Duration(30 * time.Second),
+			Timeout:          Duration(5 * time.Second),
+		},
+		Invoices: InvoicesConfig{
+			IssuerName:       "car-rental",
+			Currency:         "EUR",
+			NumberPrefix:     "INV",
+			CreditNotePrefix: "CN",
+			TaxName:          "VAT",
+		},
+		Payments: PaymentsConfig{
+			Provider: payments.ProviderNone,
+		},
+		Loyalty: LoyaltyConfig{
+			PointValue:  1,
+			ExpireAfter: Duration(365 * 24 * time.Hour),
+		},
+	}
+}
+
+// ConnectionString is the DSN, or the postgres URL built from the parts.
+func (d DatabaseConfig) ConnectionString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     net.JoinHostPort(d.Host, fmt.Sprint(d.Port)),
+		Path:     d.Name,
+		RawQuery: url.Values{"sslmode": {d.SSLMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+// TracerOptions are the options of the tracer provider, stdout spans are written to stdout.
+func (t TracingConfig) TracerOptions() tracing.Options {
+	return tracing.Options{
+		Exporter:     t.Exporter,
+		OTLPEndpoint: t.OTLPEndpoint,
+		OTLPInsecure: t.OTLPInsecure,
+		SampleRatio:  t.SampleRatio,
+		Stdout:       os.Stdout,
+	}
+}
+
+// DispatcherOptions are the options of the outbox dispatcher.
+func (e EventsConfig) DispatcherOptions() events.DispatcherOptions {
+	return events.DispatcherOptions{
+		Interval:  e.DispatchInterval.Std(),
+		BatchSize: e.BatchSize,
+		Retention: e.Retention.Std(),
+	}
+}
+
+// DelivererOptions are the options of the webhook deliverer.
+func (w WebhooksConfig) DelivererOptions() webhooks.DelivererOptions {
+	return webhooks.DelivererOptions{
+		Interval:    w.DeliveryInterval.Std(),
+		BatchSize:   w.BatchSize,
+		MaxAttempts: w.MaxAttempts,
+		RetryDelay:  w.RetryDelay.Std(),
+		Timeout:     w.Timeout.Std(),
+	}
+}
+
+// Settings are the invoicing settings copied onto every invoice.
+func (i InvoicesConfig) Settings() invoicing.Settings {
+	return invoicing.Settings{
+		IssuerName:       i.IssuerName,
+		IssuerAddress:    i.IssuerAddress,
+		IssuerTaxID:      i.IssuerTaxID,
+		Currency:         i.Currency,
+		NumberPrefix:     i.NumberPrefix,
+		CreditNotePrefix: i.CreditNotePrefix,
+		TaxName:          i.TaxName,
+	}
+}
+
+// TaxRates parses the tax rates, the most specific rate of a commission type applies.
+func (t TaxConfig) TaxRates() (tax.Rates, error) {
+	rates := make([]tax.Rate, 0, len(t.Rates))
+	for _, entry := range t.Rates {
+		rate, err := tax.ParseRate(entry)
+		if err != nil {
+			return tax.Rates{}, err
+		}
+		rates = append(rates, rate)
+	}
+	return tax.NewRates(t.DefaultRate, rates...), nil
+}
+
+// DemandLevels parses the demand levels, none keeps the daily prices.
+func (p PricingConfig) DemandLevels() (pricing.Demand, error) {
+	demand := make(pricing.Demand, 0, len(p.Demand))
+	for _, entry := range p.Demand {
+		level, err := pricing.ParseLevel(entry)
+		if err != nil {
+			return nil, err
+		}
+		demand = append(demand, level)
+	}
+	return demand, nil
+}
+
+// Program parses the tiers of the loyalty programme.
+func (l LoyaltyConfig) Program() (loyalty.Program, error) {
+	program := loyalty.Program{EarnPercent: l.EarnPercent, PointValue: l.PointValue, ExpireAfter: l.ExpireAfter.Std()}
+	for _, entry := range l.Tiers {
+		tier, err := loyalty.ParseTier(entry)
+		if err != nil {
+			return loyalty.Program{}, err
+		}
+		program.Tiers = append(program.Tiers, tier)
+	}
+	return program, nil
+}
+
+// Credentials converts the settings to the authenticator configuration, reading the RS256 public key.
+func (a AuthConfig) Credentials() (auth.Config, error) {
+	var cfg auth.Config
+	for _, entry := range a.APIKeys {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			return cfg, errors.New("api key entries must be key:role:subject")
+		}
+		cfg.APIKeys = append(cfg.APIKeys, auth.APIKey{
+			Key:       parts[0],
+			Principal: auth.Principal{Role: auth.Role(parts[1]), Subject: parts[2]},
+		})
+	}
+	if a.JWTHS256Secret != "" {
+		cfg.HS256Secret = []byte(a.JWTHS256Secret)
+	}
+	if a.JWTRS256PublicKeyFile != "" {
+		pem, err := os.ReadFile(a.JWTRS256PublicKeyFile)
+		if err != nil {
+			return cfg, fmt.Errorf("reading JWT public key: %w", err)
+		}
+		cfg.RS256PublicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return cfg, fmt.Errorf("parsing JWT public key: %w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// Validate reports every invalid setting at once, named as in the config file.
+func (c Config) Validate() error {
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch c.LogLevel {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		report("log_level %q must be one of debug, info, warn, error", c.LogLevel)
+	}
+	switch c.LogFormat {
+	case LogFormatJSON, LogFormatText:
+	default:
+		report("log_format %q must be json or text", c.LogFormat)
+	}
+
+	if _, port, err := net.SplitHostPort(c.HTTP.Addr); err != nil || port == "" {
+		report("http.addr %q must be host:port, e.g. \":8080\" or \"127.0.0.1:8080\"", c.HTTP.Addr)
+	}
+	for _, timeout := range []struct {
+		name  string
+		value Duration
+	}{
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+		{"http.idempotency_ttl", c.HTTP.IdempotencyTTL},
+	} {
+		if timeout.value <= 0 {
+			report("%s must be a positive duration, e.g. 10s", timeout.name)
+		}
+	}
+	if c.HTTP.MaxHeaderBytes <= 0 {
+		report("http.max_header_bytes must be positive")
+	}
+	if c.HTTP.ShutdownDelay < 0 {
+		report("http.shutdown_delay must not be negative")
+	}
+
+	db := c.Database
+	if db.DSN == "" {
+		if db.Host == "" {
+			report("database.host is required when database.dsn is not set")
+		}
+		if db.Port <= 0 || db.Port > 65535 {
+			report("database.port %d must be between 1 and 65535", db.Port)
+		}
+		if db.User == "" {
+			report("database.user is required when database.dsn is not set")
+		}
+		if db.Name == "" {
+			report("database.name is required when database.dsn is not set")
+		}
+		switch db.SSLMode {
+		case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			report("database.sslmode %q must be one of disable, allow, prefer, require, verify-ca, verify-full", db.SSLMode)
+		}
+	}
+	if db.MaxOpenConns <= 0 {
+		report("database.max_open_conns must be positive")
+	}
+	if db.MaxIdleConns < 0 || db.MaxIdleConns > db.MaxOpenConns {
+		report("database.max_idle_conns %d must be between 0 and database.max_open_conns (%d)",
+			db.MaxIdleConns, db.MaxOpenConns)
+	}
+	if db.ConnMaxLifetime < 0 {
+		report("database.conn_max_lifetime must not be negative, 0 keeps connections forever")
+	}
+	if db.ConnectTimeout < 0 {
+		report("database.connect_timeout must not be negative, 0 connects once without retrying")
+	}
+
+	for i, entry := range c.Auth.APIKeys {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			report("auth.api_keys[%d] must be key:role:subject", i)
+		} else if !auth.Role(parts[1]).Valid() {
+			report("auth.api_keys[%d] has unknown role %q, use customer, agent or admin", i, parts[1])
+		}
+	}
+	if path := c.Auth.JWTRS256PublicKeyFile; path != "" {
+		if _, err := os.Stat(path); err != nil {
+			report("auth.jwt_rs256_public_key_file: %v", err)
+		}
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		report("tracing.exporter %q must be one of none, stdout, otlp", c.Tracing.Exporter)
+	}
+	if c.Tracing.Exporter == tracing.ExporterOTLP {
+		if _, port, err := net.SplitHostPort(c.Tracing.OTLPEndpoint); err != nil || port == "" {
+			report("tracing.otlp_endpoint %q must be host:port, e.g. \"localhost:4318\"", c.Tracing.OTLPEndpoint)
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		report("tracing.sample_ratio %v must be between 0 and 1", c.Tracing.SampleRatio)
+	}
+
+	if c.Events.DispatchInterval <= 0 {
+		report("events.dispatch_interval must be a positive duration, e.g. 1s")
+	}
+	if c.Events.BatchSize <= 0 {
+		report("events.batch_size must be positive")
+	}
+	if c.Events.Retention < 0 {
+		report("events.retention must not be negative, 0 keeps published events")
+	}
+	switch c.Events.Broker {
+	case events.BrokerNone, events.BrokerStdout:
+	default:
+		report("events.broker %q must be none or stdout", c.Events.Broker)
+	}
+	if c.Events.Broker != events.BrokerNone && c.Events.SubjectPrefix == "" {
+		report("events.subject_prefix is required with a broker")
+	}
+	if c.Events.WebhookURL != "" {
+		if webhook, err := url.Parse(c.Events.WebhookURL); err != nil ||
+			(webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			report("events.webhook_url %q must be an http or https URL", c.Events.WebhookURL)
+		}
+		if c.Events.WebhookTimeout <= 0 {
+			report("events.webhook_timeout must be a positive duration, e.g. 5s")
+		}
+	}
+
+	if c.Webhooks.DeliveryInterval <= 0 {
+		report("webhooks.delivery_interval must be a positive duration, e.g. 1s")
+	}
+	if c.Webhooks.BatchSize <= 0 {
+		report("webhooks.batch_size must be positive")
+	}
+	if c.Webhooks.MaxAttempts <= 0 {
+		report("webhooks.max_attempts must be positive")
+	}
+	if c.Webhooks.RetryDelay <= 0 {
+		report("webhooks.retry_delay must be a positive duration, e.g. 30s")
+	}
+	if c.Webhooks.Timeout <= 0 {
+		report("webhooks.timeout must be a positive duration, e.g. 5s")
+	}
+
+	if c.Invoices.IssuerName == "" {
+		report("invoices.issuer_name is required")
+	}
+	if !currencyPattern.MatchString(c.Invoices.Currency) {
+		report("invoices.currency %q must be an ISO 4217 code like EUR", c.Invoices.Currency)
+	}
+	if !prefixPattern.MatchString(c.Invoices.NumberPrefix) {
+		report("invoices.number_prefix %q must be letters and digits", c.Invoices.NumberPrefix)
+	}
+	if !prefixPattern.MatchString(c.Invoices.CreditNotePrefix) {
+		report("invoices.credit_note_prefix %q must be letters and digits", c.Invoices.CreditNotePrefix)
+	}
+	if c.Invoices.NumberPrefix == c.Invoices.CreditNotePrefix {
+		report("invoices.credit_note_prefix must differ from invoices.number_prefix")
+	}
+
+	if c.Tax.DefaultRate < 0 || c.Tax.DefaultRate >= 100 {
+		report("tax.default_rate %v must be a percentage from 0 to below 100", c.Tax.DefaultRate)
+	}
+	for i, entry := range c.Tax.Rates {
+		if _, err := tax.ParseRate(entry); err != nil {
+			report("tax.rates[%d]: %v", i, err)
+		}
+	}
+
+	switch c.Payments.Provider {
+	case payments.ProviderNone, payments.ProviderMemory:
+	default:
+		report("payments.provider %q must be none or memory", c.Payments.Provider)
+	}
+
+	for i, entry := range c.Pricing.Demand {
+		if _, err := pricing.ParseLevel(entry); err != nil {
+			report("pricing.demand[%d]: %v", i, err)
+		}
+	}
+
+	if c.Loyalty.EarnPercent < 0 || c.Loyalty.EarnPercent > 100 {
+		report("loyalty.earn_percent %d must be a percentage from 0 to 100", c.Loyalty.EarnPercent)
+	}
+	if c.Loyalty.PointValue <= 0 {
+		report("loyalty.point_value %d must be positive", c.Loyalty.PointValue)
+	}
+	if c.Loyalty.ExpireAfter < 0 {
+		report("loyalty.expire_after must not be negative")
+	}
+	for i, entry := range c.Loyalty.Tiers {
+		if _, err := loyalty.ParseTier(entry); err != nil {
+			report("loyalty.tiers[%d]: %v", i, err)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+}
+
+// Duration is a time.Duration written as "10s" or "1m30s" in files, the environment and flags.
+type Duration time.Duration
+
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
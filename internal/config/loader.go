@@ -0,0 +1,309 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the config file when the -config flag isn't given.
+const FileEnv = "CAR_RENTAL_CONFIG"
+
+// setting is one value that can be set from the environment and, unless flag is empty, a flag.
+// Secrets have no flag, command lines are visible to every user of the host.
+type setting struct {
+	env   string
+	flag  string
+	usage string
+	set   func(cfg *Config, value string) error
+}
+
+var settings = []setting{
+	{"LOG_LEVEL", "log-level", "debug, info, warn or error",
+		setString(func(c *Config) *string { return &c.LogLevel })},
+	{"LOG_FORMAT", "log-format", "json or text",
+		setString(func(c *Config) *string { return &c.LogFormat })},
+	// PORT is what most platforms set, HTTP_ADDR comes later and wins
+	{"PORT", "", "",
+		func(c *Config, value string) error { c.HTTP.Addr = ":" + value; return nil }},
+	{"HTTP_ADDR", "http-addr", "listen address, e.g. :8080",
+		setString(func(c *Config) *string { return &c.HTTP.Addr })},
+	{"HTTP_READ_TIMEOUT", "http-read-timeout", "timeout to read a request",
+		setDuration(func(c *Config) *Duration { return &c.HTTP.ReadTimeout })},
+	{"HTTP_WRITE_TIMEOUT", "http-write-timeout", "timeout to write a response",
+		setDuration(func(c *Config) *Duration { return &c.HTTP.WriteTimeout })},
+	{"HTTP_IDLE_TIMEOUT", "http-idle-timeout", "keep-alive timeout",
+		setDuration(func(c *Config) *Duration { return &c.HTTP.IdleTimeout })},
+	{"HTTP_MAX_HEADER_BYTES", "http-max-header-bytes", "maximum size of request headers",
+		setInt(func(c *Config) *int { return &c.HTTP.MaxHeaderBytes })},
+	{"HTTP_SHUTDOWN_DELAY", "http-shutdown-delay", "time to keep serving after SIGTERM before draining",
+		setDuration(func(c *Config) *Duration { return &c.HTTP.ShutdownDelay })},
+	{"HTTP_SHUTDOWN_TIMEOUT", "http-shutdown-timeout", "time in-flight requests get to finish on shutdown",
+		setDuration(func(c *Config) *Duration { return &c.HTTP.ShutdownTimeout })},
+	{"HTTP_IDEMPOTENCY_TTL", "http-idempotency-ttl", "how long the response of an Idempotency-Key is replayed",
+		setDuration(func(c *Config) *Duration { return &c.HTTP.IdempotencyTTL })},
+	{"DB_DSN", "", "",
+		setString(func(c *Config) *string { return &c.Database.DSN })},
+	{"DB_HOST", "db-host", "database host",
+		setString(func(c *Config) *string { return &c.Database.Host })},
+	{"DB_PORT", "db-port", "database port",
+		setInt(func(c *Config) *int { return &c.Database.Port })},
+	{"DB_USER", "db-user", "database user",
+		setString(func(c *Config) *string { return &c.Database.User })},
+	{"DB_PASSWORD", "", "",
+		setString(func(c *Config) *string { return &c.Database.Password })},
+	{"DB_NAME", "db-name", "database name",
+		setString(func(c *Config) *string { return &c.Database.Name })},
+	{"DB_SSLMODE", "db-sslmode", "postgres sslmode",
+		setString(func(c *Config) *string { return &c.Database.SSLMode })},
+	{"DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum open database connections",
+		setInt(func(c *Config) *int { return &c.Database.MaxOpenConns })},
+	{"DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum idle database connections",
+		setInt(func(c *Config) *int { return &c.Database.MaxIdleConns })},
+	{"DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum lifetime of a database connection",
+		setDuration(func(c *Config) *Duration { return &c.Database.ConnMaxLifetime })},
+	{"DB_CONNECT_TIMEOUT", "db-connect-timeout", "how long connecting is retried at startup",
+		setDuration(func(c *Config) *Duration { return &c.Database.ConnectTimeout })},
+	{"API_KEYS", "", "",
+		func(c *Config, value string) error { c.Auth.APIKeys = splitList(value); return nil }},
+	{"JWT_HS256_SECRET", "", "",
+		setString(func(c *Config) *string { return &c.Auth.JWTHS256Secret })},
+	{"JWT_RS256_PUBLIC_KEY_FILE", "jwt-rs256-public-key-file", "PEM public key enabling RS256 tokens",
+		setString(func(c *Config) *string { return &c.Auth.JWTRS256PublicKeyFile })},
+	{"FEATURE_LEGACY_RENTALS", "feature-legacy-rentals", "serve the deprecated v1 bind and release endpoints",
+		setBool(func(c *Config) *bool { return &c.Features.LegacyRentals })},
+	{"FEATURE_OPENAPI", "feature-openapi", "serve the OpenAPI document",
+		setBool(func(c *Config) *bool { return &c.Features.OpenAPI })},
+	{"FEATURE_METRICS", "feature-metrics", "serve the Prometheus metrics at /metrics",
+		setBool(func(c *Config) *bool { return &c.Features.Metrics })},
+	{"TRACING_EXPORTER", "tracing-exporter", "none, stdout or otlp",
+		setString(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{"TRACING_OTLP_ENDPOINT", "tracing-otlp-endpoint", "host:port of the OTLP/HTTP collector",
+		setString(func(c *Config) *string { return &c.Tracing.OTLPEndpoint })},
+	{"TRACING_OTLP_INSECURE", "tracing-otlp-insecure", "send spans to the collector over plain HTTP",
+		setBool(func(c *Config) *bool { return &c.Tracing.OTLPInsecure })},
+	{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "share of traces recorded, from 0 to 1",
+		setFloat(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{"EVENTS_DISPATCH_INTERVAL", "events-dispatch-interval", "how often the outbox is polled for events to publish",
+		setDuration(func(c *Config) *Duration { return &c.Events.DispatchInterval })},
+	{"EVENTS_BATCH_SIZE", "events-batch-size", "events published per poll",
+		setInt(func(c *Config) *int { return &c.Events.BatchSize })},
+	{"EVENTS_RETENTION", "events-retention", "how long published events are kept, 0 keeps them",
+		setDuration(func(c *Config) *Duration { return &c.Events.Retention })},
+	{"EVENTS_BROKER", "events-broker", "none or stdout",
+		setString(func(c *Config) *string { return &c.Events.Broker })},
+	{"EVENTS_SUBJECT_PREFIX", "events-subject-prefix", "prefix of the broker subjects",
+		setString(func(c *Config) *string { return &c.Events.SubjectPrefix })},
+	{"EVENTS_WEBHOOK_URL", "events-webhook-url", "URL every event is POSTed to",
+		setString(func(c *Config) *string { return &c.Events.WebhookURL })},
+	{"EVENTS_WEBHOOK_TIMEOUT", "events-webhook-timeout", "timeout of a webhook delivery",
+		setDuration(func(c *Config) *Duration { return &c.Events.WebhookTimeout })},
+	{"WEBHOOKS_DELIVERY_INTERVAL", "webhooks-delivery-interval", "how often due webhook deliveries are sent",
+		setDuration(func(c *Config) *Duration { return &c.Webhooks.DeliveryInterval })},
+	{"WEBHOOKS_BATCH_SIZE", "webhooks-batch-size", "webhook deliveries sent per poll",
+		setInt(func(c *Config) *int { return &c.Webhooks.BatchSize })},
+	{"WEBHOOKS_MAX_ATTEMPTS", "webhooks-max-attempts", "attempts before a webhook delivery is dead",
+		setInt(func(c *Config) *int { return &c.Webhooks.MaxAttempts })},
+	{"WEBHOOKS_RETRY_DELAY", "webhooks-retry-delay", "delay of the first webhook retry, doubling after",
+		setDuration(func(c *Config) *Duration { return &c.Webhooks.RetryDelay })},
+	{"WEBHOOKS_TIMEOUT", "webhooks-timeout", "how long partners get to answer a webhook",
+		setDuration(func(c *Config) *Duration { return &c.Webhooks.Timeout })},
+	{"INVOICES_ISSUER_NAME", "invoices-issuer-name", "issuer printed on invoices",
+		setString(func(c *Config) *string { return &c.Invoices.IssuerName })},
+	{"INVOICES_ISSUER_ADDRESS", "invoices-issuer-address", "issuer address printed on invoices",
+		setString(func(c *Config) *string { return &c.Invoices.IssuerAddress })},
+	{"INVOICES_ISSUER_TAX_ID", "invoices-issuer-tax-id", "issuer VAT or tax id printed on invoices",
+		setString(func(c *Config) *string { return &c.Invoices.IssuerTaxID })},
+	{"INVOICES_CURRENCY", "invoices-currency", "ISO 4217 code of the checkout amounts",
+		setString(func(c *Config) *string { return &c.Invoices.Currency })},
+	{"INVOICES_NUMBER_PREFIX", "invoices-number-prefix", "prefix of invoice numbers",
+		setString(func(c *Config) *string { return &c.Invoices.NumberPrefix })},
+	{"INVOICES_CREDIT_NOTE_PREFIX", "invoices-credit-note-prefix", "prefix of credit note numbers",
+		setString(func(c *Config) *string { return &c.Invoices.CreditNotePrefix })},
+	{"INVOICES_TAX_NAME", "invoices-tax-name", "name of the tax on invoices, like VAT",
+		setString(func(c *Config) *string { return &c.Invoices.TaxName })},
+	{"TAX_DEFAULT_RATE", "tax-default-rate", "tax percentage where no tax rate matches",
+		setFloat(func(c *Config) *float64 { return &c.Tax.DefaultRate })},
+	{"TAX_RATES", "", "",
+		func(c *Config, value string) error { c.Tax.Rates = splitList(value); return nil }},
+	{"PAYMENTS_PROVIDER", "payments-provider", "none, or memory to collect deposits in memory",
+		setString(func(c *Config) *string { return &c.Payments.Provider })},
+	{"PRICING_DEMAND", "", "",
+		func(c *Config, value string) error { c.Pricing.Demand = splitList(value); return nil }},
+	{"LOYALTY_EARN_PERCENT", "loyalty-earn-percent", "percent of the checkout earned in loyalty points, 0 is off",
+		setInt(func(c *Config) *int { return &c.Loyalty.EarnPercent })},
+	{"LOYALTY_POINT_VALUE", "loyalty-point-value", "what a redeemed loyalty point takes off the checkout",
+		setInt(func(c *Config) *int { return &c.Loyalty.PointValue })},
+	{"LOYALTY_EXPIRE_AFTER", "loyalty-expire-after", "how long earned loyalty points can be redeemed, 0 keeps them",
+		setDuration(func(c *Config) *Duration { return &c.Loyalty.ExpireAfter })},
+	{"LOYALTY_TIERS", "", "",
+		func(c *Config, value string) error { c.Loyalty.Tiers = splitList(value); return nil }},
+}
+
+// Loader builds the Config. Later sources win: defaults, the config file, the environment, the flags.
+type Loader struct {
+	file  string
+	flags []flagValue
+	// getenv is replaced in tests
+	getenv func(string) string
+}
+
+type flagValue struct {
+	setting setting
+	value   string
+}
+
+// NewLoader registers -config and the setting flags on fs, fs may be nil to load without flags.
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{getenv: os.Getenv}
+	if fs == nil {
+		return l
+	}
+	fs.StringVar(&l.file, "config", "", "YAML or TOML config file, $"+FileEnv+" by default")
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		s := s
+		usage := fmt.Sprintf("%s ($%s)", s.usage, s.env)
+		record := func(value string) error {
+			l.flags = append(l.flags, flagValue{setting: s, value: value})
+			return nil
+		}
+		// feature toggles are boolean flags, -feature-openapi=false turns one off
+		if strings.HasPrefix(s.flag, "feature-") {
+			fs.BoolFunc(s.flag, usage, func(value string) error {
+				if _, err := strconv.ParseBool(value); err != nil {
+					return err
+				}
+				return record(value)
+			})
+			continue
+		}
+		fs.Func(s.flag, usage, record)
+	}
+	return l
+}
+
+func (l *Loader) Load() (Config, error) {
+	cfg := Default()
+	file := l.file
+	if file == "" {
+		file = l.getenv(FileEnv)
+	}
+	if file != "" {
+		if err := readFile(file, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+	for _, s := range settings {
+		value := l.getenv(s.env)
+		if value == "" {
+			continue
+		}
+		if err := s.set(&cfg, value); err != nil {
+			return cfg, fmt.Errorf("environment variable %s: %w", s.env, err)
+		}
+	}
+	for _, f := range l.flags {
+		if err := f.setting.set(&cfg, f.value); err != nil {
+			return cfg, fmt.Errorf("flag -%s: %w", f.setting.flag, err)
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// readFile decodes the file over cfg, keys missing from the file keep their value.
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(cfg)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func setString(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		*field(c) = value
+		return nil
+	}
+}
+
+func setInt(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setFloat(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setBool(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+func setDuration(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, value string) error {
+		if err := field(c).UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("%q is not a duration like 10s or 1m", value)
+		}
+		return nil
+	}
+}
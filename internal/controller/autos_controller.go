@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+type ListAutosQuery struct {
+	Type         string `form:"type" binding:"max=255"`
+	Status       string `form:"status" binding:"omitempty,oneof=available rented retired"`
+	Location     string `form:"location" binding:"max=255"`
+	Make         string `form:"make" binding:"max=255"`
+	Model        string `form:"model" binding:"max=255"`
+	MinYear      int    `form:"min_year" binding:"omitempty,min=1900,max=2100"`
+	MaxYear      int    `form:"max_year" binding:"omitempty,min=1900,max=2100"`
+	MinSeats     int    `form:"min_seats" binding:"omitempty,min=1"`
+	FuelType     string `form:"fuel_type" binding:"omitempty,oneof=petrol diesel electric hybrid"`
+	Transmission string `form:"transmission" binding:"omitempty,oneof=manual automatic"`
+	// Features are repeated or comma separated tags, autos must have all of them
+	Features []string `form:"features" binding:"max=20"`
+	MinPrice *int     `form:"min_price" binding:"omitempty,min=0"`
+	MaxPrice *int     `form:"max_price" binding:"omitempty,min=0"`
+	// AvailableFrom and AvailableTo in YYYY-MM-DD format, a single day when only one is set
+	AvailableFrom string `form:"available_from" binding:"omitempty,datetime=2006-01-02"`
+	AvailableTo   string `form:"available_to" binding:"omitempty,datetime=2006-01-02"`
+	// Sort by id or price, descending with a leading minus
+	Sort   string `form:"sort" binding:"omitempty,oneof=id -id price -price"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+}
+
+type AutoResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	Location string `json:"location"`
+	// VIN and Plate are shown to agents and admins only
+	VIN          string   `json:"vin,omitempty"`
+	Plate        string   `json:"plate,omitempty"`
+	Make         string   `json:"make"`
+	Model        string   `json:"model"`
+	Year         int      `json:"year"`
+	Seats        int      `json:"seats"`
+	FuelType     string   `json:"fuel_type"`
+	Transmission string   `json:"transmission"`
+	Features     []string `json:"features"`
+	DailyPrice   int      `json:"daily_price"`
+}
+
+type AutoListResponse struct {
+	Items []AutoResponse `json:"items"`
+	// NextCursor is passed as cursor to get the next page, empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// autoCursorToken is the opaque cursor handed to clients, bound to the sort it was made for.
+type autoCursorToken struct {
+	Sort string `json:"sort"`
+	repository.AutoCursor
+}
+
+func (r RentalController) ListAutos(ctx *gin.Context) {
+	var input ListAutosQuery
+	if !respondBindingError(ctx, ctx.ShouldBindQuery(&input)) {
+		return
+	}
+	if input.Type != "" && !r.knownAutoType(ctx, "type", input.Type) {
+		return
+	}
+	query, fieldErrors := input.autoQuery()
+	if len(fieldErrors) != 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, newValidationErrorResponse(fieldErrors...))
+		return
+	}
+	autos, next, err := r.rentalService.ListAutos(ctx.Request.Context(), query)
+	if err != nil {
+		r.internalServerError(ctx, "listing autos failed", err)
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(ctx.Request.Context())
+	response := AutoListResponse{Items: make([]AutoResponse, 0, len(autos))}
+	for _, auto := range autos {
+		item := newAutoResponse(auto)
+		if principal.Role == auth.RoleCustomer {
+			item.VIN, item.Plate = "", ""
+		}
+		response.Items = append(response.Items, item)
+	}
+	if next != nil {
+		response.NextCursor = encodeAutoCursor(autoCursorToken{Sort: input.Sort, AutoCursor: *next})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func newAutoResponse(auto repository.AutoListing) AutoResponse {
+	features := []string(auto.Features)
+	if features == nil {
+		features = []string{}
+	}
+	return AutoResponse{
+		ID:           auto.ID,
+		Name:         auto.Name,
+		Type:         auto.Type,
+		Status:       auto.Status(),
+		Location:     auto.Location,
+		VIN:          auto.VIN,
+		Plate:        auto.Plate,
+		Make:         auto.Make,
+		Model:        auto.Model,
+		Year:         auto.Year,
+		Seats:        auto.Seats,
+		FuelType:     auto.FuelType,
+		Transmission: auto.Transmission,
+		Features:     features,
+		DailyPrice:   auto.DailyPrice,
+	}
+}
+
+// autoQuery checks the constraints between the parameters the binding can't express.
+func (q ListAutosQuery) autoQuery() (repository.AutoQuery, []FieldError) {
+	var fieldErrors []FieldError
+	query := repository.AutoQuery{
+		Type:         q.Type,
+		Status:       q.Status,
+		Location:     q.Location,
+		Make:         q.Make,
+		Model:        q.Model,
+		MinYear:      q.MinYear,
+		MaxYear:      q.MaxYear,
+		MinSeats:     q.MinSeats,
+		FuelType:     q.FuelType,
+		Transmission: q.Transmission,
+		MinPrice:     q.MinPrice,
+		MaxPrice:     q.MaxPrice,
+		SortBy:       strings.TrimPrefix(q.Sort, "-"),
+		Descending:   strings.HasPrefix(q.Sort, "-"),
+		Limit:        q.Limit,
+	}
+	if query.SortBy == "" {
+		query.SortBy = repository.AutoSortById
+	}
+	for _, features := range q.Features {
+		for _, feature := range strings.Split(features, ",") {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				query.Features = append(query.Features, feature)
+			}
+		}
+	}
+	if q.MinYear != 0 && q.MaxYear != 0 && q.MinYear > q.MaxYear {
+		fieldErrors = append(fieldErrors, FieldError{Field: "max_year", Reason: "must not be less than min_year"})
+	}
+	if q.MinPrice != nil && q.MaxPrice != nil && *q.MinPrice > *q.MaxPrice {
+		fieldErrors = append(fieldErrors, FieldError{Field: "max_price", Reason: "must not be less than min_price"})
+	}
+	if q.AvailableFrom != "" || q.AvailableTo != "" {
+		from, _ := time.Parse(dateLayout, q.AvailableFrom)
+		to, _ := time.Parse(dateLayout, q.AvailableTo)
+		if q.AvailableFrom == "" {
+			from = to
+		}
+		if q.AvailableTo == "" {
+			to = from
+		}
+		if to.Before(from) {
+			fieldErrors = append(fieldErrors, FieldError{Field: "available_to", Reason: "must not be before available_from"})
+		}
+		query.AvailableFrom, query.AvailableTo = &from, &to
+	}
+	if q.Cursor != "" {
+		token, ok := decodeAutoCursor(q.Cursor)
+		if !ok || token.Sort != q.Sort {
+			fieldErrors = append(fieldErrors, FieldError{Field: "cursor", Reason: "is invalid"})
+		}
+		query.After = &token.AutoCursor
+	}
+	return query, fieldErrors
+}
+
+func encodeAutoCursor(token autoCursorToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeAutoCursor(cursor string) (autoCursorToken, bool) {
+	var token autoCursorToken
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return token, false
+	}
+	if err = json.Unmarshal(data, &token); err != nil {
+		return token, false
+	}
+	return token, true
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ListExtrasQuery struct {
+	// Location adds what is left of the stocked extras at the location now
+	Location string `form:"location" binding:"max=255"`
+}
+
+type ExtraResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Pricing is day, the price is charged for every rent day, or rental, charged once
+	Pricing string `json:"pricing"`
+	Price   int    `json:"price"`
+	Stocked bool   `json:"stocked"`
+	// Available is the quantity left at the location, omitted for extras that aren't stocked
+	Available *int `json:"available,omitempty"`
+}
+
+type ExtraListResponse struct {
+	Items []ExtraResponse `json:"items"`
+}
+
+func (r RentalController) ListExtras(ctx *gin.Context) {
+	var query ListExtrasQuery
+	if !respondBindingError(ctx, ctx.ShouldBindQuery(&query)) {
+		return
+	}
+	extras, err := r.rentalService.ListExtras(ctx.Request.Context(), query.Location)
+	if err != nil {
+		r.internalServerError(ctx, "listing extras failed", err, "location", query.Location)
+		return
+	}
+	response := ExtraListResponse{Items: make([]ExtraResponse, 0, len(extras))}
+	for _, extra := range extras {
+		response.Items = append(response.Items, ExtraResponse{ID: extra.ID, Name: extra.Name,
+			Pricing: extra.Pricing, Price: extra.Price, Stocked: extra.Stocked, Available: extra.Available})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"car-rental/internal/health"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	readinessTimeout = 2 * time.Second
+	statusReady      = "ready"
+	statusNotReady   = "unavailable"
+)
+
+type HealthResponse struct {
+	Status string `json:"status"`
+	// Checks has "ok" or the failure of every readiness check
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type HealthController struct {
+	checker *health.Checker
+}
+
+func NewHealthController(checker *health.Checker) *HealthController {
+	return &HealthController{checker: checker}
+}
+
+// Liveness only tells the process serves requests, dependencies are left to Readiness.
+func (h HealthController) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, HealthResponse{Status: health.StatusOK})
+}
+
+func (h HealthController) Readiness(ctx *gin.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx.Request.Context(), readinessTimeout)
+	defer cancel()
+	ready, checks := h.checker.Ready(checkCtx)
+	if !ready {
+		ctx.JSON(http.StatusServiceUnavailable, HealthResponse{Status: statusNotReady, Checks: checks})
+		return
+	}
+	ctx.JSON(http.StatusOK, HealthResponse{Status: statusReady, Checks: checks})
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ListInsuranceQuery struct {
+	AutoType string `form:"auto_type" binding:"required,max=255"`
+}
+
+type InsurancePackageResponse struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	DailyPrice int    `json:"daily_price"`
+	// RentalPrice is charged once per rental, on top of the daily price
+	RentalPrice int `json:"rental_price"`
+	// Excess is the most of the repair cost of a damage charged to the client
+	Excess int `json:"excess"`
+}
+
+type InsuranceListResponse struct {
+	Items []InsurancePackageResponse `json:"items"`
+}
+
+func (r RentalController) ListInsurance(ctx *gin.Context) {
+	var query ListInsuranceQuery
+	if !respondBindingError(ctx, ctx.ShouldBindQuery(&query)) || !r.knownAutoType(ctx, "auto_type", query.AutoType) {
+		return
+	}
+	packages, err := r.rentalService.ListInsurance(ctx.Request.Context(), query.AutoType)
+	if err != nil {
+		r.internalServerError(ctx, "listing insurance packages failed", err, "auto_type", query.AutoType)
+		return
+	}
+	response := InsuranceListResponse{Items: make([]InsurancePackageResponse, 0, len(packages))}
+	for _, insurance := range packages {
+		response.Items = append(response.Items, InsurancePackageResponse{Code: insurance.Code, Name: insurance.Name,
+			DailyPrice: insurance.DailyPrice, RentalPrice: insurance.RentalPrice, Excess: insurance.Excess})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
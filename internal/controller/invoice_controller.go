@@ -0,0 +1,257 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/invoicing"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"car-rental/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const defaultInvoiceLimit = 20
+
+type ListInvoicesQuery struct {
+	RentID int `form:"rent_id" binding:"omitempty,min=1"`
+	// ClientId is ignored for customers, they see their own invoices
+	ClientId string `form:"client_id" binding:"max=255"`
+	Limit    int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type InvoiceParams struct {
+	ID int64 `uri:"id" binding:"min=1"`
+}
+
+type CreditNoteInput struct {
+	Reason string `json:"reason" binding:"required,max=2000"`
+	// Lines are the positions of the invoice lines to credit, every line not credited yet when empty
+	Lines []int `json:"lines" binding:"max=100,dive,min=1"`
+}
+
+type InvoiceLineResponse struct {
+	Position       int     `json:"position"`
+	CommissionType string  `json:"commission_type"`
+	Description    string  `json:"description"`
+	Quantity       int     `json:"quantity"`
+	UnitPrice      int     `json:"unit_price"`
+	Amount         int     `json:"amount"`
+	TaxRate        float64 `json:"tax_rate"`
+	// CreditedPosition is the invoice line a credit note line reverses
+	CreditedPosition int `json:"credited_position,omitempty"`
+}
+
+type InvoiceTaxResponse struct {
+	Name   string  `json:"name"`
+	Rate   float64 `json:"rate"`
+	Base   int     `json:"base"`
+	Amount int     `json:"amount"`
+}
+
+type IssuerResponse struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	TaxID   string `json:"tax_id,omitempty"`
+}
+
+type InvoiceResponse struct {
+	ID     int64  `json:"id"`
+	Number string `json:"number"`
+	// Kind is invoice or credit_note
+	Kind                  string                `json:"kind"`
+	CreditedInvoiceID     *int64                `json:"credited_invoice_id,omitempty"`
+	CreditedInvoiceNumber string                `json:"credited_invoice_number,omitempty"`
+	Reason                string                `json:"reason,omitempty"`
+	RentID                int                   `json:"rent_id"`
+	AutoID                string                `json:"auto_id"`
+	AutoType              string                `json:"auto_type"`
+	ClientID              string                `json:"client_id"`
+	Issuer                IssuerResponse        `json:"issuer"`
+	IssuedAt              time.Time             `json:"issued_at"`
+	Currency              string                `json:"currency"`
+	Lines                 []InvoiceLineResponse `json:"lines"`
+	Taxes                 []InvoiceTaxResponse  `json:"taxes"`
+	Net                   int                   `json:"net"`
+	Tax                   int                   `json:"tax"`
+	Gross                 int                   `json:"gross"`
+}
+
+type InvoiceListResponse struct {
+	Items []InvoiceResponse `json:"items"`
+}
+
+func newInvoiceResponse(invoice models.Invoice) InvoiceResponse {
+	response := InvoiceResponse{
+		ID:                    invoice.ID,
+		Number:                invoice.Number,
+		Kind:                  invoice.Kind,
+		CreditedInvoiceID:     invoice.CreditedInvoiceID,
+		CreditedInvoiceNumber: invoice.CreditedInvoiceNumber,
+		Reason:                invoice.Reason,
+		RentID:                invoice.RentID,
+		AutoID:                invoice.AutoID,
+		AutoType:              invoice.AutoType,
+		ClientID:              invoice.ClientID,
+		Issuer: IssuerResponse{
+			Name:    invoice.IssuerName,
+			Address: invoice.IssuerAddress,
+			TaxID:   invoice.IssuerTaxID,
+		},
+		IssuedAt: invoice.IssuedAt,
+		Currency: invoice.Currency,
+		Lines:    make([]InvoiceLineResponse, 0, len(invoice.Lines)),
+		Taxes:    make([]InvoiceTaxResponse, 0, len(invoice.Taxes)),
+		Net:      invoice.Net,
+		Tax:      invoice.Tax,
+		Gross:    invoice.Gross,
+	}
+	for _, line := range invoice.Lines {
+		response.Lines = append(response.Lines, InvoiceLineResponse{
+			Position:         line.Position,
+			CommissionType:   line.CommissionType,
+			Description:      line.Description,
+			Quantity:         line.Quantity,
+			UnitPrice:        line.UnitPrice,
+			Amount:           line.Amount,
+			TaxRate:          line.TaxRate,
+			CreditedPosition: line.CreditedPosition,
+		})
+	}
+	for _, tax := range invoice.Taxes {
+		response.Taxes = append(response.Taxes,
+			InvoiceTaxResponse{Name: tax.Name, Rate: tax.Rate, Base: tax.Base, Amount: tax.Amount})
+	}
+	return response
+}
+
+type InvoiceController struct {
+	invoiceService service.InvoiceService
+	logger         *slog.Logger
+}
+
+func NewInvoiceController(invoiceService service.InvoiceService, logger *slog.Logger) *InvoiceController {
+	return &InvoiceController{invoiceService: invoiceService, logger: logger}
+}
+
+func (i InvoiceController) ListInvoices(ctx *gin.Context) {
+	var input ListInvoicesQuery
+	if !respondBindingError(ctx, ctx.ShouldBindQuery(&input)) {
+		return
+	}
+	query := repository.InvoiceQuery{RentID: input.RentID, ClientID: input.ClientId, Limit: input.Limit}
+	if principal, _ := auth.PrincipalFromContext(ctx.Request.Context()); principal.Role == auth.RoleCustomer {
+		query.ClientID = principal.Subject
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultInvoiceLimit
+	}
+	invoices, err := i.invoiceService.ListInvoices(ctx.Request.Context(), query)
+	if err != nil {
+		i.internalServerError(ctx, "listing invoices failed", err)
+		return
+	}
+	response := InvoiceListResponse{Items: make([]InvoiceResponse, 0, len(invoices))}
+	for _, invoice := range invoices {
+		response.Items = append(response.Items, newInvoiceResponse(invoice))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (i InvoiceController) GetInvoice(ctx *gin.Context) {
+	invoice, ok := i.invoiceById(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newInvoiceResponse(invoice))
+}
+
+func (i InvoiceController) GetInvoiceHTML(ctx *gin.Context) {
+	i.render(ctx, "text/html; charset=utf-8", "", invoicing.HTML)
+}
+
+// GetInvoicePDF serves the PDF inline, named after the invoice number for downloads.
+func (i InvoiceController) GetInvoicePDF(ctx *gin.Context) {
+	i.render(ctx, "application/pdf", ".pdf", invoicing.PDF)
+}
+
+func (i InvoiceController) render(
+	ctx *gin.Context, contentType string, extension string, render func(io.Writer, models.Invoice) error) {
+	invoice, ok := i.invoiceById(ctx)
+	if !ok {
+		return
+	}
+	var document bytes.Buffer
+	if err := render(&document, invoice); err != nil {
+		i.internalServerError(ctx, "rendering invoice failed", err, "invoice_id", invoice.ID)
+		return
+	}
+	if extension != "" {
+		ctx.Header("Content-Disposition", `inline; filename="`+invoice.Number+extension+`"`)
+	}
+	ctx.Data(http.StatusOK, contentType, document.Bytes())
+}
+
+// CreateCreditNote corrects an invoice, the credit note is a new document with the reversed lines.
+func (i InvoiceController) CreateCreditNote(ctx *gin.Context) {
+	var params InvoiceParams
+	if !bindUri(ctx, &params) {
+		return
+	}
+	var input CreditNoteInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+	creditNote, err := i.invoiceService.IssueCreditNote(ctx.Request.Context(), params.ID, input.Lines, input.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, "invoice not found")
+		case err.Error() == service.NoSuchLineError:
+			ctx.JSON(http.StatusUnprocessableEntity,
+				newValidationErrorResponse(FieldError{Field: "lines", Reason: "must be positions of invoice lines"}))
+		case err.Error() == service.AlreadyCreditedError || err.Error() == service.CreditNoteError:
+			ctx.JSON(http.StatusConflict, err.Error())
+		default:
+			i.internalServerError(ctx, "issuing credit note failed", err, "invoice_id", params.ID)
+		}
+		return
+	}
+	ctx.Header("Location", "/api/v2/invoices/"+strconv.FormatInt(creditNote.ID, 10))
+	ctx.JSON(http.StatusCreated, newInvoiceResponse(creditNote))
+}
+
+// invoiceById finds the invoice of the path, customers only find their own.
+func (i InvoiceController) invoiceById(ctx *gin.Context) (models.Invoice, bool) {
+	var params InvoiceParams
+	if !bindUri(ctx, &params) {
+		return models.Invoice{}, false
+	}
+	invoice, err := i.invoiceService.GetInvoice(ctx.Request.Context(), params.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, "invoice not found")
+			return invoice, false
+		}
+		i.internalServerError(ctx, "finding invoice failed", err, "invoice_id", params.ID)
+		return invoice, false
+	}
+	principal, _ := auth.PrincipalFromContext(ctx.Request.Context())
+	if principal.Role == auth.RoleCustomer && invoice.ClientID != principal.Subject {
+		ctx.JSON(http.StatusNotFound, "invoice not found")
+		return models.Invoice{}, false
+	}
+	return invoice, true
+}
+
+func (i InvoiceController) internalServerError(ctx *gin.Context, msg string, err error, attrs ...any) {
+	i.logger.ErrorContext(ctx.Request.Context(), msg, append(attrs, slog.Any("error", err))...)
+	ctx.JSON(500, internalError)
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/models"
+	"car-rental/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultLoyaltyLimit = 20
+
+type LoyaltyParams struct {
+	ClientId string `uri:"client_id" binding:"required,max=255"`
+}
+
+type LoyaltyQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type LoyaltyTierResponse struct {
+	Name      string `json:"name"`
+	MinPoints int    `json:"min_points"`
+	// Waived are the commission types the tier waives
+	Waived []string `json:"waived"`
+}
+
+type LoyaltyEntryResponse struct {
+	ID int64 `json:"id"`
+	// Kind is earn, redeem, expire or adjust
+	Kind      string     `json:"kind"`
+	Points    int        `json:"points"`
+	RentID    *int       `json:"rent_id,omitempty"`
+	Note      string     `json:"note,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type LoyaltyResponse struct {
+	ClientId string `json:"client_id"`
+	Balance  int    `json:"balance"`
+	// Earned are the points earned over all rentals, the tier is reached with them
+	Earned int `json:"earned"`
+	// Tier is omitted below every tier
+	Tier *LoyaltyTierResponse `json:"tier,omitempty"`
+	// PointValue is what a redeemed point takes off the checkout
+	PointValue int                    `json:"point_value"`
+	Entries    []LoyaltyEntryResponse `json:"entries"`
+}
+
+func newLoyaltyEntryResponse(entry models.LoyaltyEntry) LoyaltyEntryResponse {
+	return LoyaltyEntryResponse{
+		ID:        entry.ID,
+		Kind:      entry.Kind,
+		Points:    entry.Points,
+		RentID:    entry.RentID,
+		Note:      entry.Note,
+		ExpiresAt: entry.ExpiresAt,
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+type LoyaltyController struct {
+	loyaltyService service.LoyaltyService
+	logger         *slog.Logger
+}
+
+func NewLoyaltyController(loyaltyService service.LoyaltyService, logger *slog.Logger) *LoyaltyController {
+	return &LoyaltyController{loyaltyService: loyaltyService, logger: logger}
+}
+
+// GetAccount answers the balance, tier and newest entries of the client, customers only see their own.
+func (l LoyaltyController) GetAccount(ctx *gin.Context) {
+	var params LoyaltyParams
+	if !bindUri(ctx, &params) {
+		return
+	}
+	var query LoyaltyQuery
+	if !respondBindingError(ctx, ctx.ShouldBindQuery(&query)) {
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(ctx.Request.Context())
+	if principal.Role == auth.RoleCustomer && params.ClientId != principal.Subject {
+		ctx.JSON(http.StatusNotFound, "client not found")
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultLoyaltyLimit
+	}
+	account, err := l.loyaltyService.Account(ctx.Request.Context(), params.ClientId, query.Limit)
+	if err != nil {
+		l.logger.ErrorContext(ctx.Request.Context(), "reading loyalty account failed",
+			slog.String("client_id", params.ClientId), slog.Any("error", err))
+		ctx.JSON(500, internalError)
+		return
+	}
+	response := LoyaltyResponse{
+		ClientId:   account.ClientID,
+		Balance:    account.Balance,
+		Earned:     account.Earned,
+		PointValue: l.loyaltyService.Program().PointValue,
+		Entries:    make([]LoyaltyEntryResponse, 0, len(account.Entries)),
+	}
+	if account.Tier.Name != "" {
+		response.Tier = &LoyaltyTierResponse{Name: account.Tier.Name, MinPoints: account.Tier.MinPoints,
+			Waived: append([]string{}, account.Tier.Waived...)}
+	}
+	for _, entry := range account.Entries {
+		response.Entries = append(response.Entries, newLoyaltyEntryResponse(entry))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
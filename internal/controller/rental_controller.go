@@ -2,101 +2,266 @@ package controller
 
 import (
 	"errors"
+	"log/slog"
 	"net/http"
+	"slices"
 	"time"
 
+	"car-rental/internal/auth"
+	"car-rental/internal/models"
 	"car-rental/internal/service"
+	"car-rental/internal/tax"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 const internalError = "internal server error"
 
+// ErrorResponse is returned when the request body can't be read.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// TotalsResponse is an amount before and after tax.
+type TotalsResponse struct {
+	Net   int `json:"net"`
+	Tax   int `json:"tax"`
+	Gross int `json:"gross"`
+}
+
+func newTotalsResponse(totals tax.Totals) TotalsResponse {
+	return TotalsResponse{Net: totals.Net, Tax: totals.Tax, Gross: totals.Gross}
+}
+
+func newCheckoutTotals(rent models.AutoRent) TotalsResponse {
+	return TotalsResponse{Net: rent.Checkout, Tax: rent.CheckoutTax, Gross: rent.Checkout + rent.CheckoutTax}
+}
+
+// CheckoutResponse keeps the net checkout, the totals add the tax. The discount is already taken off the checkout.
+type CheckoutResponse struct {
+	Checkout int            `json:"checkout"`
+	Discount int            `json:"discount"`
+	Totals   TotalsResponse `json:"totals"`
+}
+
+// CommissionResponse keeps the net commission and insurance, the totals add their tax.
+type CommissionResponse struct {
+	Commission       int            `json:"commission"`
+	Insurance        int            `json:"insurance"`
+	CommissionTotals TotalsResponse `json:"commission_totals"`
+	InsuranceTotals  TotalsResponse `json:"insurance_totals"`
+}
+
 type RentalController struct {
 	rentalService service.RentalService
+	logger        *slog.Logger
+}
+
+func NewRentalController(rentalService service.RentalService, logger *slog.Logger) *RentalController {
+	return &RentalController{rentalService: rentalService, logger: logger}
+}
+
+// knownAutoType answers 422 on the field when the auto type isn't known and 500 when looking it up failed.
+func (r RentalController) knownAutoType(ctx *gin.Context, field string, autoType string) bool {
+	autoTypes, err := r.rentalService.GetAutoTypes(ctx.Request.Context())
+	if err != nil {
+		r.internalServerError(ctx, "looking up auto types failed", err, "auto_type", autoType)
+		return false
+	}
+	if !slices.Contains(autoTypes, autoType) {
+		ctx.JSON(http.StatusUnprocessableEntity,
+			newValidationErrorResponse(FieldError{Field: field, Reason: "is not a known auto type"}))
+		return false
+	}
+	return true
+}
+
+// internalServerError logs the cause of a failed request and answers with the generic message only.
+func (r RentalController) internalServerError(ctx *gin.Context, msg string, err error, attrs ...any) {
+	r.logger.ErrorContext(ctx.Request.Context(), msg, append(attrs, slog.Any("error", err))...)
+	ctx.JSON(500, internalError)
 }
 
-func NewRentalController(rentalService service.RentalService) *RentalController {
-	return &RentalController{rentalService: rentalService}
+type AutoTypeParams struct {
+	Type string `uri:"type" binding:"required,max=255"`
 }
 
 func (r RentalController) GetAvailableByType(ctx *gin.Context) {
-	autos, err := r.rentalService.GetAvailableAutoByType(ctx.Params.ByName("type"))
+	var params AutoTypeParams
+	if !bindUri(ctx, &params) || !r.knownAutoType(ctx, "type", params.Type) {
+		return
+	}
+	autos, err := r.rentalService.GetAvailableAutoByType(ctx.Request.Context(), params.Type)
 	if err != nil {
 		if err.Error() == service.NotFoundError {
 			ctx.JSON(404, err.Error())
 			return
 		}
-		ctx.JSON(500, internalError)
+		r.internalServerError(ctx, "listing available autos failed", err, "auto_type", params.Type)
 		return
 	}
 	ctx.JSON(200, autos)
 }
 
+// BindAuto is the v1 adapter of CreateRental.
 func (r RentalController) BindAuto(ctx *gin.Context) {
-	var input struct {
-		AutoId string `json:"auto_id"`
-		Days   int    `json:"days"`
+	var input CreateRentalInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+	if _, ok := r.startRental(ctx, input); !ok {
+		return
+	}
+	ctx.JSON(200, "ok")
+}
+
+// ReleaseAuto is the v1 adapter of ReturnRental, the auto is returned today.
+func (r RentalController) ReleaseAuto(ctx *gin.Context) {
+	rent, ok := r.activeRentByAuto(ctx, ctx.Params.ByName("auto_id"))
+	if !ok {
+		return
 	}
-	if err := ctx.ShouldBindJSON(&input); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	rent, ok = r.returnRental(ctx, rent, service.RentalReturn{ReturnDate: time.Now()})
+	if !ok {
 		return
 	}
-	if input.Days <= 0 {
-		ctx.JSON(403, "days must be positive")
+	ctx.JSON(200, CheckoutResponse{Checkout: rent.Checkout, Discount: rent.Discount, Totals: newCheckoutTotals(rent)})
+}
+
+func (r RentalController) GetCurrentCommission(ctx *gin.Context) {
+	if _, ok := r.activeRentByAuto(ctx, ctx.Params.ByName("auto_id")); !ok {
 		return
 	}
-	err := r.rentalService.BindAuto(input.AutoId, input.Days)
+	commission, insurance, err := r.rentalService.GetCurrentCommission(
+		ctx.Request.Context(), ctx.Params.ByName("auto_id"), time.Now())
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(404, "rent not found")
+			return
+		} else {
+			r.internalServerError(ctx, "calculating commission failed", err, "auto_id", ctx.Params.ByName("auto_id"))
+			return
+		}
+	}
+	ctx.JSON(200, CommissionResponse{
+		Commission:       commission.Net,
+		Insurance:        insurance.Net,
+		CommissionTotals: newTotalsResponse(commission),
+		InsuranceTotals:  newTotalsResponse(insurance),
+	})
+}
+
+func (r RentalController) startRental(ctx *gin.Context, input CreateRentalInput) (models.AutoRent, bool) {
+	if principal, _ := auth.PrincipalFromContext(ctx.Request.Context()); principal.Role == auth.RoleCustomer {
+		input.ClientId = principal.Subject
+	}
+	rent, err := r.rentalService.BindAuto(ctx.Request.Context(), input.AutoId, input.Days, input.ClientId,
+		service.RentalOptions{PromoCode: input.PromoCode, RedeemPoints: input.RedeemPoints, Extras: input.Extras,
+			Insurance: input.Insurance})
+	if err != nil {
+		var thresholdError service.ThresholdError
+		if errors.As(err, &thresholdError) {
+			ctx.JSON(http.StatusUnprocessableEntity,
+				newValidationErrorResponse(FieldError{Field: "days", Reason: thresholdError.Error()}))
+			return rent, false
+		}
+		var promoCodeError service.PromoCodeError
+		if errors.As(err, &promoCodeError) {
+			ctx.JSON(http.StatusUnprocessableEntity,
+				newValidationErrorResponse(FieldError{Field: "promo_code", Reason: promoCodeError.Error()}))
+			return rent, false
+		}
+		var loyaltyError service.LoyaltyError
+		if errors.As(err, &loyaltyError) {
+			ctx.JSON(http.StatusUnprocessableEntity,
+				newValidationErrorResponse(FieldError{Field: "redeem_points", Reason: loyaltyError.Error()}))
+			return rent, false
+		}
+		var extraError service.ExtraError
+		if errors.As(err, &extraError) {
+			ctx.JSON(http.StatusUnprocessableEntity,
+				newValidationErrorResponse(FieldError{Field: "extras", Reason: extraError.Error()}))
+			return rent, false
+		}
+		var insuranceError service.InsuranceError
+		if errors.As(err, &insuranceError) {
+			ctx.JSON(http.StatusUnprocessableEntity,
+				newValidationErrorResponse(FieldError{Field: "insurance", Reason: insuranceError.Error()}))
+			return rent, false
+		}
+		if r.paymentFailed(ctx, err) {
+			return rent, false
+		}
 		if errors.Is(err, gorm.ErrRecordNotFound) || err.Error() == service.NotFoundError {
 			ctx.JSON(404, "auto is not available")
-			return
+			return rent, false
 		} else if err.Error() == service.AlreadyRentedError {
 			ctx.JSON(400, err.Error())
-			return
-		} else if err.Error() == service.ThresholdValidationError {
-			ctx.JSON(403, err.Error())
-			return
+			return rent, false
 		}
-		ctx.JSON(500, errors.New(internalError))
-		return
-
+		r.internalServerError(ctx, "binding auto failed", err, "auto_id", input.AutoId, "days", input.Days,
+			"client_id", input.ClientId, "promo_code", input.PromoCode, "redeem_points", input.RedeemPoints)
+		return rent, false
 	}
-	ctx.JSON(200, "ok")
+	return rent, true
 }
-func (r RentalController) ReleaseAuto(ctx *gin.Context) {
-	checkout, err := r.rentalService.ReleaseAuto(ctx.Params.ByName("auto_id"), time.Now())
+
+func (r RentalController) returnRental(
+	ctx *gin.Context, rent models.AutoRent, rentalReturn service.RentalReturn) (models.AutoRent, bool) {
+	rent, err := r.rentalService.ReturnRent(ctx.Request.Context(), rent.ID, rentalReturn)
 	if err != nil {
+		if r.paymentFailed(ctx, err) {
+			return rent, false
+		}
+		var returnDateError service.ReturnDateError
+		if errors.As(err, &returnDateError) {
+			ctx.JSON(http.StatusUnprocessableEntity,
+				newValidationErrorResponse(FieldError{Field: "return_date", Reason: returnDateError.Error()}))
+			return rent, false
+		}
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			ctx.JSON(404, err.Error())
-			return
-		} else {
-			ctx.JSON(500, errors.New(internalError))
-			return
+			return rent, false
+		} else if err.Error() == service.AlreadyClosedError {
+			ctx.JSON(409, err.Error())
+			return rent, false
 		}
+		r.internalServerError(ctx, "returning rent failed", err, "rent_id", rent.ID, "auto_id", rent.AutoID)
+		return rent, false
 	}
-	if err != nil {
-		ctx.JSON(500, err.Error())
-		return
+	return rent, true
+}
+
+// paymentFailed answers 402 when the payment provider declined or failed, the rent is left as it was.
+func (r RentalController) paymentFailed(ctx *gin.Context, err error) bool {
+	var paymentError service.PaymentError
+	if !errors.As(err, &paymentError) {
+		return false
 	}
-	ctx.JSON(200, gin.H{
-		"checkout": checkout,
-	})
+	ctx.JSON(http.StatusPaymentRequired, err.Error())
+	return true
 }
 
-func (r RentalController) GetCurrentCommission(ctx *gin.Context) {
-	commission, insurance, err := r.rentalService.GetCurrentCommission(ctx.Params.ByName("auto_id"), time.Now())
+func (r RentalController) activeRentByAuto(ctx *gin.Context, autoId string) (models.AutoRent, bool) {
+	rent, err := r.rentalService.GetRentByAuto(ctx.Request.Context(), autoId)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			ctx.JSON(404, "rent not found")
-			return
-		} else {
-			ctx.JSON(500, errors.New("internal server error"))
-			return
+			return rent, false
 		}
+		r.internalServerError(ctx, "finding active rent failed", err, "auto_id", autoId)
+		return rent, false
 	}
-	ctx.JSON(200, gin.H{
-		"commission": commission,
-		"insurance":  insurance,
-	})
+	return rent, r.authorizeRent(ctx, rent)
+}
+
+// authorizeRent restricts customers to the rents they own. A rent of another client
+// is reported as not found, so customers can't probe which autos are rented.
+func (r RentalController) authorizeRent(ctx *gin.Context, rent models.AutoRent) bool {
+	principal, _ := auth.PrincipalFromContext(ctx.Request.Context())
+	if principal.Role == auth.RoleCustomer && rent.ClientID != principal.Subject {
+		ctx.JSON(404, "rent not found")
+		return false
+	}
+	return true
 }
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/models"
+	"car-rental/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const dateLayout = "2006-01-02"
+
+type CreateRentalInput struct {
+	AutoId string `json:"auto_id" binding:"required,max=255"`
+	Days   int    `json:"days" binding:"min=1"`
+	// ClientId lets agents and admins rent on behalf of a client, customers always rent for themselves
+	ClientId string `json:"client_id" binding:"max=255"`
+	// PromoCode is redeemed when the rental starts, its discount is taken off the checkout
+	PromoCode string `json:"promo_code" binding:"max=64"`
+	// RedeemPoints are taken off the loyalty balance when the rental starts and off the checkout at the return,
+	// those the checkout doesn't use are credited back
+	RedeemPoints int `json:"redeem_points" binding:"min=0"`
+	// Extras are the quantities of the extras rented with the auto by extra id, like {"child-seat": 1}
+	Extras map[string]int `json:"extras" binding:"max=10,dive,keys,max=64,endkeys,min=1,max=20"`
+	// Insurance is the code of an insurance package of the auto type, charged per day and capping the damage charged
+	Insurance string `json:"insurance" binding:"max=64"`
+}
+
+type ReturnRentalInput struct {
+	// ReturnDate in YYYY-MM-DD format from the start of the rental to today, today when empty.
+	// Only agents and admins set it, customers return autos today.
+	ReturnDate string `json:"return_date" binding:"omitempty,datetime=2006-01-02"`
+	Odometer   int    `json:"odometer" binding:"min=0"`
+	Notes      string `json:"notes" binding:"max=2000"`
+	// Damage is the repair cost of the damage found, charged up to the excess of the insurance of the rental.
+	// Only agents and admins report it.
+	Damage int `json:"damage" binding:"min=0"`
+}
+
+type RentalResponse struct {
+	ID         int     `json:"id"`
+	AutoId     string  `json:"auto_id"`
+	ClientId   string  `json:"client_id"`
+	StartDate  string  `json:"start_date"`
+	EndDate    string  `json:"end_date"`
+	Status     string  `json:"status"`
+	ReturnDate *string `json:"return_date,omitempty"`
+	Odometer   int     `json:"odometer,omitempty"`
+	Notes      string  `json:"notes,omitempty"`
+	PromoCode  string  `json:"promo_code,omitempty"`
+	// DemandPercent of the daily prices is charged, by the demand when the rental started
+	DemandPercent int `json:"demand_percent,omitempty"`
+	// LoyaltyTier of the client when the rental started, its benefits apply to the checkout
+	LoyaltyTier    string `json:"loyalty_tier,omitempty"`
+	RedeemedPoints int    `json:"redeemed_points,omitempty"`
+	// Insurance is the package of the rental, at the prices and excess it had when the rental started
+	Insurance            string `json:"insurance,omitempty"`
+	InsuranceDailyPrice  int    `json:"insurance_daily_price,omitempty"`
+	InsuranceRentalPrice int    `json:"insurance_rental_price,omitempty"`
+	InsuranceExcess      *int   `json:"insurance_excess,omitempty"`
+	Damage               int    `json:"damage,omitempty"`
+	Checkout             *int   `json:"checkout,omitempty"`
+	// Discount of the long rental tier, the promo code and the loyalty points, already taken off the checkout
+	Discount *int `json:"discount,omitempty"`
+	// CheckoutTotals add the tax to the checkout of a closed rental
+	CheckoutTotals *TotalsResponse `json:"checkout_totals,omitempty"`
+}
+
+func newRentalResponse(rent models.AutoRent) RentalResponse {
+	response := RentalResponse{
+		ID:             rent.ID,
+		AutoId:         rent.AutoID,
+		ClientId:       rent.ClientID,
+		StartDate:      rent.StartDate.Format(dateLayout),
+		EndDate:        rent.EndDate.Format(dateLayout),
+		Status:         rent.Status,
+		Odometer:       rent.Odometer,
+		Notes:          rent.Notes,
+		PromoCode:      rent.PromoCode,
+		DemandPercent:  rent.DemandPercent,
+		LoyaltyTier:    rent.LoyaltyTier,
+		RedeemedPoints: rent.RedeemedPoints,
+		Damage:         rent.Damage,
+	}
+	if rent.Insurance != "" {
+		excess := rent.InsuranceExcess
+		response.Insurance, response.InsuranceDailyPrice = rent.Insurance, rent.InsuranceDailyPrice
+		response.InsuranceRentalPrice = rent.InsuranceRentalPrice
+		response.InsuranceExcess = &excess
+	}
+	if rent.Status == models.RentStatusClosed {
+		checkout, discount, totals := rent.Checkout, rent.Discount, newCheckoutTotals(rent)
+		response.Checkout = &checkout
+		response.Discount = &discount
+		response.CheckoutTotals = &totals
+	}
+	if rent.ReturnedAt != nil {
+		returnDate := rent.ReturnedAt.Format(dateLayout)
+		response.ReturnDate = &returnDate
+	}
+	return response
+}
+
+func (r RentalController) CreateRental(ctx *gin.Context) {
+	var input CreateRentalInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+	rent, ok := r.startRental(ctx, input)
+	if !ok {
+		return
+	}
+	ctx.Header("Location", "/api/v2/rentals/"+strconv.Itoa(rent.ID))
+	ctx.JSON(http.StatusCreated, newRentalResponse(rent))
+}
+
+func (r RentalController) GetRental(ctx *gin.Context) {
+	rent, ok := r.rentalById(ctx)
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newRentalResponse(rent))
+}
+
+func (r RentalController) ReturnRental(ctx *gin.Context) {
+	var input ReturnRentalInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+	if principal, _ := auth.PrincipalFromContext(ctx.Request.Context()); principal.Role == auth.RoleCustomer {
+		// customers return autos today and the damage is assessed by the staff
+		var fieldErrors []FieldError
+		if input.ReturnDate != "" {
+			fieldErrors = append(fieldErrors, FieldError{Field: "return_date", Reason: "can only be set by agents and admins"})
+		}
+		if input.Damage != 0 {
+			fieldErrors = append(fieldErrors, FieldError{Field: "damage", Reason: "can only be set by agents and admins"})
+		}
+		if len(fieldErrors) != 0 {
+			ctx.JSON(http.StatusUnprocessableEntity, newValidationErrorResponse(fieldErrors...))
+			return
+		}
+	}
+	var returnDate time.Time
+	if input.ReturnDate != "" {
+		// already validated by the binding
+		returnDate, _ = time.Parse(dateLayout, input.ReturnDate)
+	}
+	rent, ok := r.rentalById(ctx)
+	if !ok {
+		return
+	}
+	rent, ok = r.returnRental(ctx, rent, service.RentalReturn{
+		ReturnDate: returnDate,
+		Odometer:   input.Odometer,
+		Notes:      input.Notes,
+		Damage:     input.Damage,
+	})
+	if !ok {
+		return
+	}
+	ctx.JSON(http.StatusOK, newRentalResponse(rent))
+}
+
+func (r RentalController) rentalById(ctx *gin.Context) (models.AutoRent, bool) {
+	rentId, err := strconv.Atoi(ctx.Params.ByName("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: "rental id must be a number"})
+		return models.AutoRent{}, false
+	}
+	rent, err := r.rentalService.GetRent(ctx.Request.Context(), rentId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(404, "rent not found")
+			return rent, false
+		}
+		r.internalServerError(ctx, "finding rent failed", err, "rent_id", rentId)
+		return rent, false
+	}
+	return rent, r.authorizeRent(ctx, rent)
+}
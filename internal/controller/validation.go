@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"car-rental/internal/events"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const validationFailed = "VALIDATION_FAILED"
+
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ValidationErrorResponse lists every invalid field of a request.
+type ValidationErrorResponse struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func newValidationErrorResponse(errs ...FieldError) ValidationErrorResponse {
+	return ValidationErrorResponse{Code: validationFailed, Message: "request is invalid", Errors: errs}
+}
+
+// RegisterValidations makes gin's validator report fields by their json, uri or form name and adds the
+// eventtype tag. It changes the validator of every gin engine, so it is called once at startup.
+// Auto types are looked up per request instead, see RentalController.knownAutoType.
+func RegisterValidations() error {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("gin validator is not go-playground/validator")
+	}
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "uri", "form"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	return engine.RegisterValidation("eventtype", func(fl validator.FieldLevel) bool {
+		return slices.Contains(events.Types, fl.Field().String())
+	})
+}
+
+// bindJSON binds and validates the request body, responding 400 to malformed JSON
+// and 422 to invalid fields.
+func bindJSON(ctx *gin.Context, obj interface{}) bool {
+	return respondBindingError(ctx, ctx.ShouldBindJSON(obj))
+}
+
+func bindUri(ctx *gin.Context, obj interface{}) bool {
+	return respondBindingError(ctx, ctx.ShouldBindUri(obj))
+}
+
+func respondBindingError(ctx *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	var validationErrors validator.ValidationErrors
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrors):
+		fieldErrors := make([]FieldError, 0, len(validationErrors))
+		for _, fieldError := range validationErrors {
+			fieldErrors = append(fieldErrors, FieldError{Field: fieldError.Field(), Reason: reason(fieldError)})
+		}
+		ctx.JSON(http.StatusUnprocessableEntity, newValidationErrorResponse(fieldErrors...))
+	case errors.As(err, &typeError):
+		ctx.JSON(http.StatusUnprocessableEntity, newValidationErrorResponse(
+			FieldError{Field: typeError.Field, Reason: "must be " + typeError.Type.Kind().String()}))
+	default:
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+	return false
+}
+
+func reason(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters", fieldError.Param())
+		}
+		return "must be at least " + fieldError.Param()
+	case "max":
+		if fieldError.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters", fieldError.Param())
+		}
+		return "must be at most " + fieldError.Param()
+	case "datetime":
+		if fieldError.Param() == dateLayout {
+			return "must be a date in YYYY-MM-DD format"
+		}
+		return "must be a date in " + fieldError.Param() + " format"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fieldError.Param(), " ", ", ")
+	case "eventtype":
+		return "is not a known event type"
+	case "http_url":
+		return "must be an http or https URL"
+	}
+	return "is invalid"
+}
@@ -0,0 +1,243 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"car-rental/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const defaultDeliveryLimit = 50
+
+type CreateWebhookInput struct {
+	URL        string   `json:"url" binding:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,max=20,dive,eventtype"`
+	// ClientId limits the rental events sent to the ones of the client
+	ClientId string `json:"client_id" binding:"max=255"`
+}
+
+type WebhookParams struct {
+	ID int `uri:"id" binding:"min=1"`
+}
+
+type DeliveryParams struct {
+	ID         int   `uri:"id" binding:"min=1"`
+	DeliveryID int64 `uri:"delivery_id" binding:"min=1"`
+}
+
+type ListDeliveriesQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending delivered dead"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type WebhookResponse struct {
+	ID         int      `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	ClientId   string   `json:"client_id,omitempty"`
+	// Secret signs the deliveries, it is only shown when the subscription is created
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookListResponse struct {
+	Items []WebhookResponse `json:"items"`
+}
+
+type DeliveryResponse struct {
+	ID             int64      `json:"id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+type DeliveryListResponse struct {
+	Items []DeliveryResponse `json:"items"`
+}
+
+func newWebhookResponse(subscription models.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		ClientId:   subscription.ClientID,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+func newDeliveryResponse(delivery models.WebhookDelivery) DeliveryResponse {
+	response := DeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+	}
+	// only pending deliveries are attempted again
+	if delivery.Status == models.DeliveryStatusPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		response.NextAttemptAt = &nextAttemptAt
+	}
+	return response
+}
+
+type WebhookController struct {
+	webhookService service.WebhookService
+	logger         *slog.Logger
+}
+
+func NewWebhookController(webhookService service.WebhookService, logger *slog.Logger) *WebhookController {
+	return &WebhookController{webhookService: webhookService, logger: logger}
+}
+
+func (w WebhookController) CreateWebhook(ctx *gin.Context) {
+	var input CreateWebhookInput
+	if !bindJSON(ctx, &input) {
+		return
+	}
+	principal, _ := auth.PrincipalFromContext(ctx.Request.Context())
+	subscription, err := w.webhookService.CreateSubscription(ctx.Request.Context(), models.WebhookSubscription{
+		Owner:      principal.Subject,
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		ClientID:   input.ClientId,
+	})
+	if err != nil {
+		w.internalServerError(ctx, "creating webhook subscription failed", err)
+		return
+	}
+	response := newWebhookResponse(subscription)
+	response.Secret = subscription.Secret
+	ctx.Header("Location", "/api/v1/webhooks/"+strconv.Itoa(subscription.ID))
+	ctx.JSON(http.StatusCreated, response)
+}
+
+func (w WebhookController) ListWebhooks(ctx *gin.Context) {
+	subscriptions, err := w.webhookService.ListSubscriptions(ctx.Request.Context(), owner(ctx))
+	if err != nil {
+		w.internalServerError(ctx, "listing webhook subscriptions failed", err)
+		return
+	}
+	response := WebhookListResponse{Items: make([]WebhookResponse, 0, len(subscriptions))}
+	for _, subscription := range subscriptions {
+		response.Items = append(response.Items, newWebhookResponse(subscription))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (w WebhookController) GetWebhook(ctx *gin.Context) {
+	var params WebhookParams
+	if !bindUri(ctx, &params) {
+		return
+	}
+	subscription, err := w.webhookService.GetSubscription(ctx.Request.Context(), owner(ctx), params.ID)
+	if !w.respondError(ctx, err, "finding webhook subscription failed", "subscription_id", params.ID) {
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookResponse(subscription))
+}
+
+// DeleteWebhook answers with the deleted subscription, its pending deliveries are dropped.
+func (w WebhookController) DeleteWebhook(ctx *gin.Context) {
+	var params WebhookParams
+	if !bindUri(ctx, &params) {
+		return
+	}
+	subscription, err := w.webhookService.DeleteSubscription(ctx.Request.Context(), owner(ctx), params.ID)
+	if !w.respondError(ctx, err, "deleting webhook subscription failed", "subscription_id", params.ID) {
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookResponse(subscription))
+}
+
+func (w WebhookController) ListDeliveries(ctx *gin.Context) {
+	var params WebhookParams
+	if !bindUri(ctx, &params) {
+		return
+	}
+	var input ListDeliveriesQuery
+	if !respondBindingError(ctx, ctx.ShouldBindQuery(&input)) {
+		return
+	}
+	limit := input.Limit
+	if limit == 0 {
+		limit = defaultDeliveryLimit
+	}
+	deliveries, err := w.webhookService.ListDeliveries(ctx.Request.Context(), owner(ctx), repository.DeliveryQuery{
+		SubscriptionID: params.ID,
+		Status:         input.Status,
+		Limit:          limit,
+	})
+	if !w.respondError(ctx, err, "listing webhook deliveries failed", "subscription_id", params.ID) {
+		return
+	}
+	response := DeliveryListResponse{Items: make([]DeliveryResponse, 0, len(deliveries))}
+	for _, delivery := range deliveries {
+		response.Items = append(response.Items, newDeliveryResponse(delivery))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// RetryDelivery queues a dead delivery again.
+func (w WebhookController) RetryDelivery(ctx *gin.Context) {
+	var params DeliveryParams
+	if !bindUri(ctx, &params) {
+		return
+	}
+	delivery, err := w.webhookService.RedeliverDelivery(
+		ctx.Request.Context(), owner(ctx), params.ID, params.DeliveryID)
+	if err != nil && err.Error() == service.NotDeadError {
+		ctx.JSON(http.StatusConflict, err.Error())
+		return
+	}
+	if !w.respondError(ctx, err, "retrying webhook delivery failed",
+		"subscription_id", params.ID, "delivery_id", params.DeliveryID) {
+		return
+	}
+	ctx.JSON(http.StatusOK, newDeliveryResponse(delivery))
+}
+
+// respondError answers 404 to subscriptions and deliveries that don't exist or belong to another owner.
+func (w WebhookController) respondError(ctx *gin.Context, err error, msg string, attrs ...any) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, "webhook not found")
+		return false
+	}
+	w.internalServerError(ctx, msg, err, attrs...)
+	return false
+}
+
+func (w WebhookController) internalServerError(ctx *gin.Context, msg string, err error, attrs ...any) {
+	w.logger.ErrorContext(ctx.Request.Context(), msg, append(attrs, slog.Any("error", err))...)
+	ctx.JSON(500, internalError)
+}
+
+// owner scopes the webhooks to the principal, admins manage the webhooks of every partner.
+func owner(ctx *gin.Context) string {
+	principal, _ := auth.PrincipalFromContext(ctx.Request.Context())
+	if principal.Role == auth.RoleAdmin {
+		return ""
+	}
+	return principal.Subject
+}
@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"car-rental/internal/repository"
+)
+
+const (
+	maxRetryDelay   = time.Hour
+	cleanupInterval = time.Hour
+)
+
+type DispatcherOptions struct {
+	// Interval is how often the outbox is polled, and the delay of the first retry
+	Interval  time.Duration
+	BatchSize int
+	// Retention is how long published events are kept, 0 keeps them
+	Retention time.Duration
+}
+
+// Dispatcher publishes the events of the outbox to every sink. Several dispatchers may run against
+// one database, each event is locked by one of them while it is published.
+type Dispatcher struct {
+	transactor repository.Transactor
+	outbox     repository.OutboxRepository
+	sinks      []Sink
+	options    DispatcherOptions
+	logger     *slog.Logger
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func NewDispatcher(transactor repository.Transactor, outbox repository.OutboxRepository,
+	options DispatcherOptions, logger *slog.Logger, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		transactor: transactor,
+		outbox:     outbox,
+		sinks:      sinks,
+		options:    options,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Run publishes the pending events until ctx is cancelled. Delivery is at least once: the events
+// of a batch interrupted by shutdown, and failed events, are published again to every sink.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+	var cleaned time.Time
+	for {
+		handled, err := d.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "dispatching events failed", slog.Any("error", err))
+		}
+		if d.options.Retention > 0 && d.now().Sub(cleaned) >= cleanupInterval {
+			d.deletePublished(ctx)
+			cleaned = d.now()
+		}
+		// a full batch means more events are waiting
+		if err == nil && handled == d.options.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch publishes a batch of the events due and returns how many were handled, published or postponed.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	handled := 0
+	err := d.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		records, err := d.outbox.LockPendingEvents(ctx, d.now(), d.options.BatchSize)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			event := FromOutbox(record)
+			if err = d.publish(ctx, event); err != nil {
+				delay := retryDelay(d.options.Interval, record.Attempts)
+				d.logger.WarnContext(ctx, "publishing event failed",
+					slog.Int64("event_id", event.ID), slog.String("event_type", event.Type),
+					slog.Int("attempt", record.Attempts+1), slog.Duration("retry_in", delay), slog.Any("error", err))
+				err = d.outbox.MarkFailed(ctx, record.ID, err.Error(), d.now().Add(delay))
+			} else {
+				err = d.outbox.MarkPublished(ctx, record.ID, d.now())
+			}
+			if err != nil {
+				return err
+			}
+			handled++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return handled, nil
+}
+
+func (d *Dispatcher) publish(ctx context.Context, event Event) error {
+	var errs []error
+	for _, sink := range d.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Dispatcher) deletePublished(ctx context.Context) {
+	deleted, err := d.outbox.DeletePublishedEvents(ctx, d.now().Add(-d.options.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "deleting published events failed", slog.Any("error", err))
+		}
+		return
+	}
+	if deleted > 0 {
+		d.logger.DebugContext(ctx, "published events deleted", slog.Int64("count", deleted))
+	}
+}
+
+// retryDelay doubles from the poll interval with every failed attempt, up to an hour.
+func retryDelay(interval time.Duration, attempts int) time.Duration {
+	delay := interval
+	for i := 0; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
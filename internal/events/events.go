@@ -0,0 +1,124 @@
+package events
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"car-rental/internal/models"
+)
+
+// Event types, consumers subscribe to them by name.
+const (
+	TypeRentalStarted     = "rental.started"
+	TypeRentalClosed      = "rental.closed"
+	TypeAutoStatusChanged = "auto.status_changed"
+	TypePricingChanged    = "pricing.changed"
+)
+
+// Types lists every event type.
+var Types = []string{TypeRentalStarted, TypeRentalClosed, TypeAutoStatusChanged, TypePricingChanged}
+
+// Payload is the data of a domain event.
+type Payload interface {
+	EventType() string
+	// AggregateID is the id of the rent, auto or auto type the event is about
+	AggregateID() string
+}
+
+// RentalStarted is recorded when an auto is bound.
+type RentalStarted struct {
+	RentID    int       `json:"rent_id"`
+	AutoID    string    `json:"auto_id"`
+	AutoType  string    `json:"auto_type"`
+	ClientID  string    `json:"client_id"`
+	Days      int       `json:"days"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+}
+
+func (e RentalStarted) EventType() string   { return TypeRentalStarted }
+func (e RentalStarted) AggregateID() string { return strconv.Itoa(e.RentID) }
+
+// RentalClosed is recorded when an auto is returned, with the checkout charged.
+type RentalClosed struct {
+	RentID     int       `json:"rent_id"`
+	AutoID     string    `json:"auto_id"`
+	AutoType   string    `json:"auto_type"`
+	ClientID   string    `json:"client_id"`
+	ReturnedAt time.Time `json:"returned_at"`
+	Odometer   int       `json:"odometer"`
+	Checkout   int       `json:"checkout"`
+	// CheckoutTax is the tax charged on top of the checkout
+	CheckoutTax int `json:"checkout_tax"`
+	// Penalty tells whether the checkout includes the early return penalty
+	Penalty bool `json:"penalty"`
+}
+
+func (e RentalClosed) EventType() string   { return TypeRentalClosed }
+func (e RentalClosed) AggregateID() string { return strconv.Itoa(e.RentID) }
+
+// AutoStatusChanged is recorded when an auto is rented, returned or retired,
+// From and To are statuses such as models.AutoStatusRented.
+type AutoStatusChanged struct {
+	AutoID   string `json:"auto_id"`
+	AutoType string `json:"auto_type"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+func (e AutoStatusChanged) EventType() string   { return TypeAutoStatusChanged }
+func (e AutoStatusChanged) AggregateID() string { return e.AutoID }
+
+// PricingChanged is recorded when a commission of an auto type is set.
+type PricingChanged struct {
+	AutoType     string `json:"auto_type"`
+	Commission   string `json:"commission"`
+	Value        int    `json:"value"`
+	MinThreshold int    `json:"min_threshold"`
+	// PreviousValue is nil when the auto type had no such commission
+	PreviousValue *int `json:"previous_value"`
+}
+
+func (e PricingChanged) EventType() string   { return TypePricingChanged }
+func (e PricingChanged) AggregateID() string { return e.AutoType }
+
+// Event is the envelope sinks publish. ID is the outbox id, consumers skip events they have seen by it.
+type Event struct {
+	ID          int64           `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// Decode unmarshals the data into the payload of the event type.
+func (e Event) Decode(payload Payload) error {
+	return json.Unmarshal(e.Data, payload)
+}
+
+// NewOutboxEvent is the outbox record of the payload.
+func NewOutboxEvent(payload Payload, occurredAt time.Time) (models.OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return models.OutboxEvent{}, err
+	}
+	return models.OutboxEvent{
+		Type:          payload.EventType(),
+		AggregateID:   payload.AggregateID(),
+		Payload:       string(data),
+		OccurredAt:    occurredAt,
+		NextAttemptAt: occurredAt,
+	}, nil
+}
+
+// FromOutbox is the event of an outbox record.
+func FromOutbox(record models.OutboxEvent) Event {
+	return Event{
+		ID:          record.ID,
+		Type:        record.Type,
+		AggregateID: record.AggregateID,
+		OccurredAt:  record.OccurredAt,
+		Data:        json.RawMessage(record.Payload),
+	}
+}
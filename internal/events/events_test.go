@@ -0,0 +1,179 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"car-rental/internal/logging"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+)
+
+// memoryOutbox keeps the outbox in memory, its transactions run the function as is.
+type memoryOutbox struct {
+	repository.OutboxRepository
+	events []models.OutboxEvent
+}
+
+func (m *memoryOutbox) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (m *memoryOutbox) LockPendingEvents(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var due []models.OutboxEvent
+	for _, event := range m.events {
+		if event.PublishedAt == nil && !event.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, event)
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryOutbox) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error {
+	event := m.find(id)
+	event.Attempts++
+	event.PublishedAt = &publishedAt
+	return nil
+}
+
+func (m *memoryOutbox) MarkFailed(ctx context.Context, id int64, reason string, nextAttemptAt time.Time) error {
+	event := m.find(id)
+	event.Attempts++
+	event.LastError = reason
+	event.NextAttemptAt = nextAttemptAt
+	return nil
+}
+
+func (m *memoryOutbox) find(id int64) *models.OutboxEvent {
+	for i := range m.events {
+		if m.events[i].ID == id {
+			return &m.events[i]
+		}
+	}
+	return nil
+}
+
+func newOutboxEvent(t *testing.T, id int64, payload Payload, occurredAt time.Time) models.OutboxEvent {
+	record, err := NewOutboxEvent(payload, occurredAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.ID = id
+	return record
+}
+
+func TestDispatch(t *testing.T) {
+	now := time.Date(2023, 10, 15, 12, 0, 0, 0, time.UTC)
+	outbox := &memoryOutbox{events: []models.OutboxEvent{
+		newOutboxEvent(t, 1, RentalStarted{RentID: 7, AutoID: "MINI", Days: 3}, now),
+		newOutboxEvent(t, 2, AutoStatusChanged{AutoID: "MINI", From: "available", To: "rented"}, now),
+	}}
+	bus := NewBus()
+	var started []RentalStarted
+	bus.Subscribe(TypeRentalStarted, func(ctx context.Context, event Event) error {
+		var payload RentalStarted
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		started = append(started, payload)
+		return nil
+	})
+	failing := errors.New("broker unavailable")
+	bus.Subscribe(TypeAutoStatusChanged, func(ctx context.Context, event Event) error { return failing })
+	dispatcher := NewDispatcher(outbox, outbox,
+		DispatcherOptions{Interval: time.Second, BatchSize: 10}, logging.Discard(), bus)
+	dispatcher.now = func() time.Time { return now }
+
+	handled, err := dispatcher.Dispatch(context.Background())
+	if err != nil || handled != 2 {
+		t.Fatalf("want both events handled, got %d %v", handled, err)
+	}
+	if len(started) != 1 || started[0].RentID != 7 || outbox.events[0].PublishedAt == nil {
+		t.Errorf("want the rental started published, got %v", started)
+	}
+	failed := outbox.events[1]
+	if failed.PublishedAt != nil || failed.Attempts != 1 || !strings.Contains(failed.LastError, "broker unavailable") {
+		t.Errorf("want the failed event kept for a retry, got %+v", failed)
+	}
+	if !failed.NextAttemptAt.Equal(now.Add(time.Second)) {
+		t.Errorf("want the retry after the interval, got %v", failed.NextAttemptAt)
+	}
+
+	// nothing is due until the retry
+	if handled, _ = dispatcher.Dispatch(context.Background()); handled != 0 {
+		t.Errorf("want the failed event postponed, got %d handled", handled)
+	}
+	now = now.Add(time.Second)
+	if _, err = dispatcher.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if outbox.events[1].Attempts != 2 || !outbox.events[1].NextAttemptAt.Equal(now.Add(2*time.Second)) {
+		t.Errorf("want the delay doubled, got %+v", outbox.events[1])
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempts, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if got := retryDelay(time.Second, attempts); got != want {
+			t.Errorf("attempt %d: want %v, got %v", attempts, want, got)
+		}
+	}
+	if got := retryDelay(time.Second, 100); got != maxRetryDelay {
+		t.Errorf("want the delay capped at %v, got %v", maxRetryDelay, got)
+	}
+}
+
+func TestBrokerSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewBrokerSink(NewWriterBroker(&out), "car-rental")
+	record := newOutboxEvent(t, 42, PricingChanged{AutoType: "special", Commission: "daily", Value: 250}, time.Now())
+	if err := sink.Publish(context.Background(), FromOutbox(record)); err != nil {
+		t.Fatal(err)
+	}
+	var message struct {
+		Subject string            `json:"subject"`
+		Headers map[string]string `json:"headers"`
+		Data    Event             `json:"data"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Subject != "car-rental.pricing.changed" || message.Headers["aggregate-id"] != "special" ||
+		message.Headers["event-id"] != "42" || message.Data.ID != 42 {
+		t.Errorf("unexpected message %+v", message)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	status := http.StatusNoContent
+	var received Event
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Event-Type") != TypeRentalClosed {
+			t.Errorf("want the event type header, got %q", r.Header.Get("X-Event-Type"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+	sink := NewWebhookSink(receiver.URL, time.Second)
+	event := FromOutbox(newOutboxEvent(t, 3, RentalClosed{RentID: 7, Checkout: 300}, time.Now()))
+
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	var closed RentalClosed
+	if err := received.Decode(&closed); err != nil || closed.Checkout != 300 {
+		t.Errorf("want the event delivered, got %+v %v", received, err)
+	}
+	status = http.StatusBadGateway
+	if err := sink.Publish(context.Background(), event); err == nil {
+		t.Error("want a 502 to fail the delivery")
+	}
+}
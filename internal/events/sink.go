@@ -0,0 +1,161 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	BrokerNone   = "none"
+	BrokerStdout = "stdout"
+
+	// AllEvents subscribes a bus handler to every event type
+	AllEvents = "*"
+)
+
+// Sink publishes events to their consumers. An event may be published again after a failure
+// or a restart, delivery is at least once.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event Event) error
+}
+
+// Handler handles an event published on the bus.
+type Handler func(ctx context.Context, event Event) error
+
+// Bus publishes events to handlers in the process.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[string][]Handler{}}
+}
+
+// Subscribe calls the handler for the events of the type, or of every type for AllEvents.
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+func (b *Bus) Name() string {
+	return "bus"
+}
+
+// Publish calls every handler of the event, in the order they subscribed, and fails with their errors.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[event.Type]...), b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+	var errs []error
+	for _, handler := range handlers {
+		errs = append(errs, handler(ctx, event))
+	}
+	return errors.Join(errs...)
+}
+
+// Broker is what NATS and Kafka clients have in common: a message published to a subject, or topic,
+// with headers. An adapter of the client library implements it, WriterBroker stands in locally.
+type Broker interface {
+	Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error
+}
+
+// BrokerSink publishes each event as JSON to the subject prefix.type, e.g. car-rental.rental.started.
+// The aggregate-id header is the key that keeps the events of a rent or an auto in one Kafka partition.
+type BrokerSink struct {
+	broker Broker
+	prefix string
+}
+
+func NewBrokerSink(broker Broker, prefix string) *BrokerSink {
+	return &BrokerSink{broker: broker, prefix: prefix}
+}
+
+func (s *BrokerSink) Name() string {
+	return "broker"
+}
+
+func (s *BrokerSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.broker.Publish(ctx, s.prefix+"."+event.Type, data, map[string]string{
+		"event-id":     strconv.FormatInt(event.ID, 10),
+		"event-type":   event.Type,
+		"aggregate-id": event.AggregateID,
+	})
+}
+
+// WriterBroker writes each message as a JSON line, standing in for a broker in development.
+type WriterBroker struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterBroker(w io.Writer) *WriterBroker {
+	return &WriterBroker{w: w}
+}
+
+func (b *WriterBroker) Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error {
+	line, err := json.Marshal(struct {
+		Subject string            `json:"subject"`
+		Headers map[string]string `json:"headers"`
+		Data    json.RawMessage   `json:"data"`
+	}{subject, headers, data})
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err = b.w.Write(append(line, '\n'))
+	return err
+}
+
+// WebhookSink POSTs every event as JSON to a URL, any status but 2xx fails the delivery.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Event-Type", event.Type)
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", res.Status)
+	}
+	return nil
+}
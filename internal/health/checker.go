@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusDraining = "draining"
+)
+
+// Check returns nil when the dependency it checks is usable.
+type Check func(ctx context.Context) error
+
+// Checker runs the readiness checks. Once draining it reports not ready,
+// so load balancers stop routing to an instance that is shutting down.
+type Checker struct {
+	names    []string
+	checks   []Check
+	draining atomic.Bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{}
+}
+
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks = append(c.checks, check)
+}
+
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Ready runs the checks concurrently, results has StatusOK or the failure for every check.
+func (c *Checker) Ready(ctx context.Context) (ready bool, results map[string]string) {
+	results = make(map[string]string, len(c.checks)+1)
+	errs := make([]error, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	ready = true
+	for i, err := range errs {
+		results[c.names[i]] = StatusOK
+		if err != nil {
+			results[c.names[i]] = err.Error()
+			ready = false
+		}
+	}
+	if c.draining.Load() {
+		results["shutdown"] = StatusDraining
+		ready = false
+	}
+	return ready, results
+}
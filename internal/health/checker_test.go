@@ -0,0 +1,32 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReady(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	ready, results := checker.Ready(context.Background())
+	if !ready || results["database"] != StatusOK {
+		t.Errorf("want ready, got %v %v", ready, results)
+	}
+
+	checker.Add("migrations", func(ctx context.Context) error { return errors.New("schema at version 5, want 6") })
+	ready, results = checker.Ready(context.Background())
+	if ready || results["migrations"] != "schema at version 5, want 6" || results["database"] != StatusOK {
+		t.Errorf("want not ready on the failing check, got %v %v", ready, results)
+	}
+}
+
+func TestReadyWhileDraining(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.Drain()
+	ready, results := checker.Ready(context.Background())
+	if ready || results["shutdown"] != StatusDraining {
+		t.Errorf("want not ready while draining, got %v %v", ready, results)
+	}
+}
@@ -0,0 +1,58 @@
+// Package invoicing numbers, taxes and renders the invoices of closed rents.
+package invoicing
+
+import (
+	"fmt"
+	"sort"
+
+	"car-rental/internal/models"
+	"car-rental/internal/tax"
+)
+
+// Settings are copied onto every invoice issued, later changes leave issued invoices as they are.
+type Settings struct {
+	IssuerName    string
+	IssuerAddress string
+	IssuerTaxID   string
+	Currency      string
+	// NumberPrefix and CreditNotePrefix start the numbers of invoices and credit notes, like INV-2023-000042
+	NumberPrefix     string
+	CreditNotePrefix string
+	TaxName          string
+}
+
+// Series is the numbering series of the prefix in the year, numbering restarts every year.
+func Series(prefix string, year int) string {
+	return fmt.Sprintf("%s-%d", prefix, year)
+}
+
+// Number formats the number taken from the series.
+func Number(series string, number int) string {
+	return fmt.Sprintf("%s-%06d", series, number)
+}
+
+// Taxes sums the lines per tax rate, the tax of each rate is rounded once.
+func Taxes(lines []models.InvoiceLine, name string) []models.InvoiceTax {
+	bases := map[float64]int{}
+	for _, line := range lines {
+		bases[line.TaxRate] += line.Amount
+	}
+	taxes := make([]models.InvoiceTax, 0, len(bases))
+	for rate, base := range bases {
+		taxes = append(taxes, models.InvoiceTax{Name: name, Rate: rate, Base: base, Amount: tax.Amount(base, rate)})
+	}
+	sort.Slice(taxes, func(i, j int) bool { return taxes[i].Rate < taxes[j].Rate })
+	return taxes
+}
+
+// Total sets the net, tax and gross amounts of the invoice from its lines and taxes.
+func Total(invoice *models.Invoice) {
+	invoice.Net, invoice.Tax = 0, 0
+	for _, line := range invoice.Lines {
+		invoice.Net += line.Amount
+	}
+	for _, tax := range invoice.Taxes {
+		invoice.Tax += tax.Amount
+	}
+	invoice.Gross = invoice.Net + invoice.Tax
+}
@@ -0,0 +1,128 @@
+package invoicing
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"car-rental/internal/models"
+)
+
+func testInvoice() models.Invoice {
+	invoice := models.Invoice{
+		Number:        "INV-2023-000042",
+		Kind:          models.InvoiceKindInvoice,
+		RentID:        7,
+		AutoID:        "MINI",
+		AutoType:      "standard",
+		ClientID:      "client-1",
+		IssuerName:    "Car Rental <GmbH>",
+		IssuerAddress: "Hauptstraße 1, Berlin",
+		Currency:      "EUR",
+		IssuedAt:      time.Date(2023, 10, 15, 12, 0, 0, 0, time.UTC),
+		Lines: []models.InvoiceLine{
+			{Position: 1, CommissionType: "daily", Description: "Rent of MINI (daily rate)", Quantity: 10,
+				UnitPrice: 200, Amount: 2000, TaxRate: 19},
+			{Position: 2, CommissionType: "penalty", Description: "Early return penalty", Quantity: 1,
+				UnitPrice: 33, Amount: 33, TaxRate: 19},
+			{Position: 3, CommissionType: "agreement", Description: "Agreement fee", Quantity: 1,
+				UnitPrice: 200, Amount: 200, TaxRate: 7},
+		},
+	}
+	invoice.Taxes = Taxes(invoice.Lines, "VAT")
+	Total(&invoice)
+	return invoice
+}
+
+func TestTaxes(t *testing.T) {
+	invoice := testInvoice()
+	// 19% of 2033 rounds 386.27 down, 7% of 200 is 14
+	want := []models.InvoiceTax{
+		{Name: "VAT", Rate: 7, Base: 200, Amount: 14},
+		{Name: "VAT", Rate: 19, Base: 2033, Amount: 386},
+	}
+	if fmt.Sprint(invoice.Taxes) != fmt.Sprint(want) {
+		t.Errorf("want taxes %v, got %v", want, invoice.Taxes)
+	}
+	if invoice.Net != 2233 || invoice.Tax != 400 || invoice.Gross != 2633 {
+		t.Errorf("want 2233 + 400 = 2633, got %d + %d = %d", invoice.Net, invoice.Tax, invoice.Gross)
+	}
+	// a credit note of every line reverses the tax exactly
+	var reversed []models.InvoiceLine
+	for _, line := range invoice.Lines {
+		line.Amount, line.UnitPrice = -line.Amount, -line.UnitPrice
+		reversed = append(reversed, line)
+	}
+	total := 0
+	for _, tax := range Taxes(reversed, "VAT") {
+		total += tax.Amount
+	}
+	if total != -invoice.Tax {
+		t.Errorf("want the reversed tax %d, got %d", -invoice.Tax, total)
+	}
+}
+
+func TestNumber(t *testing.T) {
+	if got := Number(Series("INV", 2023), 42); got != "INV-2023-000042" {
+		t.Errorf("want INV-2023-000042, got %s", got)
+	}
+}
+
+func TestAmount(t *testing.T) {
+	for amount, want := range map[int]string{0: "0", 999: "999", 1000: "1,000", -1234567: "-1,234,567"} {
+		if got := Amount(amount); got != want {
+			t.Errorf("Amount(%d): want %s, got %s", amount, want, got)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := HTML(&out, testInvoice()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<h1>Invoice INV-2023-000042</h1>", "Car Rental &lt;GmbH&gt;", "VAT 19% on 2,033",
+		"<strong>2,633 EUR</strong>"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("html misses %s:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	invoice := testInvoice()
+	// enough lines for a second page
+	for i := 4; i < 60; i++ {
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{Position: i, Description: "Extra (child seat)",
+			Quantity: 1, UnitPrice: 5, Amount: 5})
+	}
+	var out bytes.Buffer
+	if err := PDF(&out, invoice); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.String()
+	if !strings.HasPrefix(pdf, "%PDF-1.4\n") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Fatal("not a PDF document")
+	}
+	for _, want := range []string{"(Invoice INV-2023-000042) Tj", `(Hauptstra\337e 1, Berlin) Tj`,
+		`(Extra \(child seat\)) Tj`, "/Count 2"} {
+		if !strings.Contains(pdf, want) {
+			t.Errorf("pdf misses %s", want)
+		}
+	}
+	// every object of the cross-reference table starts where it says
+	xref := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(pdf, -1)
+	if len(xref) != 8 {
+		t.Fatalf("want 8 objects, got %d", len(xref))
+	}
+	for i, entry := range xref {
+		offset, _ := strconv.Atoi(entry[1])
+		if !strings.HasPrefix(pdf[offset:], fmt.Sprintf("%d 0 obj", i+1)) {
+			t.Errorf("object %d is not at offset %d", i+1, offset)
+		}
+	}
+}
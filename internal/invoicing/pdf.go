@@ -0,0 +1,164 @@
+package invoicing
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	pdfWidth  = 595
+	pdfHeight = 842
+	pdfMargin = 50
+)
+
+// pdfColumns are the left edges of the line table columns, the last four are right aligned to the next edge.
+var pdfColumns = []float64{pdfMargin, 75, 300, 365, 440, 480, pdfWidth - pdfMargin}
+
+// pdfDocument writes text in the standard Helvetica fonts, enough for an invoice without embedding fonts.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	// y is the baseline of the next line on the last page
+	y float64
+}
+
+func newPDF() *pdfDocument {
+	d := &pdfDocument{}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfHeight - pdfMargin
+}
+
+func (d *pdfDocument) space(points float64) {
+	d.y -= points
+}
+
+// text writes a line at x and moves below it.
+func (d *pdfDocument) text(x float64, size float64, bold bool, s string) {
+	d.advance(size)
+	d.put(x, size, bold, s)
+}
+
+// row writes a line of the table, cut to fit the description column.
+func (d *pdfDocument) row(bold bool, cells ...string) {
+	d.advance(10)
+	d.put(pdfColumns[0], 10, bold, cells[0])
+	d.put(pdfColumns[1], 10, bold, fit(cells[1], pdfColumns[2]-pdfColumns[1]-10, 10))
+	for i := 2; i < len(cells); i++ {
+		d.put(pdfColumns[i+1]-textWidth(cells[i], 10), 10, bold, cells[i])
+	}
+}
+
+// total writes a label with an amount right aligned to the table.
+func (d *pdfDocument) total(bold bool, label string, amount string) {
+	d.advance(10)
+	d.put(pdfColumns[2], 10, bold, label)
+	d.put(pdfColumns[len(pdfColumns)-1]-textWidth(amount, 10), 10, bold, amount)
+}
+
+func (d *pdfDocument) advance(size float64) {
+	d.y -= size * 1.4
+	if d.y < pdfMargin {
+		d.newPage()
+		d.y -= size * 1.4
+	}
+}
+
+func (d *pdfDocument) put(x float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.pages[len(d.pages)-1], "BT /%s %.0f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.y, escape(s))
+}
+
+// WriteTo writes the catalog, the page tree, the two fonts and a page with its content per page.
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	out.WriteString("%PDF-1.4\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfWidth, pdfHeight, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.WriteTo(w)
+}
+
+// escape encodes the text as a PDF string in WinAnsi, characters outside Latin-1 become ?.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// textWidth approximates the width of Helvetica text, exact for digits and the separators of amounts.
+func textWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			width += 0.556
+		case r == ',' || r == '.' || r == ' ':
+			width += 0.278
+		case r == '-':
+			width += 0.333
+		case r == '%':
+			width += 0.889
+		case r >= 'A' && r <= 'Z':
+			width += 0.667
+		default:
+			width += 0.5
+		}
+	}
+	return width * size
+}
+
+// fit cuts the text to the width, ending it with ... when cut.
+func fit(s string, width float64, size float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
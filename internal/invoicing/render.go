@@ -0,0 +1,131 @@
+package invoicing
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+
+	"car-rental/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"title":  Title,
+	"amount": Amount,
+	"rate":   Rate,
+	"date":   func(invoice models.Invoice) string { return invoice.IssuedAt.Format(dateLayout) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{title .}} {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 14px; margin: 40px; }
+table { border-collapse: collapse; width: 100%; margin-top: 24px; }
+th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+td.amount, th.amount { text-align: right; }
+.totals td { border: none; }
+</style>
+</head>
+<body>
+<h1>{{title .}} {{.Number}}</h1>
+<p>{{.IssuerName}}<br>{{.IssuerAddress}}{{if .IssuerTaxID}}<br>Tax ID {{.IssuerTaxID}}{{end}}</p>
+<p>Issued {{date .}}<br>Client {{.ClientID}}<br>Rent {{.RentID}}, auto {{.AutoID}} ({{.AutoType}})
+{{- if .CreditedInvoiceNumber}}<br>Corrects invoice {{.CreditedInvoiceNumber}}{{end}}
+{{- if .Reason}}<br>Reason: {{.Reason}}{{end}}</p>
+<table>
+<tr><th>#</th><th>Description</th><th class="amount">Quantity</th><th class="amount">Unit price</th>
+<th class="amount">Tax</th><th class="amount">Amount</th></tr>
+{{- range .Lines}}
+<tr><td>{{.Position}}</td><td>{{.Description}}</td><td class="amount">{{.Quantity}}</td>
+<td class="amount">{{amount .UnitPrice}}</td><td class="amount">{{rate .TaxRate}}</td>
+<td class="amount">{{amount .Amount}}</td></tr>
+{{- end}}
+</table>
+<table class="totals">
+<tr><td>Net</td><td class="amount">{{amount .Net}} {{.Currency}}</td></tr>
+{{- range .Taxes}}
+<tr><td>{{.Name}} {{rate .Rate}} on {{amount .Base}}</td><td class="amount">{{amount .Amount}} {{$.Currency}}</td></tr>
+{{- end}}
+<tr><td><strong>Total</strong></td><td class="amount"><strong>{{amount .Gross}} {{.Currency}}</strong></td></tr>
+</table>
+</body>
+</html>
+`))
+
+// HTML renders the invoice as a standalone page.
+func HTML(w io.Writer, invoice models.Invoice) error {
+	return htmlTemplate.Execute(w, invoice)
+}
+
+// PDF renders the invoice as an A4 document, continued on further pages when the lines don't fit.
+func PDF(w io.Writer, invoice models.Invoice) error {
+	doc := newPDF()
+	doc.text(pdfMargin, 18, true, Title(invoice)+" "+invoice.Number)
+	doc.space(8)
+	for _, line := range []string{invoice.IssuerName, invoice.IssuerAddress} {
+		doc.text(pdfMargin, 10, false, line)
+	}
+	if invoice.IssuerTaxID != "" {
+		doc.text(pdfMargin, 10, false, "Tax ID "+invoice.IssuerTaxID)
+	}
+	doc.space(10)
+	doc.text(pdfMargin, 10, false, "Issued "+invoice.IssuedAt.Format(dateLayout))
+	doc.text(pdfMargin, 10, false, "Client "+invoice.ClientID)
+	doc.text(pdfMargin, 10, false,
+		fmt.Sprintf("Rent %d, auto %s (%s)", invoice.RentID, invoice.AutoID, invoice.AutoType))
+	if invoice.CreditedInvoiceNumber != "" {
+		doc.text(pdfMargin, 10, false, "Corrects invoice "+invoice.CreditedInvoiceNumber)
+	}
+	if invoice.Reason != "" {
+		doc.text(pdfMargin, 10, false, "Reason: "+invoice.Reason)
+	}
+	doc.space(14)
+	doc.row(true, "#", "Description", "Quantity", "Unit price", "Tax", "Amount")
+	for _, line := range invoice.Lines {
+		doc.row(false, strconv.Itoa(line.Position), line.Description, strconv.Itoa(line.Quantity),
+			Amount(line.UnitPrice), Rate(line.TaxRate), Amount(line.Amount))
+	}
+	doc.space(10)
+	doc.total(false, "Net", Amount(invoice.Net)+" "+invoice.Currency)
+	for _, tax := range invoice.Taxes {
+		doc.total(false, fmt.Sprintf("%s %s on %s", tax.Name, Rate(tax.Rate), Amount(tax.Base)),
+			Amount(tax.Amount)+" "+invoice.Currency)
+	}
+	doc.total(true, "Total", Amount(invoice.Gross)+" "+invoice.Currency)
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// Title names the document, an invoice or a credit note.
+func Title(invoice models.Invoice) string {
+	if invoice.Kind == models.InvoiceKindCreditNote {
+		return "Credit note"
+	}
+	return "Invoice"
+}
+
+// Amount formats an amount with thousands separators, like 12,500.
+func Amount(amount int) string {
+	digits := strconv.Itoa(amount)
+	sign := ""
+	if amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
+
+// Rate formats a tax rate like 19% or 7.5%.
+func Rate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// SlowQueryThreshold is the duration above which a query is logged as a warning.
+const SlowQueryThreshold = 200 * time.Millisecond
+
+// GormLogger sends gorm's logs to slog: failed queries at error, slow queries at warn
+// and every other statement at debug. Missing records are the caller's business and not logged.
+type GormLogger struct {
+	logger *slog.Logger
+	level  gormlogger.LogLevel
+}
+
+func NewGormLogger(logger *slog.Logger) *GormLogger {
+	return &GormLogger{logger: logger, level: gormlogger.Info}
+}
+
+func (g *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *g
+	copied.level = level
+	return &copied
+}
+
+func (g *GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Info {
+		g.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Warn {
+		g.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if g.level >= gormlogger.Error {
+		g.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (g *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if g.level <= gormlogger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	level := slog.LevelDebug
+	msg := "query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && g.level >= gormlogger.Error:
+		level, msg = slog.LevelError, "query failed"
+	case elapsed > SlowQueryThreshold && g.level >= gormlogger.Warn:
+		level, msg = slog.LevelWarn, "slow query"
+	case g.level < gormlogger.Info:
+		return
+	}
+	if !g.logger.Enabled(ctx, level) {
+		return
+	}
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("elapsed", elapsed),
+	}
+	if level == slog.LevelError {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	g.logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"car-rental/internal/auth"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New builds the service logger. Records logged with a context carry its request id, trace id
+// and the authenticated principal, so every line of a request can be found and attributed.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("log level %q: %w", level, err)
+	}
+	options := &slog.HandlerOptions{Level: lvl}
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("log format %q must be json or text", format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// Discard drops every record, for tests and callers that don't log.
+func Discard() *slog.Logger {
+	return slog.New(discardHandler{})
+}
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request id, the trace and the principal found in the context to each record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			record.AddAttrs(slog.String("request_id", id))
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+		}
+		if principal, ok := auth.PrincipalFromContext(ctx); ok {
+			record.AddAttrs(slog.Group("actor",
+				slog.String("subject", principal.Subject),
+				slog.String("role", string(principal.Role))))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"car-rental/internal/auth"
+	"gorm.io/gorm"
+)
+
+func decodeLines(t *testing.T, logs *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestContextAttributes(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := New(&logs, "info", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = auth.WithPrincipal(ctx, auth.Principal{Subject: "ops", Role: auth.RoleAdmin})
+	logger.With("component", "test").InfoContext(ctx, "auto retired")
+	logger.Debug("hidden below the level")
+
+	records := decodeLines(t, &logs)
+	if len(records) != 1 {
+		t.Fatalf("want 1 record, got %d", len(records))
+	}
+	actor, _ := records[0]["actor"].(map[string]interface{})
+	if records[0]["request_id"] != "req-1" || actor["subject"] != "ops" || actor["role"] != "admin" ||
+		records[0]["component"] != "test" {
+		t.Errorf("want request id and actor added, got %v", records[0])
+	}
+}
+
+func TestNewRejectsUnknownSettings(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "loud", FormatJSON); err == nil {
+		t.Error("want an error for an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("want an error for an unknown format")
+	}
+}
+
+func TestGormLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger, err := New(&logs, "info", FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gormLogger := NewGormLogger(logger)
+	query := func() (string, int64) { return "SELECT 1", 1 }
+	ctx := context.Background()
+	gormLogger.Trace(ctx, time.Now(), query, nil)
+	gormLogger.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+	gormLogger.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	gormLogger.Trace(ctx, time.Now(), query, errors.New("relation does not exist"))
+
+	// plain queries are debug and missing records aren't failures, leaving the slow and the failed query
+	records := decodeLines(t, &logs)
+	if len(records) != 2 || records[0]["msg"] != "slow query" || records[1]["msg"] != "query failed" ||
+		records[1]["error"] != "relation does not exist" || records[1]["sql"] != "SELECT 1" {
+		t.Errorf("unexpected records %v", records)
+	}
+}
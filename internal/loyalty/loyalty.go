@@ -0,0 +1,90 @@
+// Package loyalty holds the programme clients earn points in on their rentals and the tiers their points reach.
+package loyalty
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Waivable are the commissions a tier can waive, those charged once per rent.
+var Waivable = []string{"agreement", "penalty"}
+
+// Tier is reached with MinPoints earned over all rentals and waives the commission types of its benefits.
+type Tier struct {
+	Name      string
+	MinPoints int
+	Waived    []string
+}
+
+// ParseTier reads a name:points[:commission+commission] entry, like gold:5000:agreement.
+func ParseTier(entry string) (Tier, error) {
+	parts := strings.Split(entry, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+		return Tier{}, errors.New("loyalty tiers must be name:points[:commission+commission]")
+	}
+	tier := Tier{Name: parts[0]}
+	var err error
+	if tier.MinPoints, err = strconv.Atoi(parts[1]); err != nil || tier.MinPoints <= 0 {
+		return Tier{}, fmt.Errorf("loyalty tier points %q must be a positive number", parts[1])
+	}
+	if len(parts) == 3 {
+		tier.Waived = strings.Split(parts[2], "+")
+		for _, commissionType := range tier.Waived {
+			if !slices.Contains(Waivable, commissionType) {
+				return Tier{}, fmt.Errorf("loyalty tier can't waive %q, only %s",
+					commissionType, strings.Join(Waivable, " or "))
+			}
+		}
+	}
+	return tier, nil
+}
+
+// Waives tells whether the tier waives the commission type.
+func (t Tier) Waives(commissionType string) bool {
+	return slices.Contains(t.Waived, commissionType)
+}
+
+// Program is the loyalty programme, the zero value earns nothing.
+type Program struct {
+	// EarnPercent of the checkout is earned in points when a rent is returned
+	EarnPercent int
+	// PointValue is what a redeemed point takes off the checkout
+	PointValue int
+	// ExpireAfter is how long earned points can be redeemed, 0 keeps them
+	ExpireAfter time.Duration
+	Tiers       []Tier
+}
+
+func (p Program) Enabled() bool {
+	return p.EarnPercent > 0
+}
+
+// Points earned on a checkout.
+func (p Program) Points(checkout int) int {
+	return max(checkout, 0) * p.EarnPercent / 100
+}
+
+// Tier is the tier with the most points up to earned, the zero Tier below every tier.
+func (p Program) Tier(earned int) Tier {
+	var reached Tier
+	for _, tier := range p.Tiers {
+		if tier.MinPoints <= earned && tier.MinPoints > reached.MinPoints {
+			reached = tier
+		}
+	}
+	return reached
+}
+
+// TierNamed returns the tier of the name, the zero Tier for one no longer in the programme.
+func (p Program) TierNamed(name string) Tier {
+	for _, tier := range p.Tiers {
+		if tier.Name == name {
+			return tier
+		}
+	}
+	return Tier{}
+}
@@ -0,0 +1,45 @@
+package loyalty
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTier(t *testing.T) {
+	program := Program{EarnPercent: 5, Tiers: []Tier{
+		{Name: "gold", MinPoints: 5000, Waived: []string{"agreement"}},
+		{Name: "silver", MinPoints: 1000},
+	}}
+	for _, test := range []struct {
+		earned int
+		want   string
+	}{
+		{0, ""},
+		{999, ""},
+		{1000, "silver"},
+		{7000, "gold"},
+	} {
+		if got := program.Tier(test.earned); got.Name != test.want {
+			t.Errorf("%d earned: want tier %q, got %q", test.earned, test.want, got.Name)
+		}
+	}
+	if !program.TierNamed("gold").Waives("agreement") || program.TierNamed("silver").Waives("agreement") {
+		t.Error("want only gold waiving the agreement")
+	}
+	if got := program.Points(2160); got != 108 {
+		t.Errorf("want 5%% of the checkout earned, got %d", got)
+	}
+}
+
+func TestParseTier(t *testing.T) {
+	tier, err := ParseTier("gold:5000:agreement+penalty")
+	if err != nil || tier.Name != "gold" || tier.MinPoints != 5000 ||
+		!slices.Equal(tier.Waived, []string{"agreement", "penalty"}) {
+		t.Errorf("unexpected tier %+v, %v", tier, err)
+	}
+	for _, entry := range []string{"gold", ":5000", "gold:many", "gold:0", "gold:5000:daily", "gold:1:agreement:x"} {
+		if _, err := ParseTier(entry); err == nil {
+			t.Errorf("want %q rejected", entry)
+		}
+	}
+}
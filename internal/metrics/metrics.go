@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "car_rental"
+
+// ActiveRents counts the active rents per auto type, it is called on every scrape.
+type ActiveRents func(ctx context.Context) (map[string]int, error)
+
+// activeRentsTimeout bounds the query of a scrape, collectors aren't given the request context.
+const activeRentsTimeout = 5 * time.Second
+
+// Metrics owns the registry served at /metrics. It records HTTP traffic, database
+// queries through GormPlugin and the rental business events of the service.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	dbQueryDuration     *prometheus.HistogramVec
+	dbQueryErrors       *prometheus.CounterVec
+
+	rentalsStarted      *prometheus.CounterVec
+	rentalsClosed       *prometheus.CounterVec
+	checkoutRevenue     *prometheus.CounterVec
+	penalties           *prometheus.CounterVec
+	thresholdRejections *prometheus.CounterVec
+}
+
+// New registers the collectors, activeRents may be nil to leave the active rents gauge out.
+func New(activeRents ActiveRents) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "http_requests_total",
+			Help: "HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "http_request_duration_seconds",
+			Help:    "HTTP request latency by method and route template.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "db_query_duration_seconds",
+			Help:    "Database query latency by operation and table.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation", "table"}),
+		dbQueryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "db_query_errors_total",
+			Help: "Failed database queries by operation and table, not found results excluded.",
+		}, []string{"operation", "table"}),
+		rentalsStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "rentals_started_total",
+			Help: "Autos bound by auto type.",
+		}, []string{"auto_type"}),
+		rentalsClosed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "rentals_closed_total",
+			Help: "Autos released by auto type.",
+		}, []string{"auto_type"}),
+		checkoutRevenue: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "checkout_revenue_total",
+			Help: "Sum of the checkouts of released autos by auto type.",
+		}, []string{"auto_type"}),
+		penalties: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "rental_penalties_total",
+			Help: "Releases charged the early return penalty by auto type.",
+		}, []string{"auto_type"}),
+		thresholdRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "rental_threshold_rejections_total",
+			Help: "Binds rejected for days out of the rent threshold by auto type.",
+		}, []string{"auto_type"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpRequestDuration, m.dbQueryDuration, m.dbQueryErrors,
+		m.rentalsStarted, m.rentalsClosed, m.checkoutRevenue, m.penalties, m.thresholdRejections,
+	)
+	if activeRents != nil {
+		m.registry.MustRegister(activeRentsCollector{count: activeRents})
+	}
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+func (m *Metrics) RentalStarted(autoType string) {
+	m.rentalsStarted.WithLabelValues(autoType).Inc()
+}
+
+func (m *Metrics) RentalClosed(autoType string, checkout int, penalty bool) {
+	m.rentalsClosed.WithLabelValues(autoType).Inc()
+	m.checkoutRevenue.WithLabelValues(autoType).Add(float64(checkout))
+	if penalty {
+		m.penalties.WithLabelValues(autoType).Inc()
+	}
+}
+
+func (m *Metrics) ThresholdRejected(autoType string) {
+	m.thresholdRejections.WithLabelValues(autoType).Inc()
+}
+
+var activeRentsDesc = prometheus.NewDesc(namespace+"_rentals_active",
+	"Active rents by auto type.", []string{"auto_type"}, nil)
+
+// activeRentsCollector reads the gauge from the database on scrape, so it is right
+// across restarts and instances.
+type activeRentsCollector struct {
+	count ActiveRents
+}
+
+func (c activeRentsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activeRentsDesc
+}
+
+func (c activeRentsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), activeRentsTimeout)
+	defer cancel()
+	counts, err := c.count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(activeRentsDesc, err)
+		return
+	}
+	for autoType, count := range counts {
+		ch <- prometheus.MustNewConstMetric(activeRentsDesc, prometheus.GaugeValue, float64(count), autoType)
+	}
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"car-rental/internal/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestBusinessMetrics(t *testing.T) {
+	m := New(func(context.Context) (map[string]int, error) { return map[string]int{"standard": 2, "special": 0}, nil })
+	m.RentalStarted("standard")
+	m.RentalClosed("standard", 300, false)
+	m.RentalClosed("special", 2320, true)
+	m.ThresholdRejected("special")
+
+	if got := testutil.ToFloat64(m.checkoutRevenue.WithLabelValues("special")); got != 2320 {
+		t.Errorf("want revenue 2320, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.penalties.WithLabelValues("special")); got != 1 {
+		t.Errorf("want 1 penalty, got %v", got)
+	}
+	expected := `
+# HELP car_rental_rentals_active Active rents by auto type.
+# TYPE car_rental_rentals_active gauge
+car_rental_rentals_active{auto_type="special"} 0
+car_rental_rentals_active{auto_type="standard"} 2
+`
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(expected), "car_rental_rentals_active"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestActiveRentsFailure(t *testing.T) {
+	m := New(func(context.Context) (map[string]int, error) { return nil, errors.New("database is down") })
+	if _, err := m.registry.Gather(); err == nil || !strings.Contains(err.Error(), "database is down") {
+		t.Errorf("want the scrape to report the failure, got %v", err)
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	// dry run builds the statements without a database
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "postgres://localhost:1/none"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New(nil)
+	if err = db.Use(m.GormPlugin()); err != nil {
+		t.Fatal(err)
+	}
+	var autos []models.Auto
+	db.Where("type = ?", "standard").Find(&autos)
+	db.Create(&models.AutoType{ID: "standard"})
+
+	if count := testutil.CollectAndCount(m.dbQueryDuration, "car_rental_db_query_duration_seconds"); count != 2 {
+		t.Errorf("want query and create observed, got %d series", count)
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"car-rental/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorResponse is the body of requests rejected by a middleware.
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Authenticate rejects requests without valid credentials and stores the
+// principal in the request context.
+func Authenticate(authenticator *auth.Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, err := authenticator.Authenticate(ctx.Request)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized,
+				ErrorResponse{Code: "UNAUTHORIZED", Message: err.Error()})
+			return
+		}
+		ctx.Request = ctx.Request.WithContext(auth.WithPrincipal(ctx.Request.Context(), principal))
+		ctx.Next()
+	}
+}
+
+// RequireRoles lets through only principals with one of the given roles.
+func RequireRoles(roles ...auth.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+		if ok {
+			for _, role := range roles {
+				if principal.Role == role {
+					ctx.Next()
+					return
+				}
+			}
+		}
+		ctx.AbortWithStatusJSON(http.StatusForbidden,
+			ErrorResponse{Code: "FORBIDDEN", Message: "not allowed for this role"})
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks responses of a deprecated endpoint with Deprecation and Sunset
+// headers and links the endpoint replacing it.
+func Deprecated(deprecatedAt time.Time, sunset time.Time, successor string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+		ctx.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		ctx.Header("Link", "<"+successor+`>; rel="successor-version"`)
+		ctx.Next()
+	}
+}
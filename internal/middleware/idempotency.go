@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	internalError             = "internal server error"
+)
+
+// replayedHeaders are the response headers stored with the body and set again on replays.
+var replayedHeaders = []string{"Location", "Deprecation", "Sunset", "Link"}
+
+// Idempotency replays the stored response when a request is retried with the same
+// Idempotency-Key header. Keys are scoped per authenticated subject, reusing a key
+// for a different request is rejected. Requests without the header pass through.
+// A key expires after ttl, a request that never finished, e.g. when the server stopped, blocks it until then.
+func Idempotency(repo repository.IdempotencyRepository, ttl time.Duration, logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := ctx.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			ctx.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest,
+				ErrorResponse{Code: "INVALID_IDEMPOTENCY_KEY", Message: "idempotency key is too long"})
+			return
+		}
+		principal, _ := auth.PrincipalFromContext(ctx.Request.Context())
+		body, err := io.ReadAll(ctx.Request.Body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest,
+				ErrorResponse{Code: "INVALID_BODY", Message: "could not read request body"})
+			return
+		}
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(ctx.Request, body)
+
+		created, err := repo.CreateKey(ctx.Request.Context(), models.IdempotencyKey{
+			Key:         key,
+			Scope:       principal.Subject,
+			Fingerprint: fingerprint,
+			ExpiresAt:   time.Now().Add(ttl),
+		})
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, internalError)
+			return
+		}
+		if !created {
+			replayIdempotentResponse(ctx, repo, principal.Subject, key, fingerprint)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = recorder
+		finished := false
+		// deferred so a panicking handler, recovered further out, releases the key too
+		defer func() {
+			// the response is stored even when the client is gone, or the key would stay in progress
+			storeCtx := context.WithoutCancel(ctx.Request.Context())
+			if !finished || recorder.Status() >= http.StatusInternalServerError {
+				// failed requests are not remembered, so the client can retry them
+				if err := repo.DeleteKey(storeCtx, principal.Subject, key); err != nil {
+					logger.ErrorContext(storeCtx, "releasing idempotency key failed", slog.Any("error", err))
+				}
+				return
+			}
+			err := repo.CompleteKey(storeCtx, models.IdempotencyKey{
+				Key:          key,
+				Scope:        principal.Subject,
+				StatusCode:   recorder.Status(),
+				ResponseBody: recorder.body.Bytes(),
+				ContentType:  recorder.Header().Get("Content-Type"),
+				Headers:      storedHeaders(recorder.Header()),
+			})
+			if err != nil {
+				logger.ErrorContext(storeCtx, "storing idempotent response failed", slog.Any("error", err))
+			}
+		}()
+		ctx.Next()
+		finished = true
+	}
+}
+
+func storedHeaders(header http.Header) map[string]string {
+	stored := map[string]string{}
+	for _, name := range replayedHeaders {
+		if value := header.Get(name); value != "" {
+			stored[name] = value
+		}
+	}
+	return stored
+}
+
+func replayIdempotentResponse(
+	ctx *gin.Context, repo repository.IdempotencyRepository, scope, key, fingerprint string) {
+	stored, err := repo.GetKey(ctx.Request.Context(), scope, key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// the first request failed and released the key in the meantime
+		ctx.AbortWithStatusJSON(http.StatusConflict,
+			ErrorResponse{Code: "IDEMPOTENCY_KEY_IN_PROGRESS", Message: "request with this key is in progress, retry later"})
+		return
+	}
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, internalError)
+		return
+	}
+	if stored.Fingerprint != fingerprint {
+		ctx.AbortWithStatusJSON(http.StatusUnprocessableEntity,
+			ErrorResponse{Code: "IDEMPOTENCY_KEY_REUSED", Message: "idempotency key was used for a different request"})
+		return
+	}
+	if !stored.Completed {
+		ctx.AbortWithStatusJSON(http.StatusConflict,
+			ErrorResponse{Code: "IDEMPOTENCY_KEY_IN_PROGRESS", Message: "request with this key is in progress, retry later"})
+		return
+	}
+	for name, value := range stored.Headers {
+		ctx.Header(name, value)
+	}
+	contentType := stored.ContentType
+	if contentType == "" {
+		// stored before the content type was
+		contentType = "application/json; charset=utf-8"
+	}
+	ctx.Header(IdempotencyReplayedHeader, "true")
+	ctx.Data(stored.StatusCode, contentType, stored.ResponseBody)
+	ctx.Abort()
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(r.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body while writing it to the client.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
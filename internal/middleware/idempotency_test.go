@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"car-rental/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type memoryIdempotencyRepository struct {
+	mu   sync.Mutex
+	keys map[string]models.IdempotencyKey
+}
+
+func (m *memoryIdempotencyRepository) GetKey(_ context.Context, scope string, key string) (models.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.keys[scope+"/"+key]
+	if !ok {
+		return stored, gorm.ErrRecordNotFound
+	}
+	return stored, nil
+}
+
+func (m *memoryIdempotencyRepository) CreateKey(_ context.Context, key models.IdempotencyKey) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.keys[key.Scope+"/"+key.Key]; ok {
+		return false, nil
+	}
+	m.keys[key.Scope+"/"+key.Key] = key
+	return true, nil
+}
+
+func (m *memoryIdempotencyRepository) CompleteKey(_ context.Context, response models.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := m.keys[response.Scope+"/"+response.Key]
+	stored.StatusCode, stored.ResponseBody, stored.Completed = response.StatusCode, response.ResponseBody, true
+	stored.ContentType, stored.Headers = response.ContentType, response.Headers
+	m.keys[response.Scope+"/"+response.Key] = stored
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) DeleteKey(_ context.Context, scope string, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, scope+"/"+key)
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) DeleteExpiredKeys(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var deleted int64
+	for id, stored := range m.keys {
+		if stored.ExpiresAt.Before(before) {
+			delete(m.keys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	calls := 0
+	engine := gin.New()
+	engine.Use(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	repo := &memoryIdempotencyRepository{keys: map[string]models.IdempotencyKey{}}
+	engine.POST("/bind", Idempotency(repo, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil))),
+		func(ctx *gin.Context) {
+			calls++
+			switch calls {
+			case 1:
+				ctx.JSON(http.StatusInternalServerError, "internal server error")
+				return
+			case 2:
+				panic("handler failed")
+			}
+			ctx.Header("Location", "/api/v2/rentals/1")
+			ctx.String(http.StatusCreated, "call %d", calls)
+		})
+	do := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/bind", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// failed and panicking requests release the key
+	for i := 0; i < 2; i++ {
+		if rec := do("k1", `{"days":1}`); rec.Code != http.StatusInternalServerError {
+			t.Errorf("want %d, got %d", http.StatusInternalServerError, rec.Code)
+		}
+	}
+	first := do("k1", `{"days":1}`)
+	if first.Code != http.StatusCreated {
+		t.Errorf("want %d, got %d", http.StatusCreated, first.Code)
+	}
+	retry := do("k1", `{"days":1}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("want replay of %q, got %d %q", first.Body.String(), retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get(IdempotencyReplayedHeader) != "true" {
+		t.Error("replayed response is not marked")
+	}
+	for _, header := range []string{"Content-Type", "Location"} {
+		if retry.Header().Get(header) != first.Header().Get(header) {
+			t.Errorf("want %s %q replayed, got %q", header, first.Header().Get(header), retry.Header().Get(header))
+		}
+	}
+	if calls != 3 {
+		t.Errorf("want handler called %d times, got %d", 3, calls)
+	}
+	if rec := do("k1", `{"days":2}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("want %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	// expired keys are deleted and may be used again
+	if deleted, _ := repo.DeleteExpiredKeys(context.Background(), time.Now().Add(2*time.Hour)); deleted != 1 {
+		t.Errorf("want %d expired key deleted, got %d", 1, deleted)
+	}
+	if rec := do("k1", `{"days":2}`); rec.Code != http.StatusCreated || rec.Header().Get(IdempotencyReplayedHeader) != "" {
+		t.Errorf("want a new %d response, got %d", http.StatusCreated, rec.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"car-rental/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds ids taken from clients, longer ones are replaced
+	maxRequestIDLength = 128
+)
+
+// RequestID takes the request id from the X-Request-ID header, or generates one, stores it
+// in the request context for the loggers and echoes it in the response.
+func RequestID() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		ctx.Request = ctx.Request.WithContext(logging.WithRequestID(ctx.Request.Context(), id))
+		ctx.Header(RequestIDHeader, id)
+		ctx.Next()
+	}
+}
+
+// validRequestID accepts printable ASCII without spaces, so ids can't forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// AccessLog logs every request once it is served, at warn for 4xx and error for 5xx responses.
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		started := time.Now()
+		ctx.Next()
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(ctx.Request.Context(), level, "request",
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("elapsed", time.Since(started)),
+			slog.Int("size", ctx.Writer.Size()),
+			slog.String("client_ip", ctx.ClientIP()))
+	}
+}
+
+// Recovery answers a panicking handler with a generic 500 and logs the panic.
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(ctx *gin.Context, recovered any) {
+		logger.ErrorContext(ctx.Request.Context(), "handler panicked",
+			slog.String("panic", fmt.Sprint(recovered)),
+			slog.String("route", ctx.FullPath()),
+			slog.String("stack", string(debug.Stack())))
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, "internal server error")
+	})
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners can't blow up the label cardinality.
+const unmatchedRoute = "unmatched"
+
+type RequestObserver interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+}
+
+// Metrics observes every request by its route template, /api/v2/rentals/:id rather than the concrete path.
+func Metrics(observer RequestObserver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		started := time.Now()
+		ctx.Next()
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		observer.ObserveRequest(ctx.Request.Method, route, ctx.Writer.Status(), time.Since(started))
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace of the traceparent header.
+// Spans are named by route template like the metrics, unmatched paths share one name.
+func Tracing(tracer trace.Tracer) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		parent := otel.GetTextMapPropagator().Extract(
+			ctx.Request.Context(), propagation.HeaderCarrier(ctx.Request.Header))
+		route := ctx.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		spanCtx, span := tracer.Start(parent, ctx.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(ctx.Request.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(ctx.Request.URL.Path)))
+		defer span.End()
+		ctx.Request = ctx.Request.WithContext(spanCtx)
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed sql/*.sql
+var migrationFiles embed.FS
+
+//go:embed seed.sql
+var seedSQL string
+
+// Migration is a pair of NNNN_name.up.sql and NNNN_name.down.sql files.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has up and down files with different names", version)
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be consecutive from 1, found %d at position %d",
+				migration.Version, i+1)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", migration.Version)
+		}
+	}
+	return migrations, nil
+}
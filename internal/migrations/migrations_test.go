@@ -0,0 +1,176 @@
+package migrations
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"car-rental/internal/config"
+	"car-rental/internal/logging"
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	files, err := fs.Sub(migrationFiles, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].Name != "init" {
+		t.Errorf("want migrations starting with init, got %v", migrations)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		ok    bool
+	}{
+		{"complete", fstest.MapFS{
+			"0001_a.up.sql": file, "0001_a.down.sql": file, "0002_b.up.sql": file, "0002_b.down.sql": file}, true},
+		{"missing down", fstest.MapFS{"0001_a.up.sql": file}, false},
+		{"gap", fstest.MapFS{
+			"0001_a.up.sql": file, "0001_a.down.sql": file, "0003_b.up.sql": file, "0003_b.down.sql": file}, false},
+		{"name mismatch", fstest.MapFS{"0001_a.up.sql": file, "0001_b.down.sql": file}, false},
+		{"unexpected file", fstest.MapFS{"README.md": file}, false},
+	}
+	for _, tt := range tests {
+		_, err := loadMigrations(tt.files)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+// migrateScratch applies the migrations up to version in a schema of its own, in a transaction rolled back
+// at the end of the test.
+func migrateScratch(t *testing.T, version int) (*gorm.DB, []Migration) {
+	cfg, err := config.NewLoader(nil).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := models.ConnectDatabase(cfg.Database.ConnectionString(), logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := fs.Sub(migrationFiles, "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	if err = tx.Exec("CREATE SCHEMA migrations_test; SET LOCAL search_path TO migrations_test, public").Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range migrations[:version] {
+		if err = tx.Exec(migration.Up).Error; err != nil {
+			t.Fatalf("applying migration %d_%s: %v", migration.Version, migration.Name, err)
+		}
+	}
+	return tx, migrations
+}
+
+func TestInsurancePackagesMigration(t *testing.T) {
+	tx, migrations := migrateScratch(t, 15)
+	tx.Exec("INSERT INTO auto_type (id) VALUES ('standard')")
+	tx.Exec("INSERT INTO commission (auto_type, type, value) VALUES ('standard', 'insurance', 133)")
+	if err := tx.Exec(migrations[15].Up).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the insurance commission was charged once per rent and no damage was, so is the basic package
+	var basic models.InsurancePackage
+	if err := tx.Where("auto_type = ? AND code = ?", "standard", "basic").First(&basic).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := models.InsurancePackage{AutoType: "standard", Code: "basic", Name: "Basic", RentalPrice: 133}
+	if basic != want {
+		t.Errorf("want %+v, got %+v", want, basic)
+	}
+	var left int64
+	tx.Model(&models.Commission{}).Where("type = ?", "insurance").Count(&left)
+	if left != 0 {
+		t.Errorf("want the insurance commission deleted, %d left", left)
+	}
+
+	if err := tx.Exec(migrations[15].Down).Error; err != nil {
+		t.Fatal(err)
+	}
+	var commission models.Commission
+	if err := tx.Where("auto_type = ? AND type = ?", "standard", "insurance").First(&commission).Error; err != nil {
+		t.Fatal(err)
+	}
+	if commission.Value != 133 {
+		t.Errorf("want the insurance commission of 133 back, got %d", commission.Value)
+	}
+}
+
+func TestAutoSurrogateIdsMigration(t *testing.T) {
+	tx, migrations := migrateScratch(t, 17)
+	for _, statement := range []string{
+		"INSERT INTO auto_type (id) VALUES ('standard')",
+		"INSERT INTO auto (id, name, type, availability) VALUES ('MINI-COOPER-SE', 'MINI Cooper SE', 'standard', true)",
+		"INSERT INTO auto (name, type, availability) VALUES ('Fiat 500', 'standard', true)",
+		"INSERT INTO auto_rent (id, auto_id, start_date, end_date) VALUES (7, 'MINI-COOPER-SE', now(), now())",
+		`INSERT INTO invoice (number, kind, rent_id, auto_id, auto_type, client_id, currency, net, tax, gross)
+			VALUES ('INV-1', 'invoice', 7, 'MINI-COOPER-SE', 'standard', 'client', 'EUR', 100, 0, 100)`,
+		`INSERT INTO outbox_event (type, aggregate_id, payload)
+			VALUES ('auto.status_changed', 'MINI-COOPER-SE', '{"auto_id": "MINI-COOPER-SE"}')`,
+	} {
+		if err := tx.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	var generated string
+	tx.Raw("SELECT id FROM auto WHERE name = 'Fiat 500'").Scan(&generated)
+	if err := tx.Exec(migrations[17].Up).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var id string
+	tx.Raw("SELECT id FROM auto_legacy_id WHERE legacy_id = 'MINI-COOPER-SE'").Scan(&id)
+	var auto models.Auto
+	if err := tx.Where("id = ?", id).First(&auto).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(id) != 36 || auto.Name != "MINI Cooper SE" {
+		t.Errorf("want MINI Cooper SE with a generated id, got %q %q", id, auto.Name)
+	}
+	// autos with a generated id keep it
+	var kept int64
+	tx.Model(&models.Auto{}).Where("id = ?", generated).Count(&kept)
+	if kept != 1 {
+		t.Errorf("want %s kept", generated)
+	}
+	var rentAuto, invoiceAuto, aggregate, payloadAuto string
+	tx.Raw("SELECT auto_id FROM auto_rent WHERE id = 7").Scan(&rentAuto)
+	tx.Raw("SELECT auto_id FROM invoice WHERE number = 'INV-1'").Scan(&invoiceAuto)
+	tx.Raw("SELECT aggregate_id FROM outbox_event").Scan(&aggregate)
+	tx.Raw("SELECT payload ->> 'auto_id' FROM outbox_event").Scan(&payloadAuto)
+	for _, got := range []string{rentAuto, invoiceAuto, aggregate, payloadAuto} {
+		if got != id {
+			t.Errorf("want references to %s, got %s", id, got)
+		}
+	}
+
+	if err := tx.Exec(migrations[17].Down).Error; err != nil {
+		t.Fatal(err)
+	}
+	tx.Raw("SELECT auto_id FROM auto_rent WHERE id = 7").Scan(&rentAuto)
+	if rentAuto != "MINI-COOPER-SE" {
+		t.Errorf("want the rent back on MINI-COOPER-SE, got %s", rentAuto)
+	}
+}
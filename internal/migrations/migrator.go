@@ -0,0 +1,153 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+)
+
+// advisoryLockId serialises migrations of concurrently starting instances.
+const advisoryLockId = 7_351_001
+
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator uses the migrations embedded in the binary.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	files, err := fs.Sub(migrationFiles, "sql")
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest is the version the binary expects the schema at.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version is the version of the last applied migration, 0 on an empty database.
+func (m *Migrator) Version() (int, error) {
+	if err := m.ensureVersionTable(); err != nil {
+		return 0, err
+	}
+	return currentVersion(m.db)
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		statuses = append(statuses, MigrationStatus{Migration: migration, Applied: migration.Version <= version})
+	}
+	return statuses, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up() error {
+	return m.To(m.Latest())
+}
+
+// Down rolls back the last applied migration.
+func (m *Migrator) Down() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return errors.New("no migration to roll back")
+	}
+	return m.To(version - 1)
+}
+
+// To migrates up or down until the schema is at the given version.
+func (m *Migrator) To(target int) error {
+	if target < 0 || target > m.Latest() {
+		return fmt.Errorf("version must be between 0 and %d", m.Latest())
+	}
+	if err := m.ensureVersionTable(); err != nil {
+		return err
+	}
+	for {
+		done, err := m.step(target)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// step applies or rolls back one migration towards the target in its own transaction.
+func (m *Migrator) step(target int) (done bool, err error) {
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", advisoryLockId).Error; err != nil {
+			return err
+		}
+		version, err := currentVersion(tx)
+		if err != nil {
+			return err
+		}
+		switch {
+		case version > m.Latest():
+			return fmt.Errorf("schema version %d is newer than the %d migrations of this binary", version, m.Latest())
+		case version == target:
+			done = true
+			return nil
+		case version < target:
+			migration := m.migrations[version]
+			if err = tx.Exec(migration.Up).Error; err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			return tx.Create(&models.SchemaVersion{Version: migration.Version, Name: migration.Name}).Error
+		default:
+			migration := m.migrations[version-1]
+			if err = tx.Exec(migration.Down).Error; err != nil {
+				return fmt.Errorf("rolling back migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			return tx.Delete(&models.SchemaVersion{}, "version = ?", migration.Version).Error
+		}
+	})
+	return done, err
+}
+
+// Seed inserts the demo auto types, autos, commissions and thresholds.
+func (m *Migrator) Seed() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version != m.Latest() {
+		return fmt.Errorf("schema is at version %d, migrate up to %d before seeding", version, m.Latest())
+	}
+	return m.db.Exec(seedSQL).Error
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	return m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT now()
+	)`).Error
+}
+
+func currentVersion(db *gorm.DB) (int, error) {
+	var version int
+	res := db.Model(&models.SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version)
+	return version, res.Error
+}
@@ -0,0 +1,60 @@
+-- Demo auto types, autos, commissions and thresholds according to the requirements,
+-- with insurance packages, extras, seasons and discount tiers to try the pricing with.
+-- Safe to run repeatedly, existing rows are left untouched.
+insert into auto_type (id) values ('standard') ON CONFLICT DO NOTHING;
+insert into auto_type (id) values ('special') ON CONFLICT DO NOTHING;
+
+-- autos get generated ids, they are told apart by name
+insert into auto (name, type, availability, location, make, model, year, seats, fuel_type, transmission, features)
+select seed.*
+from (values ('MINI Cooper SE', 'standard', true, 'HQ', 'MINI', 'Cooper SE', 2022, 4, 'electric', 'automatic', '{navigation,heated-seats}'::text[]),
+             ('John Deere 1050K', 'special', true, 'HQ', 'John Deere', '1050K', 2021, 1, 'diesel', 'automatic', '{}'::text[]))
+         as seed (name, type, availability, location, make, model, year, seats, fuel_type, transmission, features)
+where not exists (select 1 from auto a where a.name = seed.name);
+
+insert into commission (auto_type, type, value, min_threshold)
+select seed.auto_type, seed.type, seed.value, seed.min_threshold
+from (values ('standard', 'daily', 50, 0),
+             ('special', 'daily', 200, 0),
+             ('special', 'agreement', 200, 0),
+             ('special', 'weekend', 20, 0),
+             ('special', 'penalty', 5, 10)) as seed (auto_type, type, value, min_threshold)
+where not exists (select 1 from commission c where c.auto_type = seed.auto_type and c.type = seed.type);
+
+insert into rent_threshold (auto_type, min_threshold, max_threshold) values ('standard', 0, 1826) ON CONFLICT DO NOTHING;
+insert into rent_threshold (auto_type, min_threshold, max_threshold) values ('special', 10, 90) ON CONFLICT DO NOTHING;
+
+-- an excess of 0 covers all of the damage
+insert into insurance_package (auto_type, code, name, daily_price, excess)
+values ('standard', 'basic', 'Basic', 10, 1000),
+       ('standard', 'full', 'Full', 20, 300),
+       ('standard', 'zero-excess', 'Zero excess', 30, 0),
+       ('special', 'basic', 'Basic', 40, 5000),
+       ('special', 'full', 'Full', 80, 1500),
+       ('special', 'zero-excess', 'Zero excess', 120, 0)
+ON CONFLICT DO NOTHING;
+
+insert into extra (id, name, pricing, price, stocked)
+values ('gps', 'GPS', 'day', 8, true),
+       ('child-seat', 'Child seat', 'day', 5, true),
+       ('additional-driver', 'Additional driver', 'rental', 30, false)
+ON CONFLICT DO NOTHING;
+insert into extra_stock (extra_id, location, quantity)
+values ('gps', 'HQ', 10),
+       ('child-seat', 'HQ', 4)
+ON CONFLICT DO NOTHING;
+
+insert into discount_tier (auto_type, min_days, percent)
+values ('standard', 7, 5),
+       ('standard', 30, 15),
+       ('special', 30, 10)
+ON CONFLICT DO NOTHING;
+
+-- the seasons of the current year, seeding again in a later year adds that year's
+insert into season (name, start_date, end_date, percent)
+select seed.name, seed.start_date, seed.end_date, seed.percent
+from (values ('Summer ' || extract(year from now()), make_date(extract(year from now())::int, 7, 1),
+              make_date(extract(year from now())::int, 8, 31), 120),
+             ('Winter holidays ' || extract(year from now()), make_date(extract(year from now())::int, 12, 20),
+              make_date(extract(year from now())::int, 12, 31), 130)) as seed (name, start_date, end_date, percent)
+where not exists (select 1 from season s where s.name = seed.name);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS auto_rent;
+DROP TABLE IF EXISTS commission;
+DROP TABLE IF EXISTS rent_threshold;
+DROP TABLE IF EXISTS commission_type;
+DROP TABLE IF EXISTS auto;
+DROP TABLE IF EXISTS auto_type;
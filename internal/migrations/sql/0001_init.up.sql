@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS auto_type (
+    id VARCHAR(255) PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS  auto (
+    id VARCHAR(255) PRIMARY KEY,
+    type VARCHAR(255) REFERENCES auto_type (id) NOT NULL,
+    availability BOOLEAN NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS commission_type (
+    id VARCHAR(255) PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS rent_threshold (
+    auto_type VARCHAR(255) REFERENCES auto_type (id) PRIMARY KEY,
+    min_threshold INTEGER NOT NULL,
+    max_threshold INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS commission (
+    auto_type VARCHAR(255) REFERENCES auto_type (id) NOT NULL,
+    type VARCHAR(255) REFERENCES commission_type (id) NOT NULL,
+    value INTEGER NOT NULL,
+    min_threshold INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission ON commission (auto_type, type);
+
+CREATE TABLE IF NOT EXISTS auto_rent (
+    auto_id VARCHAR(255) REFERENCES auto (id) UNIQUE NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL
+);
+
+-- commission types are referenced by the pricing code, so they are part of the schema
+insert into commission_type (id) values ('daily') ON CONFLICT DO NOTHING;
+insert into commission_type (id) values ('agreement') ON CONFLICT DO NOTHING;
+insert into commission_type (id) values ('weekend') ON CONFLICT DO NOTHING;
+insert into commission_type (id) values ('penalty') ON CONFLICT DO NOTHING;
+insert into commission_type (id) values ('insurance') ON CONFLICT DO NOTHING;
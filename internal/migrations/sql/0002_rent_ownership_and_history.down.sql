@@ -0,0 +1,11 @@
+DELETE FROM auto_rent WHERE status <> 'active';
+DROP INDEX IF EXISTS idx_auto_rent_active;
+ALTER TABLE auto_rent ADD CONSTRAINT auto_rent_auto_id_key UNIQUE (auto_id);
+
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS checkout;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS notes;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS odometer;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS returned_at;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS status;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS client_id;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS id;
@@ -0,0 +1,12 @@
+-- rents are owned by a client and kept closed after the return instead of being deleted
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS id SERIAL PRIMARY KEY;
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS client_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active';
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS returned_at DATE;
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS odometer INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS checkout INTEGER NOT NULL DEFAULT 0;
+
+-- only one active rent per auto
+ALTER TABLE auto_rent DROP CONSTRAINT IF EXISTS auto_rent_auto_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_auto_rent_active ON auto_rent (auto_id) WHERE status = 'active';
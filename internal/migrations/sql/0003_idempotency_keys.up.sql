@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    key VARCHAR(255) NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_body BYTEA,
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (scope, key)
+);
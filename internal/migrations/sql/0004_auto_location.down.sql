@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_auto_type_location;
+ALTER TABLE auto DROP COLUMN IF EXISTS location;
@@ -0,0 +1,3 @@
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS location VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_auto_type_location ON auto (type, location);
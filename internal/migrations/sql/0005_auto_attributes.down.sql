@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_auto_features;
+DROP INDEX IF EXISTS idx_auto_plate;
+DROP INDEX IF EXISTS idx_auto_vin;
+
+ALTER TABLE auto DROP COLUMN IF EXISTS features;
+ALTER TABLE auto DROP COLUMN IF EXISTS transmission;
+ALTER TABLE auto DROP COLUMN IF EXISTS fuel_type;
+ALTER TABLE auto DROP COLUMN IF EXISTS seats;
+ALTER TABLE auto DROP COLUMN IF EXISTS year;
+ALTER TABLE auto DROP COLUMN IF EXISTS model;
+ALTER TABLE auto DROP COLUMN IF EXISTS make;
+ALTER TABLE auto DROP COLUMN IF EXISTS plate;
+ALTER TABLE auto DROP COLUMN IF EXISTS vin;
+ALTER TABLE auto DROP COLUMN IF EXISTS name;
+ALTER TABLE auto ALTER COLUMN id DROP DEFAULT;
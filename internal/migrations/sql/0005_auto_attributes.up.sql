@@ -0,0 +1,27 @@
+-- gen_random_uuid() is built in from PostgreSQL 13, older servers get it from pgcrypto
+DO $$
+BEGIN
+    IF current_setting('server_version_num')::int < 130000 THEN
+        CREATE EXTENSION IF NOT EXISTS pgcrypto;
+    END IF;
+END $$;
+
+-- Existing ids become the display name, new autos get generated uuid ids.
+-- Migration 18 gives the existing autos generated ids too.
+ALTER TABLE auto ALTER COLUMN id SET DEFAULT gen_random_uuid()::text;
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS vin VARCHAR(17) NOT NULL DEFAULT '';
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS plate VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS make VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS model VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS year INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS seats INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS fuel_type VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS transmission VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS features TEXT[] NOT NULL DEFAULT '{}';
+
+UPDATE auto SET name = id WHERE name = '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_auto_vin ON auto (vin) WHERE vin <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_auto_plate ON auto (plate) WHERE plate <> '';
+CREATE INDEX IF NOT EXISTS idx_auto_features ON auto USING GIN (features);
@@ -0,0 +1 @@
+ALTER TABLE auto DROP COLUMN IF EXISTS retired_at;
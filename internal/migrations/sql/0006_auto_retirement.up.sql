@@ -0,0 +1,2 @@
+-- retired autos stay for the rent history but can't be rented or listed as available anymore
+ALTER TABLE auto ADD COLUMN IF NOT EXISTS retired_at TIMESTAMP;
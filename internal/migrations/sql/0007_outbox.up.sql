@@ -0,0 +1,14 @@
+-- domain events are written with the change that caused them and published by the dispatcher,
+-- published events are kept for events.retention
+CREATE TABLE IF NOT EXISTS outbox_event (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(64) NOT NULL,
+    aggregate_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    occurred_at TIMESTAMP NOT NULL DEFAULT now(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+    published_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS outbox_event_pending ON outbox_event (next_attempt_at, id) WHERE published_at IS NULL;
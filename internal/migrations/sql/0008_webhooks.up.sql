@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS webhook_subscription (
+    id SERIAL PRIMARY KEY,
+    owner VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    client_id VARCHAR(255) NOT NULL DEFAULT '',
+    secret VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS webhook_subscription_owner ON webhook_subscription (owner);
+
+-- one delivery per event and subscription, dead deliveries are the dead-letter list
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES webhook_subscription (id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT now(),
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMP,
+    UNIQUE (subscription_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS webhook_delivery_due ON webhook_delivery (next_attempt_at, id) WHERE status = 'pending';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS invoice_tax;
+DROP TABLE IF EXISTS invoice_line;
+DROP TABLE IF EXISTS invoice;
+DROP FUNCTION IF EXISTS invoice_immutable();
+DROP TABLE IF EXISTS invoice_sequence;
@@ -0,0 +1,70 @@
+-- numbers are taken in the transaction issuing the invoice, so a rolled back invoice leaves no gap
+CREATE TABLE IF NOT EXISTS invoice_sequence (
+    series VARCHAR(64) PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
+
+-- rents may be deleted, their invoices are kept
+CREATE TABLE IF NOT EXISTS invoice (
+    id BIGSERIAL PRIMARY KEY,
+    number VARCHAR(64) NOT NULL UNIQUE,
+    kind VARCHAR(16) NOT NULL,
+    credited_invoice_id BIGINT REFERENCES invoice (id),
+    credited_invoice_number VARCHAR(64) NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    rent_id INTEGER NOT NULL,
+    auto_id VARCHAR(255) NOT NULL,
+    auto_type VARCHAR(255) NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    issuer_name VARCHAR(255) NOT NULL DEFAULT '',
+    issuer_address TEXT NOT NULL DEFAULT '',
+    issuer_tax_id VARCHAR(64) NOT NULL DEFAULT '',
+    currency VARCHAR(3) NOT NULL,
+    net INTEGER NOT NULL,
+    tax INTEGER NOT NULL,
+    gross INTEGER NOT NULL,
+    issued_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS invoice_rent ON invoice (rent_id);
+CREATE INDEX IF NOT EXISTS invoice_client ON invoice (client_id);
+CREATE INDEX IF NOT EXISTS invoice_credited ON invoice (credited_invoice_id) WHERE credited_invoice_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS invoice_line (
+    id BIGSERIAL PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoice (id),
+    position INTEGER NOT NULL,
+    commission_type VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price INTEGER NOT NULL,
+    amount INTEGER NOT NULL,
+    tax_rate NUMERIC(5, 2) NOT NULL,
+    credited_position INTEGER NOT NULL DEFAULT 0,
+    UNIQUE (invoice_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS invoice_tax (
+    id BIGSERIAL PRIMARY KEY,
+    invoice_id BIGINT NOT NULL REFERENCES invoice (id),
+    name VARCHAR(64) NOT NULL,
+    rate NUMERIC(5, 2) NOT NULL,
+    base INTEGER NOT NULL,
+    amount INTEGER NOT NULL
+);
+
+-- issued invoices are immutable, corrections are credit notes
+CREATE OR REPLACE FUNCTION invoice_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'invoices are immutable, issue a credit note instead';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS invoice_immutable ON invoice;
+CREATE TRIGGER invoice_immutable BEFORE UPDATE OR DELETE ON invoice
+    FOR EACH ROW EXECUTE FUNCTION invoice_immutable();
+DROP TRIGGER IF EXISTS invoice_line_immutable ON invoice_line;
+CREATE TRIGGER invoice_line_immutable BEFORE UPDATE OR DELETE ON invoice_line
+    FOR EACH ROW EXECUTE FUNCTION invoice_immutable();
+DROP TRIGGER IF EXISTS invoice_tax_immutable ON invoice_tax;
+CREATE TRIGGER invoice_tax_immutable BEFORE UPDATE OR DELETE ON invoice_tax
+    FOR EACH ROW EXECUTE FUNCTION invoice_immutable();
@@ -0,0 +1 @@
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS checkout_tax;
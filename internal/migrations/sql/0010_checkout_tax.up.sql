@@ -0,0 +1,2 @@
+-- the checkout stays the net amount, rents closed before keep no tax
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS checkout_tax INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS payment;
+DELETE FROM commission WHERE type = 'deposit';
+DELETE FROM commission_type WHERE id = 'deposit';
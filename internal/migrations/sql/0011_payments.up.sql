@@ -0,0 +1,16 @@
+-- the deposit authorized at the bind is a commission of the auto type, like the prices
+insert into commission_type (id) values ('deposit') ON CONFLICT DO NOTHING;
+
+-- like invoices, payments are kept when their rent is deleted
+CREATE TABLE IF NOT EXISTS payment (
+    id BIGSERIAL PRIMARY KEY,
+    rent_id INTEGER NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    authorization_id VARCHAR(255) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    authorized INTEGER NOT NULL,
+    captured INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now(),
+    UNIQUE (rent_id, kind)
+);
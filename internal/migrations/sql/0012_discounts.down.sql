@@ -0,0 +1,4 @@
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS discount;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS promo_code;
+DROP TABLE IF EXISTS discount_tier;
+DROP TABLE IF EXISTS promo_code;
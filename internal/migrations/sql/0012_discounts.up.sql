@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS promo_code (
+    code VARCHAR(64) PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    value INTEGER NOT NULL,
+    valid_from TIMESTAMP,
+    valid_until TIMESTAMP,
+    max_uses INTEGER NOT NULL DEFAULT 0,
+    uses INTEGER NOT NULL DEFAULT 0,
+    auto_types TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- the tier with the most days not above the booked days of a rent applies
+CREATE TABLE IF NOT EXISTS discount_tier (
+    auto_type VARCHAR(255) NOT NULL REFERENCES auto_type (id),
+    min_days INTEGER NOT NULL,
+    percent INTEGER NOT NULL,
+    PRIMARY KEY (auto_type, min_days)
+);
+
+-- the checkout of a rent is priced with the promo code it was bound with
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS promo_code VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS discount INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS demand_percent;
+DROP TABLE IF EXISTS season;
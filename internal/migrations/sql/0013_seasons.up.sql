@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS season (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    percent INTEGER NOT NULL,
+    auto_types TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    CHECK (start_date <= end_date)
+);
+
+-- the share of the daily price the demand at bind charges, 0 for rents priced without demand
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS demand_percent INTEGER NOT NULL DEFAULT 0;
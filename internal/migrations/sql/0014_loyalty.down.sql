@@ -0,0 +1,3 @@
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS redeemed_points;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS loyalty_tier;
+DROP TABLE IF EXISTS loyalty_entry;
@@ -0,0 +1,15 @@
+-- the points of a client are the sum of the entries, expired points are taken off by an expire entry
+CREATE TABLE IF NOT EXISTS loyalty_entry (
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    points INTEGER NOT NULL,
+    rent_id INTEGER,
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS loyalty_entry_client_id_idx ON loyalty_entry (client_id, id);
+
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS loyalty_tier VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS redeemed_points INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS rent_extra;
+DROP TABLE IF EXISTS extra_stock;
+DROP TABLE IF EXISTS extra;
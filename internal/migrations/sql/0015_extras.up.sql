@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS extra (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    pricing VARCHAR(16) NOT NULL,
+    price INTEGER NOT NULL,
+    stocked BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- the row of an extra and location is locked while its reservations are counted
+CREATE TABLE IF NOT EXISTS extra_stock (
+    extra_id VARCHAR(64) NOT NULL REFERENCES extra (id),
+    location VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL,
+    PRIMARY KEY (extra_id, location)
+);
+
+-- the extras of a rent keep the price they were bound at
+CREATE TABLE IF NOT EXISTS rent_extra (
+    id BIGSERIAL PRIMARY KEY,
+    rent_id INTEGER NOT NULL REFERENCES auto_rent (id),
+    extra_id VARCHAR(64) NOT NULL REFERENCES extra (id),
+    name VARCHAR(255) NOT NULL,
+    pricing VARCHAR(16) NOT NULL,
+    price INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    stocked BOOLEAN NOT NULL,
+    location VARCHAR(255) NOT NULL,
+    reserved_from TIMESTAMP NOT NULL,
+    reserved_until TIMESTAMP NOT NULL,
+    released_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS rent_extra_rent_id_idx ON rent_extra (rent_id);
+CREATE INDEX IF NOT EXISTS rent_extra_reservation_idx ON rent_extra (extra_id, location, reserved_from);
@@ -0,0 +1,9 @@
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS damage;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS insurance_excess;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS insurance_rental_price;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS insurance_daily_price;
+ALTER TABLE auto_rent DROP COLUMN IF EXISTS insurance;
+
+INSERT INTO commission (auto_type, type, value)
+SELECT auto_type, 'insurance', rental_price FROM insurance_package WHERE code = 'basic';
+DROP TABLE IF EXISTS insurance_package;
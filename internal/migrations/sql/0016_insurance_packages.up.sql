@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS insurance_package (
+    auto_type VARCHAR(255) NOT NULL REFERENCES auto_type (id),
+    code VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    daily_price INTEGER NOT NULL DEFAULT 0,
+    rental_price INTEGER NOT NULL DEFAULT 0,
+    excess INTEGER NOT NULL,
+    PRIMARY KEY (auto_type, code)
+);
+-- the insurance commission of an auto type was charged once per rent and no damage was charged, so it becomes its
+-- basic package at that rental price, without a daily price and with no excess
+INSERT INTO insurance_package (auto_type, code, name, daily_price, rental_price, excess)
+SELECT auto_type, 'basic', 'Basic', 0, value, 0 FROM commission WHERE type = 'insurance'
+ON CONFLICT DO NOTHING;
+DELETE FROM commission WHERE type = 'insurance';
+-- the checkout of a rent is priced with the package it was bound with, as it was then
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS insurance VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS insurance_daily_price INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS insurance_rental_price INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS insurance_excess INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE auto_rent ADD COLUMN IF NOT EXISTS damage INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idempotency_key_expires_at_idx;
+ALTER TABLE idempotency_key DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE idempotency_key DROP COLUMN IF EXISTS headers;
+ALTER TABLE idempotency_key DROP COLUMN IF EXISTS content_type;
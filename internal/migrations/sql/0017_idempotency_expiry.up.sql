@@ -0,0 +1,6 @@
+ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS content_type VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS headers TEXT NOT NULL DEFAULT '';
+-- keys stored before the expiry get the default lifetime
+ALTER TABLE idempotency_key ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NOT NULL
+    DEFAULT now() + interval '24 hours';
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_at_idx ON idempotency_key (expires_at);
@@ -0,0 +1,19 @@
+UPDATE webhook_delivery SET payload = jsonb_set(payload, '{data,auto_id}', to_jsonb(m.legacy_id)) FROM auto_legacy_id m
+WHERE webhook_delivery.payload -> 'data' ->> 'auto_id' = m.id;
+UPDATE webhook_delivery SET payload = jsonb_set(payload, '{aggregate_id}', to_jsonb(m.legacy_id)) FROM auto_legacy_id m
+WHERE webhook_delivery.event_type = 'auto.status_changed' AND webhook_delivery.payload ->> 'aggregate_id' = m.id;
+UPDATE outbox_event SET payload = jsonb_set(payload, '{auto_id}', to_jsonb(m.legacy_id)) FROM auto_legacy_id m
+WHERE outbox_event.payload ->> 'auto_id' = m.id;
+UPDATE outbox_event SET aggregate_id = m.legacy_id FROM auto_legacy_id m
+WHERE outbox_event.type = 'auto.status_changed' AND outbox_event.aggregate_id = m.id;
+
+ALTER TABLE invoice DISABLE TRIGGER invoice_immutable;
+UPDATE invoice SET auto_id = m.legacy_id FROM auto_legacy_id m WHERE invoice.auto_id = m.id;
+ALTER TABLE invoice ENABLE TRIGGER invoice_immutable;
+
+ALTER TABLE auto_rent DROP CONSTRAINT IF EXISTS auto_rent_auto_id_fkey;
+UPDATE auto SET id = m.legacy_id FROM auto_legacy_id m WHERE auto.id = m.id;
+UPDATE auto_rent SET auto_id = m.legacy_id FROM auto_legacy_id m WHERE auto_rent.auto_id = m.id;
+ALTER TABLE auto_rent ADD CONSTRAINT auto_rent_auto_id_fkey FOREIGN KEY (auto_id) REFERENCES auto (id);
+
+DROP TABLE IF EXISTS auto_legacy_id;
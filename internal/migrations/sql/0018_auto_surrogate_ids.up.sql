@@ -0,0 +1,29 @@
+-- autos created before migration 5 kept their display name as id, they get a generated uuid like the newer ones.
+-- The old ids are kept for clients that stored them and to roll back.
+CREATE TABLE IF NOT EXISTS auto_legacy_id (
+    id VARCHAR(255) PRIMARY KEY,
+    legacy_id VARCHAR(255) NOT NULL UNIQUE
+);
+INSERT INTO auto_legacy_id (id, legacy_id)
+SELECT gen_random_uuid()::text, id FROM auto
+WHERE id !~ '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$';
+
+ALTER TABLE auto_rent DROP CONSTRAINT IF EXISTS auto_rent_auto_id_fkey;
+UPDATE auto SET id = m.id FROM auto_legacy_id m WHERE auto.id = m.legacy_id;
+UPDATE auto_rent SET auto_id = m.id FROM auto_legacy_id m WHERE auto_rent.auto_id = m.legacy_id;
+ALTER TABLE auto_rent ADD CONSTRAINT auto_rent_auto_id_fkey FOREIGN KEY (auto_id) REFERENCES auto (id);
+
+-- invoices can't be corrected but may follow the id of their auto, the lines keep the text they were printed with
+ALTER TABLE invoice DISABLE TRIGGER invoice_immutable;
+UPDATE invoice SET auto_id = m.id FROM auto_legacy_id m WHERE invoice.auto_id = m.legacy_id;
+ALTER TABLE invoice ENABLE TRIGGER invoice_immutable;
+
+-- events name the auto in their payload, auto events are also keyed by it; deliveries wrap the event in an envelope
+UPDATE outbox_event SET aggregate_id = m.id FROM auto_legacy_id m
+WHERE outbox_event.type = 'auto.status_changed' AND outbox_event.aggregate_id = m.legacy_id;
+UPDATE outbox_event SET payload = jsonb_set(payload, '{auto_id}', to_jsonb(m.id)) FROM auto_legacy_id m
+WHERE outbox_event.payload ->> 'auto_id' = m.legacy_id;
+UPDATE webhook_delivery SET payload = jsonb_set(payload, '{aggregate_id}', to_jsonb(m.id)) FROM auto_legacy_id m
+WHERE webhook_delivery.event_type = 'auto.status_changed' AND webhook_delivery.payload ->> 'aggregate_id' = m.legacy_id;
+UPDATE webhook_delivery SET payload = jsonb_set(payload, '{data,auto_id}', to_jsonb(m.id)) FROM auto_legacy_id m
+WHERE webhook_delivery.payload -> 'data' ->> 'auto_id' = m.legacy_id;
@@ -1,9 +1,55 @@
 package models
 
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	AutoStatusAvailable = "available"
+	AutoStatusRented    = "rented"
+	AutoStatusRetired   = "retired"
+)
+
+const (
+	FuelTypePetrol   = "petrol"
+	FuelTypeDiesel   = "diesel"
+	FuelTypeElectric = "electric"
+	FuelTypeHybrid   = "hybrid"
+
+	TransmissionManual    = "manual"
+	TransmissionAutomatic = "automatic"
+)
+
+// Auto is identified by the opaque ID, new autos get a generated uuid.
+// Name is the human-readable display name.
 type Auto struct {
-	ID           string `db:"id" sql:"type:VARCHAR(255)"`
-	Type         string `db:"type" sql:"type:VARCHAR(255)"`
-	Availability bool   `db:"availability" sql:"type:BOOLEAN"`
+	ID           string         `db:"id" sql:"type:VARCHAR(255)" gorm:"primaryKey;default:gen_random_uuid()"`
+	Name         string         `db:"name" sql:"type:VARCHAR(255)"`
+	Type         string         `db:"type" sql:"type:VARCHAR(255)"`
+	Availability bool           `db:"availability" sql:"type:BOOLEAN"`
+	Location     string         `db:"location" sql:"type:VARCHAR(255)"`
+	VIN          string         `db:"vin" sql:"type:VARCHAR(17)"`
+	Plate        string         `db:"plate" sql:"type:VARCHAR(32)"`
+	Make         string         `db:"make" sql:"type:VARCHAR(255)"`
+	Model        string         `db:"model" sql:"type:VARCHAR(255)"`
+	Year         int            `db:"year" sql:"type:INTEGER"`
+	Seats        int            `db:"seats" sql:"type:INTEGER"`
+	FuelType     string         `db:"fuel_type" sql:"type:VARCHAR(16)"`
+	Transmission string         `db:"transmission" sql:"type:VARCHAR(16)"`
+	Features     pq.StringArray `db:"features" sql:"type:TEXT[]" gorm:"type:text[]"`
+	RetiredAt    *time.Time     `db:"retired_at" sql:"type:TIMESTAMP"`
+}
+
+func (a Auto) Status() string {
+	switch {
+	case a.RetiredAt != nil:
+		return AutoStatusRetired
+	case a.Availability:
+		return AutoStatusAvailable
+	}
+	return AutoStatusRented
 }
 
 func (a *Auto) TableName() string {
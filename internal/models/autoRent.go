@@ -4,10 +4,42 @@ import (
 	"time"
 )
 
+const (
+	RentStatusActive = "active"
+	RentStatusClosed = "closed"
+)
+
 type AutoRent struct {
-	AutoID    string    `db:"auto_id"`
-	StartDate time.Time `db:"start_date"`
-	EndDate   time.Time `db:"end_date"`
+	ID         int        `db:"id"`
+	AutoID     string     `db:"auto_id"`
+	ClientID   string     `db:"client_id"`
+	StartDate  time.Time  `db:"start_date"`
+	EndDate    time.Time  `db:"end_date"`
+	Status     string     `db:"status" gorm:"default:active"`
+	ReturnedAt *time.Time `db:"returned_at"`
+	Odometer   int        `db:"odometer"`
+	Notes      string     `db:"notes"`
+	Checkout   int        `db:"checkout"`
+	// CheckoutTax is the tax charged on top of the checkout
+	CheckoutTax int    `db:"checkout_tax"`
+	PromoCode   string `db:"promo_code"`
+	// Discount is what the checkout was reduced by, by the long rental tier, the promo code and loyalty
+	Discount int `db:"discount"`
+	// DemandPercent of the daily prices is charged, by the share of the autos of the type rented at bind;
+	// 0 for rents priced without demand
+	DemandPercent int `db:"demand_percent"`
+	// LoyaltyTier is the tier of the client at bind, its benefits apply to the checkout
+	LoyaltyTier string `db:"loyalty_tier"`
+	// RedeemedPoints were redeemed at bind, those the checkout doesn't use are credited back at the return
+	RedeemedPoints int `db:"redeemed_points"`
+	// Insurance is the code of the insurance package chosen at bind, empty without insurance.
+	// Its price and excess are kept as they were at bind.
+	Insurance            string `db:"insurance"`
+	InsuranceDailyPrice  int    `db:"insurance_daily_price"`
+	InsuranceRentalPrice int    `db:"insurance_rental_price"`
+	InsuranceExcess      int    `db:"insurance_excess"`
+	// Damage is the repair cost of the damage found at the return, charged up to the insurance excess
+	Damage int `db:"damage"`
 }
 
 func (a *AutoRent) TableName() string {
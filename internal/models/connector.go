@@ -1,15 +1,68 @@
 package models
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"car-rental/internal/logging"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"log"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+const (
+	initialConnectBackoff = 250 * time.Millisecond
+	maxConnectBackoff     = 5 * time.Second
 )
 
-func ConnectDatabase(dsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// ConnectDatabase opens the database with gorm logging through logger.
+func ConnectDatabase(dsn string, logger *slog.Logger) (*gorm.DB, error) {
+	gormLogger := logging.NewGormLogger(logger)
+	// a failed connect is returned, gorm would log it a second time
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormLogger.LogMode(gormlogger.Silent)})
 	if err != nil {
-		log.Fatalf("Error connecting to database: %v", err)
+		return nil, fmt.Errorf("connecting to database: %w", err)
 	}
+	db.Logger = gormLogger
+	return db, nil
+}
+
+// ConnectDatabaseWithRetry keeps trying to connect with exponential backoff until it succeeds or ctx is done,
+// so the service can start before the database accepts connections.
+func ConnectDatabaseWithRetry(ctx context.Context, dsn string, logger *slog.Logger) (*gorm.DB, error) {
+	var db *gorm.DB
+	err := retryWithBackoff(ctx, initialConnectBackoff, maxConnectBackoff, func() error {
+		var err error
+		db, err = ConnectDatabase(dsn, logger)
+		return err
+	}, func(err error, delay time.Duration) {
+		logger.WarnContext(ctx, "database unavailable", slog.Any("error", err), slog.Duration("retry_in", delay))
+	})
 	return db, err
 }
+
+// retryWithBackoff calls attempt until it succeeds, telling retrying about each failure before waiting.
+func retryWithBackoff(ctx context.Context, initial, max time.Duration,
+	attempt func() error, retrying func(err error, delay time.Duration)) error {
+	delay := initial
+	for {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		retrying(err, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (gave up: %v)", err, ctx.Err())
+		case <-timer.C:
+		}
+		delay *= 2
+		if delay > max {
+			delay = max
+		}
+	}
+}
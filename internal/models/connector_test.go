@@ -0,0 +1,39 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	attempts := 0
+	var delays []time.Duration
+	err := retryWithBackoff(context.Background(), time.Millisecond, 3*time.Millisecond, func() error {
+		attempts++
+		if attempts < 4 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, func(err error, delay time.Duration) { delays = append(delays, delay) })
+	if err != nil || attempts != 4 {
+		t.Errorf("want success on the 4th attempt, got %v after %d attempts", err, attempts)
+	}
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond}
+	if !reflect.DeepEqual(delays, want) {
+		t.Errorf("want doubling delays capped at the maximum %v, got %v", want, delays)
+	}
+}
+
+func TestRetryWithBackoffGivesUp(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	refused := errors.New("connection refused")
+	err := retryWithBackoff(ctx, time.Millisecond, 5*time.Millisecond, func() error { return refused },
+		func(error, time.Duration) {})
+	if !errors.Is(err, refused) {
+		t.Errorf("want the last connection error, got %v", err)
+	}
+}
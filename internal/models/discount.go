@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	PromoKindPercent = "percent"
+	// PromoKindFixed takes Value off the checkout
+	PromoKindFixed = "fixed"
+)
+
+// PromoCode is a discount a client applies when renting an auto.
+type PromoCode struct {
+	Code string `db:"code" gorm:"primaryKey"`
+	Kind string `db:"kind"`
+	// Value is the percent, or the amount in the unit of the checkout, taken off the rent days
+	Value      int        `db:"value"`
+	ValidFrom  *time.Time `db:"valid_from"`
+	ValidUntil *time.Time `db:"valid_until"`
+	// MaxUses limits the rents the code is applied to, 0 doesn't limit
+	MaxUses int `db:"max_uses"`
+	Uses    int `db:"uses"`
+	// AutoTypes the code is valid for, every type when empty
+	AutoTypes pq.StringArray `db:"auto_types" gorm:"type:text[]"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+func (a *PromoCode) TableName() string {
+	return "promo_code"
+}
+
+// DiscountTier takes Percent off the rent days of the rents of the auto type booked for MinDays or more.
+type DiscountTier struct {
+	AutoType string `db:"auto_type" gorm:"primaryKey"`
+	MinDays  int    `db:"min_days" gorm:"primaryKey"`
+	Percent  int    `db:"percent"`
+}
+
+func (a *DiscountTier) TableName() string {
+	return "discount_tier"
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+const (
+	ExtraPricingDay = "day"
+	// ExtraPricingRental charges the price once per rent, whatever its days
+	ExtraPricingRental = "rental"
+)
+
+// Extra is an add-on rented with an auto, like a child seat or an additional driver.
+type Extra struct {
+	ID      string `db:"id" gorm:"primaryKey"`
+	Name    string `db:"name"`
+	Pricing string `db:"pricing"`
+	Price   int    `db:"price"`
+	// Stocked extras are limited by their stock at the location of the auto, the others aren't
+	Stocked   bool      `db:"stocked"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (a *Extra) TableName() string {
+	return "extra"
+}
+
+// ExtraStock is how many of a stocked extra a location has, a location without stock has none.
+type ExtraStock struct {
+	ExtraID  string `db:"extra_id" gorm:"primaryKey"`
+	Location string `db:"location" gorm:"primaryKey"`
+	Quantity int    `db:"quantity"`
+}
+
+func (a *ExtraStock) TableName() string {
+	return "extra_stock"
+}
+
+// RentExtra is an extra rented with an auto, priced as the extra was at bind. Stocked extras are reserved
+// at the location from ReservedFrom to ReservedUntil, and past it until they are released.
+type RentExtra struct {
+	ID            int64      `db:"id"`
+	RentID        int        `db:"rent_id"`
+	ExtraID       string     `db:"extra_id"`
+	Name          string     `db:"name"`
+	Pricing       string     `db:"pricing"`
+	Price         int        `db:"price"`
+	Quantity      int        `db:"quantity"`
+	Stocked       bool       `db:"stocked"`
+	Location      string     `db:"location"`
+	ReservedFrom  time.Time  `db:"reserved_from"`
+	ReservedUntil time.Time  `db:"reserved_until"`
+	ReleasedAt    *time.Time `db:"released_at"`
+}
+
+func (a *RentExtra) TableName() string {
+	return "rent_extra"
+}
+
+// Charge is the price of the extra for a rent charged for days.
+func (a RentExtra) Charge(days int) int {
+	if a.Pricing == ExtraPricingDay {
+		return a.Price * a.Quantity * days
+	}
+	return a.Price * a.Quantity
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type IdempotencyKey struct {
+	Key          string `db:"key" gorm:"primaryKey"`
+	Scope        string `db:"scope" gorm:"primaryKey"`
+	Fingerprint  string `db:"fingerprint"`
+	StatusCode   int    `db:"status_code"`
+	ResponseBody []byte `db:"response_body"`
+	ContentType  string `db:"content_type"`
+	// Headers are the response headers replayed with the body, e.g. Location
+	Headers   map[string]string `db:"headers" gorm:"serializer:json"`
+	Completed bool              `db:"completed"`
+	CreatedAt time.Time         `db:"created_at"`
+	// ExpiresAt is when the key may be reused for another request and gets deleted
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+func (a *IdempotencyKey) TableName() string {
+	return "idempotency_key"
+}
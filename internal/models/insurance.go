@@ -0,0 +1,18 @@
+package models
+
+// InsurancePackage is an insurance a client chooses for a rent of the auto type, like basic or zero-excess.
+type InsurancePackage struct {
+	AutoType string `db:"auto_type" gorm:"primaryKey"`
+	Code     string `db:"code" gorm:"primaryKey"`
+	Name     string `db:"name"`
+	// DailyPrice is charged for every rent day
+	DailyPrice int `db:"daily_price"`
+	// RentalPrice is charged once per rent, on top of the days
+	RentalPrice int `db:"rental_price"`
+	// Excess is the most the client pays for damage to the auto, 0 covers all of it
+	Excess int `db:"excess"`
+}
+
+func (a *InsurancePackage) TableName() string {
+	return "insurance_package"
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+const (
+	InvoiceKindInvoice = "invoice"
+	// InvoiceKindCreditNote corrects an invoice, its amounts are negative
+	InvoiceKindCreditNote = "credit_note"
+)
+
+// Invoice is issued when a rent is closed and never changed afterwards, corrections are credit notes.
+// The issuer details are copied from the configuration at the time of issue.
+type Invoice struct {
+	ID     int64  `db:"id"`
+	Number string `db:"number"`
+	Kind   string `db:"kind"`
+	// CreditedInvoiceID is the invoice a credit note corrects
+	CreditedInvoiceID     *int64 `db:"credited_invoice_id"`
+	CreditedInvoiceNumber string `db:"credited_invoice_number"`
+	Reason                string `db:"reason"`
+	RentID                int    `db:"rent_id"`
+	AutoID                string `db:"auto_id"`
+	AutoType              string `db:"auto_type"`
+	ClientID              string `db:"client_id"`
+	IssuerName            string `db:"issuer_name"`
+	IssuerAddress         string `db:"issuer_address"`
+	IssuerTaxID           string `db:"issuer_tax_id"`
+	Currency              string `db:"currency"`
+	// Net, Tax and Gross are in the unit of the checkout
+	Net      int           `db:"net"`
+	Tax      int           `db:"tax"`
+	Gross    int           `db:"gross"`
+	IssuedAt time.Time     `db:"issued_at"`
+	Lines    []InvoiceLine `gorm:"foreignKey:InvoiceID"`
+	Taxes    []InvoiceTax  `gorm:"foreignKey:InvoiceID"`
+}
+
+func (a *Invoice) TableName() string {
+	return "invoice"
+}
+
+// InvoiceLine is a charge of the checkout, taxed at TaxRate percent.
+type InvoiceLine struct {
+	ID        int64 `db:"id"`
+	InvoiceID int64 `db:"invoice_id"`
+	Position  int   `db:"position"`
+	// CommissionType is the commission the line charges, like daily or penalty
+	CommissionType string  `db:"commission_type"`
+	Description    string  `db:"description"`
+	Quantity       int     `db:"quantity"`
+	UnitPrice      int     `db:"unit_price"`
+	Amount         int     `db:"amount"`
+	TaxRate        float64 `db:"tax_rate"`
+	// CreditedPosition is the position of the invoice line a credit note line reverses
+	CreditedPosition int `db:"credited_position"`
+}
+
+func (a *InvoiceLine) TableName() string {
+	return "invoice_line"
+}
+
+// InvoiceTax is the tax of the lines with the same rate.
+type InvoiceTax struct {
+	ID        int64   `db:"id"`
+	InvoiceID int64   `db:"invoice_id"`
+	Name      string  `db:"name"`
+	Rate      float64 `db:"rate"`
+	Base      int     `db:"base"`
+	Amount    int     `db:"amount"`
+}
+
+func (a *InvoiceTax) TableName() string {
+	return "invoice_tax"
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	LoyaltyKindEarn   = "earn"
+	LoyaltyKindRedeem = "redeem"
+	// LoyaltyKindExpire takes earned points past their expiry off the balance
+	LoyaltyKindExpire = "expire"
+	// LoyaltyKindAdjust corrects the balance, by an operator or for points redeemed but not used
+	LoyaltyKindAdjust = "adjust"
+)
+
+// LoyaltyEntry is a change of the points of a client, the balance is the sum of the entries.
+type LoyaltyEntry struct {
+	ID       int64  `db:"id"`
+	ClientID string `db:"client_id"`
+	Kind     string `db:"kind"`
+	// Points are negative for redeemed and expired points
+	Points int    `db:"points"`
+	RentID *int   `db:"rent_id"`
+	Note   string `db:"note"`
+	// ExpiresAt is when earned points can no longer be redeemed, nil when they don't expire
+	ExpiresAt *time.Time `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+func (a *LoyaltyEntry) TableName() string {
+	return "loyalty_entry"
+}
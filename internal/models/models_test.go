@@ -0,0 +1,22 @@
+package models
+
+import (
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+// TestSchemas parses every model the way gorm does before a query, a field gorm can't map fails them all.
+func TestSchemas(t *testing.T) {
+	for _, model := range []interface{}{
+		&Auto{}, &AutoRent{}, &AutoType{}, &Client{}, &Commission{}, &CommissionType{},
+		&IdempotencyKey{}, &OutboxEvent{}, &RentThreshold{}, &SchemaVersion{}, &WebhookSubscription{}, &WebhookDelivery{},
+		&Invoice{}, &InvoiceLine{}, &InvoiceTax{}, &Payment{}, &PromoCode{}, &DiscountTier{}, &Season{},
+		&LoyaltyEntry{}, &Extra{}, &ExtraStock{}, &RentExtra{}, &InsurancePackage{},
+	} {
+		if _, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{}); err != nil {
+			t.Errorf("%T: %v", model, err)
+		}
+	}
+}
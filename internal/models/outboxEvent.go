@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// OutboxEvent is a domain event waiting to be published, or kept after it was.
+type OutboxEvent struct {
+	ID          int64  `db:"id"`
+	Type        string `db:"type"`
+	AggregateID string `db:"aggregate_id"`
+	// Payload is the JSON of the event data
+	Payload       string     `db:"payload"`
+	OccurredAt    time.Time  `db:"occurred_at"`
+	Attempts      int        `db:"attempts"`
+	LastError     string     `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	PublishedAt   *time.Time `db:"published_at"`
+}
+
+func (a *OutboxEvent) TableName() string {
+	return "outbox_event"
+}
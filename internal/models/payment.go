@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	// PaymentKindDeposit is authorized when the auto is bound and captured for the checkout at its return
+	PaymentKindDeposit = "deposit"
+	// PaymentKindCharge collects what the checkout exceeds the deposit by
+	PaymentKindCharge = "charge"
+
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusCaptured   = "captured"
+	PaymentStatusVoided     = "voided"
+)
+
+// Payment is an authorization of the payment provider for a rent, amounts are in the unit of the checkout.
+type Payment struct {
+	ID     int64  `db:"id"`
+	RentID int    `db:"rent_id"`
+	Kind   string `db:"kind"`
+	// AuthorizationID is the id of the authorization at the payment provider
+	AuthorizationID string    `db:"authorization_id"`
+	Status          string    `db:"status"`
+	Authorized      int       `db:"authorized"`
+	Captured        int       `db:"captured"`
+	CreatedAt       time.Time `db:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at"`
+}
+
+func (a *Payment) TableName() string {
+	return "payment"
+}
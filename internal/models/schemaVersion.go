@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type SchemaVersion struct {
+	Version   int       `db:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `db:"name"`
+	AppliedAt time.Time `db:"applied_at" gorm:"default:now()"`
+}
+
+func (a *SchemaVersion) TableName() string {
+	return "schema_version"
+}
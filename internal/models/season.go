@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Season charges Percent of the daily price on the days from StartDate to EndDate, both included.
+type Season struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	StartDate time.Time `db:"start_date"`
+	EndDate   time.Time `db:"end_date"`
+	// Percent of the daily price, 150 charges half as much again and 80 is a low season
+	Percent int `db:"percent"`
+	// AutoTypes the season prices, every type when empty
+	AutoTypes pq.StringArray `db:"auto_types" gorm:"type:text[]"`
+	CreatedAt time.Time      `db:"created_at"`
+}
+
+// Contains tells whether the day, at any time of it, is one of the season.
+func (a Season) Contains(day time.Time) bool {
+	day = day.Truncate(24 * time.Hour)
+	return !day.Before(a.StartDate) && !day.After(a.EndDate)
+}
+
+func (a *Season) TableName() string {
+	return "season"
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	// DeliveryStatusDead deliveries ran out of attempts, they are retried only on request
+	DeliveryStatusDead = "dead"
+)
+
+// WebhookSubscription is a partner URL called with the events of the types, signed with the secret.
+type WebhookSubscription struct {
+	ID         int            `db:"id"`
+	Owner      string         `db:"owner"`
+	URL        string         `db:"url"`
+	EventTypes pq.StringArray `db:"event_types" gorm:"type:text[]"`
+	// ClientID limits the events to the rents of the client when set
+	ClientID  string    `db:"client_id"`
+	Secret    string    `db:"secret"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (a *WebhookSubscription) TableName() string {
+	return "webhook_subscription"
+}
+
+// WebhookDelivery is an event to be sent, or sent, to a subscription.
+type WebhookDelivery struct {
+	ID             int64  `db:"id"`
+	SubscriptionID int    `db:"subscription_id"`
+	EventID        int64  `db:"event_id"`
+	EventType      string `db:"event_type"`
+	// Payload is the JSON body sent
+	Payload        string     `db:"payload"`
+	Status         string     `db:"status" gorm:"default:pending"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LastStatusCode int        `db:"last_status_code"`
+	LastError      string     `db:"last_error"`
+	CreatedAt      time.Time  `db:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+}
+
+func (a *WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
@@ -0,0 +1,167 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf derives the schema of t the way encoding/json encodes it. Named structs
+// become components. In request schemas fields tagged binding:"required" are required,
+// in response schemas every field without omitempty is.
+func (s *Spec) schemaOf(t reflect.Type, request bool) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		schema := s.schemaOf(t.Elem(), request)
+		if schema.Ref != "" {
+			return &Schema{OneOf: []*Schema{schema}, Nullable: true}
+		}
+		schema.Nullable = true
+		return schema
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// encoding/json writes nil slices as null
+		return &Schema{Type: "array", Items: s.schemaOf(t.Elem(), request), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return &Schema{Type: "object"}
+		}
+		return &Schema{Type: "object", AdditionalProperties: s.schemaOf(t.Elem(), request)}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t, request)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t, request)}
+	}
+	// interfaces accept any value
+	return &Schema{}
+}
+
+func (s *Spec) component(t reflect.Type, request bool) string {
+	if name, ok := s.componentNames[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := s.doc.Components.Schemas[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = pkg + t.Name()
+	}
+	s.componentNames[t] = name
+	// registered before the fields are walked, so recursive types terminate
+	s.doc.Components.Schemas[name] = &Schema{}
+	*s.doc.Components.Schemas[name] = *s.structSchema(t, request)
+	return name
+}
+
+func (s *Spec) structSchema(t reflect.Type, request bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitEmpty, skip := jsonField(field)
+		if skip {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && name == "" {
+			embedded := s.structSchema(field.Type, request)
+			for property, propertySchema := range embedded.Properties {
+				schema.Properties[property] = propertySchema
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		propertySchema := s.schemaOf(field.Type, request)
+		rules := bindingRules(field)
+		if request {
+			applyBindingRules(propertySchema, rules)
+		}
+		schema.Properties[name] = propertySchema
+		if request && rules["required"] != "" || !request && !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+func jsonField(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", false, true
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return parts[0], omitEmpty, false
+}
+
+// bindingRules parses the validator tags gin checks requests with.
+func bindingRules(field reflect.StructField) map[string]string {
+	rules := map[string]string{}
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "" {
+			continue
+		}
+		if param == "" {
+			param = name
+		}
+		rules[name] = param
+	}
+	return rules
+}
+
+func applyBindingRules(schema *Schema, rules map[string]string) {
+	for rule, param := range rules {
+		switch rule {
+		case "min", "max":
+			bound, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			switch {
+			case schema.Type == "string" && rule == "min":
+				schema.MinLength = &bound
+			case schema.Type == "string":
+				schema.MaxLength = &bound
+			case rule == "min":
+				minimum := float64(bound)
+				schema.Minimum = &minimum
+			default:
+				maximum := float64(bound)
+				schema.Maximum = &maximum
+			}
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "datetime":
+			if param == "2006-01-02" {
+				schema.Format = "date"
+			}
+		}
+	}
+}
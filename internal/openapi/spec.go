@@ -0,0 +1,212 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Route documents one registered handler. Request and Responses hold values of the
+// body types, the schemas are derived from them.
+type Route struct {
+	Summary    string
+	Deprecated bool
+	// Secured routes require one of the registered security schemes
+	Secured bool
+	// Headers lists optional request headers the route understands
+	Headers []string
+	// Query holds a value of the struct the query parameters are bound to
+	Query     interface{}
+	Request   interface{}
+	Responses map[int]interface{}
+}
+
+type oneOf []interface{}
+
+type text struct {
+	contentType string
+}
+
+// Text documents a response that isn't JSON, like the Prometheus exposition format.
+func Text(contentType string) interface{} {
+	return text{contentType: contentType}
+}
+
+// OneOf documents a response whose body has one of several shapes.
+func OneOf(values ...interface{}) interface{} {
+	return oneOf(values)
+}
+
+// MergeResponses combines response maps, bodies documented for the same status
+// in several maps become alternatives.
+func MergeResponses(responses ...map[int]interface{}) map[int]interface{} {
+	merged := map[int]interface{}{}
+	for _, m := range responses {
+		for status, value := range m {
+			existing, ok := merged[status]
+			if !ok {
+				merged[status] = value
+				continue
+			}
+			merged[status] = append(alternatives(existing), alternatives(value)...)
+		}
+	}
+	return merged
+}
+
+func alternatives(value interface{}) oneOf {
+	if values, ok := value.(oneOf); ok {
+		return values
+	}
+	return oneOf{value}
+}
+
+type Spec struct {
+	doc            *Document
+	componentNames map[reflect.Type]string
+	security       []map[string][]string
+}
+
+func NewSpec(title string, version string) *Spec {
+	return &Spec{
+		doc: &Document{
+			OpenAPI: "3.0.3",
+			Info:    Info{Title: title, Version: version},
+			Paths:   map[string]map[string]*Operation{},
+			Components: Components{
+				Schemas:         map[string]*Schema{},
+				SecuritySchemes: map[string]*SecurityScheme{},
+			},
+		},
+		componentNames: map[reflect.Type]string{},
+	}
+}
+
+func (s *Spec) AddSecurityScheme(name string, scheme SecurityScheme) {
+	s.doc.Components.SecuritySchemes[name] = &scheme
+	s.security = append(s.security, map[string][]string{name: {}})
+}
+
+// Add documents a route registered under a gin path like /rentals/:id.
+func (s *Spec) Add(method string, ginPath string, route Route) {
+	path, params := TemplatePath(ginPath)
+	operation := &Operation{
+		Summary:    route.Summary,
+		Deprecated: route.Deprecated,
+		Responses:  map[string]*Response{},
+	}
+	for _, param := range params {
+		operation.Parameters = append(operation.Parameters,
+			Parameter{Name: param, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, header := range route.Headers {
+		operation.Parameters = append(operation.Parameters,
+			Parameter{Name: header, In: "header", Schema: &Schema{Type: "string"}})
+	}
+	if route.Query != nil {
+		operation.Parameters = append(operation.Parameters, s.queryParameters(reflect.TypeOf(route.Query))...)
+	}
+	if route.Secured {
+		operation.Security = s.security
+	}
+	if route.Request != nil {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(s.bodySchema(route.Request, true)),
+		}
+	}
+	statuses := make([]int, 0, len(route.Responses))
+	for status := range route.Responses {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		response := &Response{Description: statusDescription(status)}
+		if body, ok := route.Responses[status].(text); ok {
+			response.Content = map[string]*MediaType{body.contentType: {Schema: &Schema{Type: "string"}}}
+		} else {
+			response.Content = jsonContent(s.bodySchema(route.Responses[status], false))
+		}
+		operation.Responses[strconv.Itoa(status)] = response
+	}
+	if s.doc.Paths[path] == nil {
+		s.doc.Paths[path] = map[string]*Operation{}
+	}
+	s.doc.Paths[path][strings.ToLower(method)] = operation
+}
+
+func (s *Spec) Document() *Document {
+	return s.doc
+}
+
+// queryParameters documents the fields of a struct bound with form tags.
+func (s *Spec) queryParameters(t reflect.Type) []Parameter {
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		schema := s.schemaOf(fieldType, true)
+		rules := bindingRules(field)
+		applyBindingRules(schema, rules)
+		params = append(params, Parameter{Name: name, In: "query", Required: rules["required"] != "", Schema: schema})
+	}
+	return params
+}
+
+func (s *Spec) bodySchema(value interface{}, request bool) *Schema {
+	if values, ok := value.(oneOf); ok {
+		schema := &Schema{}
+		for _, v := range values {
+			schema.OneOf = append(schema.OneOf, s.schemaOf(reflect.TypeOf(v), request))
+		}
+		return schema
+	}
+	return s.schemaOf(reflect.TypeOf(value), request)
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+// TemplatePath turns /rentals/:id into /rentals/{id}.
+func TemplatePath(ginPath string) (string, []string) {
+	var params []string
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	return path, params
+}
+
+func statusDescription(status int) string {
+	switch {
+	case status < 300:
+		return "success"
+	case status == 401:
+		return "missing or invalid credentials"
+	case status == 403:
+		return "forbidden"
+	case status == 404:
+		return "not found"
+	case status == 409:
+		return "conflict"
+	case status < 500:
+		return "invalid request"
+	}
+	return "internal server error"
+}
@@ -0,0 +1,123 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ResponseSchema returns the documented body schema of a response.
+func (d *Document) ResponseSchema(method string, path string, status int) (*Schema, error) {
+	operation, ok := d.Paths[path][strings.ToLower(method)]
+	if !ok {
+		return nil, fmt.Errorf("%s %s is not documented", method, path)
+	}
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		return nil, fmt.Errorf("%s %s does not document status %d", method, path, status)
+	}
+	return response.Content["application/json"].Schema, nil
+}
+
+// Validate checks a value decoded from JSON against the schema. Objects with
+// documented properties must not have undocumented ones.
+func (d *Document) Validate(schema *Schema, value interface{}) error {
+	return d.validate(schema, value, "$")
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, path string) error {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		referenced, ok := d.Components.Schemas[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", path, schema.Ref)
+		}
+		return d.validate(referenced, value, path)
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" && len(schema.OneOf) == 0 {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", path)
+	}
+	if len(schema.OneOf) != 0 {
+		var errs []string
+		for _, alternative := range schema.OneOf {
+			err := d.validate(alternative, value, path)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("%s: matches none of the alternatives (%s)", path, strings.Join(errs, "; "))
+	}
+	switch schema.Type {
+	case "":
+		return nil
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: want string, got %T", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: want boolean, got %T", path, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: want number, got %T", path, value)
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Errorf("%s: want integer, got %v", path, value)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want array, got %T", path, value)
+		}
+		for i, item := range items {
+			if err := d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want object, got %T", path, value)
+		}
+		return d.validateObject(schema, object, path)
+	}
+	return nil
+}
+
+func (d *Document) validateObject(schema *Schema, object map[string]interface{}, path string) error {
+	for _, required := range schema.Required {
+		if _, ok := object[required]; !ok {
+			return fmt.Errorf("%s: missing required property %s", path, required)
+		}
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		propertySchema, ok := schema.Properties[key]
+		if !ok {
+			propertySchema = schema.AdditionalProperties
+		}
+		if propertySchema == nil {
+			if len(schema.Properties) == 0 {
+				continue
+			}
+			return fmt.Errorf("%s: undocumented property %s", path, key)
+		}
+		if err := d.validate(propertySchema, object[key], path+"."+key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package payments holds the payment providers the rental service collects deposits and checkouts through.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	ProviderNone = "none"
+	// ProviderMemory keeps the payments in memory, standing in for a payment gateway in development
+	ProviderMemory = "memory"
+
+	StatusAuthorized = "authorized"
+	StatusCaptured   = "captured"
+	StatusVoided     = "voided"
+)
+
+var (
+	ErrDeclined             = errors.New("payment declined")
+	ErrUnknownAuthorization = errors.New("unknown authorization")
+	ErrAmountExceeded       = errors.New("amount exceeds the authorization")
+)
+
+// Authorization is a hold on the payment method of a client, kept by Memory.
+type Authorization struct {
+	ID        string
+	ClientID  string
+	Reference string
+	Amount    int
+	Captured  int
+	Refunded  int
+	Status    string
+}
+
+// Memory is a payment provider keeping the authorizations in memory, for tests and development.
+type Memory struct {
+	mu             sync.Mutex
+	authorizations map[string]*Authorization
+	// references deduplicate retried authorizations
+	references map[string]string
+	declined   map[string]bool
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		authorizations: map[string]*Authorization{},
+		references:     map[string]string{},
+		declined:       map[string]bool{},
+	}
+}
+
+// Decline makes the authorizations of the client fail, like a card without funds.
+func (m *Memory) Decline(clientId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.declined[clientId] = true
+}
+
+// Authorization returns a copy of the authorization.
+func (m *Memory) Authorization(id string) (Authorization, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	authorization, ok := m.authorizations[id]
+	if !ok {
+		return Authorization{}, false
+	}
+	return *authorization, true
+}
+
+// Authorize returns the authorization of an earlier call with the same reference.
+func (m *Memory) Authorize(ctx context.Context, clientId string, amount int, reference string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id, ok := m.references[reference]; ok {
+		return id, nil
+	}
+	if m.declined[clientId] {
+		return "", ErrDeclined
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("amount %d must be positive", amount)
+	}
+	id := fmt.Sprintf("auth_%d", len(m.authorizations)+1)
+	m.authorizations[id] = &Authorization{ID: id, ClientID: clientId, Reference: reference, Amount: amount,
+		Status: StatusAuthorized}
+	m.references[reference] = id
+	return id, nil
+}
+
+func (m *Memory) Capture(ctx context.Context, authorizationId string, amount int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	authorization, err := m.get(authorizationId, StatusAuthorized)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || amount > authorization.Amount {
+		return ErrAmountExceeded
+	}
+	authorization.Captured = amount
+	authorization.Status = StatusCaptured
+	return nil
+}
+
+func (m *Memory) Refund(ctx context.Context, authorizationId string, amount int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	authorization, err := m.get(authorizationId, StatusCaptured)
+	if err != nil {
+		return err
+	}
+	if amount <= 0 || authorization.Refunded+amount > authorization.Captured {
+		return ErrAmountExceeded
+	}
+	authorization.Refunded += amount
+	return nil
+}
+
+func (m *Memory) Void(ctx context.Context, authorizationId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	authorization, err := m.get(authorizationId, StatusAuthorized)
+	if err != nil {
+		return err
+	}
+	authorization.Status = StatusVoided
+	return nil
+}
+
+func (m *Memory) get(authorizationId string, status string) (*Authorization, error) {
+	authorization, ok := m.authorizations[authorizationId]
+	if !ok {
+		return nil, ErrUnknownAuthorization
+	}
+	if authorization.Status != status {
+		return nil, fmt.Errorf("authorization %s is %s", authorizationId, authorization.Status)
+	}
+	return authorization, nil
+}
@@ -0,0 +1,45 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	id, err := m.Authorize(ctx, "client-1", 500, "rent-1-deposit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retried, _ := m.Authorize(ctx, "client-1", 500, "rent-1-deposit"); retried != id {
+		t.Errorf("want the authorization of the reference, got %s and %s", id, retried)
+	}
+	if err = m.Capture(ctx, id, 600); !errors.Is(err, ErrAmountExceeded) {
+		t.Errorf("want %v, got %v", ErrAmountExceeded, err)
+	}
+	if err = m.Capture(ctx, id, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Void(ctx, id); err == nil {
+		t.Error("want a captured authorization not voided")
+	}
+	if err = m.Refund(ctx, id, 200); err != nil {
+		t.Fatal(err)
+	}
+	if err = m.Refund(ctx, id, 200); !errors.Is(err, ErrAmountExceeded) {
+		t.Errorf("want at most the captured amount refunded, got %v", err)
+	}
+	if authorization, _ := m.Authorization(id); authorization.Captured != 300 || authorization.Refunded != 200 {
+		t.Errorf("unexpected authorization %+v", authorization)
+	}
+
+	m.Decline("client-2")
+	if _, err = m.Authorize(ctx, "client-2", 500, "rent-2-deposit"); !errors.Is(err, ErrDeclined) {
+		t.Errorf("want %v, got %v", ErrDeclined, err)
+	}
+	if err = m.Capture(ctx, "auth_9", 100); !errors.Is(err, ErrUnknownAuthorization) {
+		t.Errorf("want %v, got %v", ErrUnknownAuthorization, err)
+	}
+}
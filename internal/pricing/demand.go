@@ -0,0 +1,51 @@
+// Package pricing holds the demand levels raising the daily prices of an auto type while most of its autos are rented.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level charges the daily prices at Percent once Share percent of the autos of a type are rented.
+type Level struct {
+	Share   int
+	Percent int
+}
+
+// ParseLevel reads a share:percent entry, like 80:120 for 120% of the daily price from 80% of the fleet rented.
+func ParseLevel(entry string) (Level, error) {
+	share, percent, ok := strings.Cut(entry, ":")
+	if !ok {
+		return Level{}, errors.New("demand levels must be share:percent")
+	}
+	level := Level{}
+	var err error
+	if level.Share, err = strconv.Atoi(share); err != nil || level.Share < 0 || level.Share > 100 {
+		return Level{}, fmt.Errorf("demand share %q must be a percentage from 0 to 100", share)
+	}
+	if level.Percent, err = strconv.Atoi(percent); err != nil || level.Percent <= 0 {
+		return Level{}, fmt.Errorf("demand percent %q must be a positive number", percent)
+	}
+	return level, nil
+}
+
+// Demand are the levels of an auto type, the zero value keeps the daily prices.
+type Demand []Level
+
+// Percent is the percentage of the daily price charged with rented autos of the fleet out,
+// that of the highest share reached or 100.
+func (d Demand) Percent(rented int, fleet int) int {
+	if fleet == 0 {
+		return 100
+	}
+	share := rented * 100 / fleet
+	percent, reached := 100, -1
+	for _, level := range d {
+		if level.Share <= share && level.Share > reached {
+			percent, reached = level.Percent, level.Share
+		}
+	}
+	return percent
+}
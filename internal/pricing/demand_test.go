@@ -0,0 +1,38 @@
+package pricing
+
+import "testing"
+
+func TestPercent(t *testing.T) {
+	demand := Demand{{Share: 90, Percent: 130}, {Share: 70, Percent: 115}}
+	for _, test := range []struct {
+		rented int
+		fleet  int
+		want   int
+	}{
+		{0, 10, 100},
+		{6, 10, 100},
+		{7, 10, 115},
+		{9, 10, 130},
+		{10, 10, 130},
+		{0, 0, 100},
+	} {
+		if got := demand.Percent(test.rented, test.fleet); got != test.want {
+			t.Errorf("%d of %d rented: want %d, got %d", test.rented, test.fleet, test.want, got)
+		}
+	}
+	if got := (Demand{}).Percent(10, 10); got != 100 {
+		t.Errorf("want the daily price without levels, got %d", got)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("80:120")
+	if err != nil || level != (Level{Share: 80, Percent: 120}) {
+		t.Errorf("unexpected level %+v, %v", level, err)
+	}
+	for _, entry := range []string{"80", "high:120", "101:120", "80:0", "80:much"} {
+		if _, err := ParseLevel(entry); err == nil {
+			t.Errorf("want %q rejected", entry)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"time"
+
+	"car-rental/internal/models"
+)
+
+const (
+	AutoSortById    = "id"
+	AutoSortByPrice = "price"
+)
+
+// AutoQuery filters the auto catalogue, zero values don't filter.
+type AutoQuery struct {
+	Type     string
+	Status   string
+	Location string
+	// Make and Model match case-insensitively
+	Make         string
+	Model        string
+	MinYear      int
+	MaxYear      int
+	MinSeats     int
+	FuelType     string
+	Transmission string
+	// Features lists tags every listed auto must have
+	Features []string
+	MinPrice *int
+	MaxPrice *int
+	// AvailableFrom and AvailableTo select autos without an active rent overlapping the window
+	AvailableFrom *time.Time
+	AvailableTo   *time.Time
+	SortBy        string
+	Descending    bool
+	// After continues the listing behind the last auto of the previous page
+	After *AutoCursor
+	Limit int
+}
+
+// AutoCursor is the sort key of the last listed auto.
+type AutoCursor struct {
+	ID    string `json:"id"`
+	Price int    `json:"price"`
+}
+
+// AutoListing is an auto with its daily price.
+type AutoListing struct {
+	models.Auto `gorm:"embedded"`
+	DailyPrice  int `db:"daily_price"`
+}
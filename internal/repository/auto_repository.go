@@ -1,10 +1,21 @@
 package repository
 
-import "car-rental/internal/models"
+import (
+	"context"
+
+	"car-rental/internal/models"
+)
 
 type AutoRepository interface {
-	GetAvailableAutoByType(autoType string) ([]models.Auto, error)
-	GetAutoById(autoId string) (models.Auto, error)
-	BindAuto(autoId string) error
-	ReleaseAuto(autoId string) error
+	GetAutoTypes(ctx context.Context) ([]models.AutoType, error)
+	GetAvailableAutoByType(ctx context.Context, autoType string) ([]models.Auto, error)
+	FindAutos(ctx context.Context, query AutoQuery) ([]AutoListing, error)
+	// CountFleet counts the autos of the type not retired and how many of them are rented
+	CountFleet(ctx context.Context, autoType string) (fleet int, rented int, err error)
+	GetAutoById(ctx context.Context, autoId string) (models.Auto, error)
+	CreateAuto(ctx context.Context, auto models.Auto) (models.Auto, error)
+	// RetireAuto takes the auto out of the fleet, it stays for the rent history
+	RetireAuto(ctx context.Context, autoId string) error
+	BindAuto(ctx context.Context, autoId string) error
+	ReleaseAuto(ctx context.Context, autoId string) error
 }
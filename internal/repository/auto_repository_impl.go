@@ -2,6 +2,8 @@ package repository
 
 import (
 	"car-rental/internal/models"
+	"context"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -13,46 +15,164 @@ func NewAutoRepositoryImpl(db *gorm.DB) *AutoRepositoryImpl {
 	return &AutoRepositoryImpl{DB: db}
 }
 
-func (a AutoRepositoryImpl) GetAvailableAutoByType(autoType string) ([]models.Auto, error) {
+func (a AutoRepositoryImpl) GetAutoTypes(ctx context.Context) ([]models.AutoType, error) {
+	var autoTypes []models.AutoType
+	res := conn(ctx, a.DB).Order("id").Find(&autoTypes)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return autoTypes, nil
+}
+
+func (a AutoRepositoryImpl) GetAvailableAutoByType(ctx context.Context, autoType string) ([]models.Auto, error) {
 	var auto []models.Auto
 	filter := &models.Auto{
 		Type:         autoType,
 		Availability: true,
 	}
-	res := a.DB.Where(filter).Find(&auto)
+	res := conn(ctx, a.DB).Where(filter).Where("retired_at IS NULL").Find(&auto)
 	if res.Error != nil {
 		return nil, res.Error
 	}
 	return auto, nil
 }
 
-func (a AutoRepositoryImpl) GetAutoById(autoId string) (models.Auto, error) {
+func (a AutoRepositoryImpl) FindAutos(ctx context.Context, query AutoQuery) ([]AutoListing, error) {
+	var autos []AutoListing
+	tx := conn(ctx, a.DB).Table("auto").
+		Select("auto.*, COALESCE(daily.value, 0) AS daily_price").
+		Joins("LEFT JOIN commission daily ON daily.auto_type = auto.type AND daily.type = ?", "daily")
+	if query.Type != "" {
+		tx = tx.Where("auto.type = ?", query.Type)
+	}
+	switch query.Status {
+	case models.AutoStatusAvailable:
+		tx = tx.Where("auto.availability AND auto.retired_at IS NULL")
+	case models.AutoStatusRented:
+		tx = tx.Where("NOT auto.availability AND auto.retired_at IS NULL")
+	case models.AutoStatusRetired:
+		tx = tx.Where("auto.retired_at IS NOT NULL")
+	default:
+		tx = tx.Where("auto.retired_at IS NULL")
+	}
+	if query.Location != "" {
+		tx = tx.Where("auto.location = ?", query.Location)
+	}
+	if query.Make != "" {
+		tx = tx.Where("LOWER(auto.make) = LOWER(?)", query.Make)
+	}
+	if query.Model != "" {
+		tx = tx.Where("LOWER(auto.model) = LOWER(?)", query.Model)
+	}
+	if query.MinYear != 0 {
+		tx = tx.Where("auto.year >= ?", query.MinYear)
+	}
+	if query.MaxYear != 0 {
+		tx = tx.Where("auto.year <= ?", query.MaxYear)
+	}
+	if query.MinSeats != 0 {
+		tx = tx.Where("auto.seats >= ?", query.MinSeats)
+	}
+	if query.FuelType != "" {
+		tx = tx.Where("auto.fuel_type = ?", query.FuelType)
+	}
+	if query.Transmission != "" {
+		tx = tx.Where("auto.transmission = ?", query.Transmission)
+	}
+	if len(query.Features) != 0 {
+		tx = tx.Where("auto.features @> ?", pq.StringArray(query.Features))
+	}
+	if query.MinPrice != nil {
+		tx = tx.Where("COALESCE(daily.value, 0) >= ?", *query.MinPrice)
+	}
+	if query.MaxPrice != nil {
+		tx = tx.Where("COALESCE(daily.value, 0) <= ?", *query.MaxPrice)
+	}
+	if query.AvailableFrom != nil && query.AvailableTo != nil {
+		tx = tx.Where(`NOT EXISTS (SELECT 1 FROM auto_rent rent
+			WHERE rent.auto_id = auto.id AND rent.status = ? AND rent.start_date <= ? AND rent.end_date >= ?)`,
+			models.RentStatusActive, *query.AvailableTo, *query.AvailableFrom)
+	}
+	order, compare := "ASC", ">"
+	if query.Descending {
+		order, compare = "DESC", "<"
+	}
+	if query.SortBy == AutoSortByPrice {
+		if query.After != nil {
+			tx = tx.Where("(COALESCE(daily.value, 0), auto.id) "+compare+" (?, ?)", query.After.Price, query.After.ID)
+		}
+		tx = tx.Order("daily_price " + order).Order("auto.id " + order)
+	} else {
+		if query.After != nil {
+			tx = tx.Where("auto.id "+compare+" ?", query.After.ID)
+		}
+		tx = tx.Order("auto.id " + order)
+	}
+	res := tx.Limit(query.Limit).Scan(&autos)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return autos, nil
+}
+
+func (a AutoRepositoryImpl) GetAutoById(ctx context.Context, autoId string) (models.Auto, error) {
 	var auto models.Auto
-	res := a.DB.Where("id = ?", autoId).First(&auto)
+	res := conn(ctx, a.DB).Where("id = ?", autoId).First(&auto)
 	if res.Error != nil {
 		return auto, res.Error
 	}
 	return auto, nil
 }
 
-func (a AutoRepositoryImpl) BindAuto(autoId string) error {
+func (a AutoRepositoryImpl) CreateAuto(ctx context.Context, auto models.Auto) (models.Auto, error) {
+	res := conn(ctx, a.DB).Create(&auto)
+	if res.Error != nil {
+		return auto, res.Error
+	}
+	return auto, nil
+}
+
+func (a AutoRepositoryImpl) CountFleet(ctx context.Context, autoType string) (fleet int, rented int, err error) {
+	var counts struct {
+		Fleet  int
+		Rented int
+	}
+	res := conn(ctx, a.DB).Model(&models.Auto{}).
+		Select("count(*) AS fleet, count(*) FILTER (WHERE NOT availability) AS rented").
+		Where("type = ? AND retired_at IS NULL", autoType).
+		Scan(&counts)
+	return counts.Fleet, counts.Rented, res.Error
+}
+
+func (a AutoRepositoryImpl) RetireAuto(ctx context.Context, autoId string) error {
+	res := conn(ctx, a.DB).Model(&models.Auto{}).
+		Where("id = ? AND retired_at IS NULL", autoId).
+		Updates(map[string]interface{}{"retired_at": gorm.Expr("now()"), "availability": false})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (a AutoRepositoryImpl) BindAuto(ctx context.Context, autoId string) error {
 	var auto models.Auto
-	res := a.DB.Where("id = ?", autoId).First(&auto)
+	res := conn(ctx, a.DB).Where("id = ?", autoId).First(&auto)
 	if res.Error != nil {
 		return res.Error
 	}
 	auto.Availability = false
-	a.DB.Save(&auto)
-	return nil
+	return conn(ctx, a.DB).Save(&auto).Error
 }
 
-func (a AutoRepositoryImpl) ReleaseAuto(autoId string) error {
+func (a AutoRepositoryImpl) ReleaseAuto(ctx context.Context, autoId string) error {
 	var auto models.Auto
-	res := a.DB.Where("id = ?", autoId).First(&auto)
+	res := conn(ctx, a.DB).Where("id = ?", autoId).First(&auto)
 	if res.Error != nil {
 		return res.Error
 	}
 	auto.Availability = true
-	a.DB.Save(&auto)
-	return nil
+	return conn(ctx, a.DB).Save(&auto).Error
 }
@@ -1,7 +1,13 @@
 package repository
 
-import "car-rental/internal/models"
+import (
+	"context"
+
+	"car-rental/internal/models"
+)
 
 type CommissionRepository interface {
-	GetCommissionsByType(autoType string) []models.Commission
+	GetCommissionsByType(ctx context.Context, autoType string) []models.Commission
+	// SetCommission replaces the commission of the same auto type and commission type
+	SetCommission(ctx context.Context, commission models.Commission) error
 }
@@ -1,20 +1,41 @@
 package repository
 
 import (
+	"context"
+	"log/slog"
+
 	"car-rental/internal/models"
 	"gorm.io/gorm"
 )
 
 type CommissionRepositoryImpl struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Logger *slog.Logger
 }
 
-func NewCommissionRepositoryImpl(db *gorm.DB) *CommissionRepositoryImpl {
-	return &CommissionRepositoryImpl{DB: db}
+func NewCommissionRepositoryImpl(db *gorm.DB, logger *slog.Logger) *CommissionRepositoryImpl {
+	return &CommissionRepositoryImpl{DB: db, Logger: logger}
 }
 
-func (r CommissionRepositoryImpl) GetCommissionsByType(autoType string) []models.Commission {
+// GetCommissionsByType returns nil when the commissions can't be read, the cause is only logged.
+func (r CommissionRepositoryImpl) GetCommissionsByType(ctx context.Context, autoType string) []models.Commission {
 	var commissions []models.Commission
-	r.DB.Where("auto_type = ?", autoType).Find(&commissions)
+	res := conn(ctx, r.DB).Where("auto_type = ?", autoType).Find(&commissions)
+	if res.Error != nil {
+		r.Logger.ErrorContext(ctx, "reading commissions failed",
+			slog.String("auto_type", autoType), slog.Any("error", res.Error))
+		return nil
+	}
 	return commissions
 }
+
+func (r CommissionRepositoryImpl) SetCommission(ctx context.Context, commission models.Commission) error {
+	return conn(ctx, r.DB).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("auto_type = ? AND type = ?", commission.AutoType, commission.Type).
+			Delete(&models.Commission{})
+		if res.Error != nil {
+			return res.Error
+		}
+		return tx.Create(&commission).Error
+	})
+}
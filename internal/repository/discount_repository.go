@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+)
+
+type DiscountRepository interface {
+	// GetDiscountTiers returns the long rental tiers of the auto type, the fewest days first
+	GetDiscountTiers(ctx context.Context, autoType string) ([]models.DiscountTier, error)
+	// SetDiscountTier replaces the tier of the auto type and days, a tier of 0 percent is removed
+	SetDiscountTier(ctx context.Context, tier models.DiscountTier) error
+	CreatePromoCode(ctx context.Context, promo models.PromoCode) (models.PromoCode, error)
+	GetPromoCode(ctx context.Context, code string) (models.PromoCode, error)
+	FindPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+	// RedeemPromoCode counts a use of the code, false when it is used up
+	RedeemPromoCode(ctx context.Context, code string) (bool, error)
+}
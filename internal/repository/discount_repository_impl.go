@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+type DiscountRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewDiscountRepositoryImpl(db *gorm.DB) *DiscountRepositoryImpl {
+	return &DiscountRepositoryImpl{DB: db}
+}
+
+func (r DiscountRepositoryImpl) GetDiscountTiers(ctx context.Context, autoType string) ([]models.DiscountTier, error) {
+	var tiers []models.DiscountTier
+	if res := conn(ctx, r.DB).Where("auto_type = ?", autoType).Order("min_days").Find(&tiers); res.Error != nil {
+		return nil, res.Error
+	}
+	return tiers, nil
+}
+
+func (r DiscountRepositoryImpl) SetDiscountTier(ctx context.Context, tier models.DiscountTier) error {
+	return conn(ctx, r.DB).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("auto_type = ? AND min_days = ?", tier.AutoType, tier.MinDays).Delete(&models.DiscountTier{})
+		if res.Error != nil || tier.Percent == 0 {
+			return res.Error
+		}
+		return tx.Create(&tier).Error
+	})
+}
+
+func (r DiscountRepositoryImpl) CreatePromoCode(ctx context.Context, promo models.PromoCode) (models.PromoCode, error) {
+	if promo.AutoTypes == nil {
+		// a nil array is stored as NULL
+		promo.AutoTypes = pq.StringArray{}
+	}
+	res := conn(ctx, r.DB).Create(&promo)
+	return promo, res.Error
+}
+
+func (r DiscountRepositoryImpl) GetPromoCode(ctx context.Context, code string) (models.PromoCode, error) {
+	var promo models.PromoCode
+	res := conn(ctx, r.DB).Where("code = ?", code).First(&promo)
+	return promo, res.Error
+}
+
+func (r DiscountRepositoryImpl) FindPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	var promos []models.PromoCode
+	if res := conn(ctx, r.DB).Order("code").Find(&promos); res.Error != nil {
+		return nil, res.Error
+	}
+	return promos, nil
+}
+
+func (r DiscountRepositoryImpl) RedeemPromoCode(ctx context.Context, code string) (bool, error) {
+	// the condition and the increment are one statement, so concurrent rents can't exceed the limit
+	res := conn(ctx, r.DB).Model(&models.PromoCode{}).
+		Where("code = ? AND (max_uses = 0 OR uses < max_uses)", code).
+		Update("uses", gorm.Expr("uses + 1"))
+	return res.RowsAffected == 1, res.Error
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+)
+
+type ExtraRepository interface {
+	// SaveExtra adds the extra to the catalogue or replaces it, rents already bound keep their price
+	SaveExtra(ctx context.Context, extra models.Extra) (models.Extra, error)
+	GetExtra(ctx context.Context, id string) (models.Extra, error)
+	FindExtras(ctx context.Context) ([]models.Extra, error)
+	// SetStock replaces the quantity of the extra at the location
+	SetStock(ctx context.Context, stock models.ExtraStock) error
+	// FindStock returns the stock of every extra and location, of one location when it isn't empty
+	FindStock(ctx context.Context, location string) ([]models.ExtraStock, error)
+	// LockStock returns the quantity of the extra at the location, 0 without stock, locking it until
+	// the transaction ends
+	LockStock(ctx context.Context, extraId string, location string) (int, error)
+	// CountReserved sums the extras reserved at the location from from to until,
+	// counting the reservations not released past their end
+	CountReserved(ctx context.Context, extraId string, location string, from time.Time, until time.Time) (int, error)
+	ReserveExtra(ctx context.Context, extra models.RentExtra) (models.RentExtra, error)
+	GetRentExtras(ctx context.Context, rentId int) ([]models.RentExtra, error)
+	// ReleaseExtras ends the reservations of the rent at releasedAt, freeing the stock for later rents
+	ReleaseExtras(ctx context.Context, rentId int, releasedAt time.Time) error
+}
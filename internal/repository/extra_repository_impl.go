@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ExtraRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewExtraRepositoryImpl(db *gorm.DB) *ExtraRepositoryImpl {
+	return &ExtraRepositoryImpl{DB: db}
+}
+
+func (r ExtraRepositoryImpl) SaveExtra(ctx context.Context, extra models.Extra) (models.Extra, error) {
+	res := conn(ctx, r.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "pricing", "price", "stocked"}),
+	}).Create(&extra)
+	return extra, res.Error
+}
+
+func (r ExtraRepositoryImpl) GetExtra(ctx context.Context, id string) (models.Extra, error) {
+	var extra models.Extra
+	res := conn(ctx, r.DB).Where("id = ?", id).First(&extra)
+	return extra, res.Error
+}
+
+func (r ExtraRepositoryImpl) FindExtras(ctx context.Context) ([]models.Extra, error) {
+	var extras []models.Extra
+	if res := conn(ctx, r.DB).Order("id").Find(&extras); res.Error != nil {
+		return nil, res.Error
+	}
+	return extras, nil
+}
+
+func (r ExtraRepositoryImpl) SetStock(ctx context.Context, stock models.ExtraStock) error {
+	return conn(ctx, r.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "extra_id"}, {Name: "location"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity"}),
+	}).Create(&stock).Error
+}
+
+func (r ExtraRepositoryImpl) FindStock(ctx context.Context, location string) ([]models.ExtraStock, error) {
+	var stock []models.ExtraStock
+	tx := conn(ctx, r.DB).Order("extra_id, location")
+	if location != "" {
+		tx = tx.Where("location = ?", location)
+	}
+	if res := tx.Find(&stock); res.Error != nil {
+		return nil, res.Error
+	}
+	return stock, nil
+}
+
+func (r ExtraRepositoryImpl) LockStock(ctx context.Context, extraId string, location string) (int, error) {
+	var stock []models.ExtraStock
+	res := conn(ctx, r.DB).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("extra_id = ? AND location = ?", extraId, location).Find(&stock)
+	if res.Error != nil || len(stock) == 0 {
+		return 0, res.Error
+	}
+	return stock[0].Quantity, nil
+}
+
+func (r ExtraRepositoryImpl) CountReserved(
+	ctx context.Context, extraId string, location string, from time.Time, until time.Time) (int, error) {
+	var reserved int
+	res := conn(ctx, r.DB).Model(&models.RentExtra{}).Select("COALESCE(sum(quantity), 0)").
+		Where("extra_id = ? AND location = ? AND stocked AND reserved_from < ?", extraId, location, until).
+		Where("(reserved_until > ? OR released_at IS NULL)", from).
+		Scan(&reserved)
+	return reserved, res.Error
+}
+
+func (r ExtraRepositoryImpl) ReserveExtra(ctx context.Context, extra models.RentExtra) (models.RentExtra, error) {
+	res := conn(ctx, r.DB).Create(&extra)
+	return extra, res.Error
+}
+
+func (r ExtraRepositoryImpl) GetRentExtras(ctx context.Context, rentId int) ([]models.RentExtra, error) {
+	var extras []models.RentExtra
+	if res := conn(ctx, r.DB).Where("rent_id = ?", rentId).Order("id").Find(&extras); res.Error != nil {
+		return nil, res.Error
+	}
+	return extras, nil
+}
+
+func (r ExtraRepositoryImpl) ReleaseExtras(ctx context.Context, rentId int, releasedAt time.Time) error {
+	res := conn(ctx, r.DB).Model(&models.RentExtra{}).Where("rent_id = ? AND released_at IS NULL", rentId).
+		Updates(map[string]interface{}{
+			"released_at":    releasedAt,
+			"reserved_until": gorm.Expr("LEAST(reserved_until, ?)", releasedAt),
+		})
+	return res.Error
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+)
+
+type IdempotencyRepository interface {
+	GetKey(ctx context.Context, scope string, key string) (models.IdempotencyKey, error)
+	// CreateKey reserves the key, created is false when it already exists and has not expired
+	CreateKey(ctx context.Context, key models.IdempotencyKey) (created bool, err error)
+	// CompleteKey stores the response of the key, its status code, body, content type and headers
+	CompleteKey(ctx context.Context, response models.IdempotencyKey) error
+	DeleteKey(ctx context.Context, scope string, key string) error
+	// DeleteExpiredKeys deletes the keys expired before the time
+	DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"car-rental/internal/models"
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type IdempotencyRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewIdempotencyRepositoryImpl(db *gorm.DB) *IdempotencyRepositoryImpl {
+	return &IdempotencyRepositoryImpl{DB: db}
+}
+
+func (r IdempotencyRepositoryImpl) GetKey(
+	ctx context.Context, scope string, key string) (models.IdempotencyKey, error) {
+	var idempotencyKey models.IdempotencyKey
+	res := conn(ctx, r.DB).Where("scope = ? AND key = ?", scope, key).First(&idempotencyKey)
+	if res.Error != nil {
+		return idempotencyKey, res.Error
+	}
+	return idempotencyKey, nil
+}
+
+func (r IdempotencyRepositoryImpl) CreateKey(ctx context.Context, key models.IdempotencyKey) (bool, error) {
+	// an expired key is free again even before the cleanup deleted it
+	res := conn(ctx, r.DB).Where("scope = ? AND key = ? AND expires_at <= ?", key.Scope, key.Key, time.Now()).
+		Delete(&models.IdempotencyKey{})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	res = conn(ctx, r.DB).Clauses(clause.OnConflict{DoNothing: true}).Create(&key)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r IdempotencyRepositoryImpl) CompleteKey(ctx context.Context, response models.IdempotencyKey) error {
+	response.Completed = true
+	res := conn(ctx, r.DB).Model(&models.IdempotencyKey{}).
+		Where("scope = ? AND key = ?", response.Scope, response.Key).
+		Select("status_code", "response_body", "content_type", "headers", "completed").
+		Updates(&response)
+	return res.Error
+}
+
+func (r IdempotencyRepositoryImpl) DeleteKey(ctx context.Context, scope string, key string) error {
+	res := conn(ctx, r.DB).Where("scope = ? AND key = ?", scope, key).Delete(&models.IdempotencyKey{})
+	return res.Error
+}
+
+func (r IdempotencyRepositoryImpl) DeleteExpiredKeys(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.DB).Where("expires_at < ?", before).Delete(&models.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
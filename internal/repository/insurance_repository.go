@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+)
+
+type InsuranceRepository interface {
+	GetPackage(ctx context.Context, autoType string, code string) (models.InsurancePackage, error)
+	// FindPackages returns the packages of the auto type, the cheapest first
+	FindPackages(ctx context.Context, autoType string) ([]models.InsurancePackage, error)
+	// SetPackage adds the package of the auto type or replaces it, rents already bound keep their price
+	SetPackage(ctx context.Context, insurance models.InsurancePackage) error
+	DeletePackage(ctx context.Context, autoType string, code string) error
+}
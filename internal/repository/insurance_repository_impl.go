@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InsuranceRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewInsuranceRepositoryImpl(db *gorm.DB) *InsuranceRepositoryImpl {
+	return &InsuranceRepositoryImpl{DB: db}
+}
+
+func (r InsuranceRepositoryImpl) GetPackage(
+	ctx context.Context, autoType string, code string) (models.InsurancePackage, error) {
+	var insurance models.InsurancePackage
+	res := conn(ctx, r.DB).Where("auto_type = ? AND code = ?", autoType, code).First(&insurance)
+	return insurance, res.Error
+}
+
+func (r InsuranceRepositoryImpl) FindPackages(ctx context.Context, autoType string) ([]models.InsurancePackage, error) {
+	var packages []models.InsurancePackage
+	res := conn(ctx, r.DB).Where("auto_type = ?", autoType).Order("daily_price, rental_price, code").Find(&packages)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return packages, nil
+}
+
+func (r InsuranceRepositoryImpl) SetPackage(ctx context.Context, insurance models.InsurancePackage) error {
+	return conn(ctx, r.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "auto_type"}, {Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "daily_price", "rental_price", "excess"}),
+	}).Create(&insurance).Error
+}
+
+func (r InsuranceRepositoryImpl) DeletePackage(ctx context.Context, autoType string, code string) error {
+	res := conn(ctx, r.DB).Where("auto_type = ? AND code = ?", autoType, code).Delete(&models.InsurancePackage{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
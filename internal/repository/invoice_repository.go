@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+)
+
+// InvoiceQuery filters invoices and credit notes, zero values don't filter.
+type InvoiceQuery struct {
+	RentID   int
+	ClientID string
+	// CreditedInvoiceID finds the credit notes of an invoice
+	CreditedInvoiceID int64
+	Limit             int
+}
+
+type InvoiceRepository interface {
+	// NextNumber takes the next number of the series, the number is released if the transaction rolls back
+	NextNumber(ctx context.Context, series string) (int, error)
+	// CreateInvoice stores the invoice with its lines and taxes, invoices can't be changed afterwards
+	CreateInvoice(ctx context.Context, invoice models.Invoice) (models.Invoice, error)
+	GetInvoice(ctx context.Context, id int64) (models.Invoice, error)
+	// FindInvoices returns the invoices with their lines and taxes, newest first
+	FindInvoices(ctx context.Context, query InvoiceQuery) ([]models.Invoice, error)
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+)
+
+type InvoiceRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewInvoiceRepositoryImpl(db *gorm.DB) *InvoiceRepositoryImpl {
+	return &InvoiceRepositoryImpl{DB: db}
+}
+
+func (r InvoiceRepositoryImpl) NextNumber(ctx context.Context, series string) (int, error) {
+	var number int
+	// the row stays locked until the transaction ends, so numbers are taken one after another
+	res := conn(ctx, r.DB).Raw(`INSERT INTO invoice_sequence (series, last_number) VALUES (?, 1)
+		ON CONFLICT (series) DO UPDATE SET last_number = invoice_sequence.last_number + 1
+		RETURNING last_number`, series).Scan(&number)
+	return number, res.Error
+}
+
+func (r InvoiceRepositoryImpl) CreateInvoice(ctx context.Context, invoice models.Invoice) (models.Invoice, error) {
+	res := conn(ctx, r.DB).Create(&invoice)
+	return invoice, res.Error
+}
+
+func (r InvoiceRepositoryImpl) GetInvoice(ctx context.Context, id int64) (models.Invoice, error) {
+	var invoice models.Invoice
+	res := withDetails(conn(ctx, r.DB)).Where("id = ?", id).First(&invoice)
+	return invoice, res.Error
+}
+
+func (r InvoiceRepositoryImpl) FindInvoices(ctx context.Context, query InvoiceQuery) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	tx := withDetails(conn(ctx, r.DB)).Order("id DESC")
+	if query.RentID != 0 {
+		tx = tx.Where("rent_id = ?", query.RentID)
+	}
+	if query.ClientID != "" {
+		tx = tx.Where("client_id = ?", query.ClientID)
+	}
+	if query.CreditedInvoiceID != 0 {
+		tx = tx.Where("credited_invoice_id = ?", query.CreditedInvoiceID)
+	}
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+	if res := tx.Find(&invoices); res.Error != nil {
+		return nil, res.Error
+	}
+	return invoices, nil
+}
+
+func withDetails(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Lines", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") }).
+		Preload("Taxes", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") })
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+)
+
+// LoyaltyBalance sums the loyalty entries of a client.
+type LoyaltyBalance struct {
+	Balance int
+	// Earned are the points earned over all rentals, the tier is reached with them
+	Earned int
+	// Expirable are the earned points past their expiry, expired or not
+	Expirable int
+	// Spent are the points redeemed, expired and adjusted off the balance
+	Spent int
+}
+
+type LoyaltyRepository interface {
+	// LockClient serializes the changes of the points of the client until the transaction ends
+	LockClient(ctx context.Context, clientId string) error
+	AddEntry(ctx context.Context, entry models.LoyaltyEntry) (models.LoyaltyEntry, error)
+	// GetBalance sums the entries of the client, with the earned points expired at now
+	GetBalance(ctx context.Context, clientId string, now time.Time) (LoyaltyBalance, error)
+	// FindEntries returns the newest entries of the client first
+	FindEntries(ctx context.Context, clientId string, limit int) ([]models.LoyaltyEntry, error)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+)
+
+type LoyaltyRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewLoyaltyRepositoryImpl(db *gorm.DB) *LoyaltyRepositoryImpl {
+	return &LoyaltyRepositoryImpl{DB: db}
+}
+
+func (r LoyaltyRepositoryImpl) LockClient(ctx context.Context, clientId string) error {
+	return conn(ctx, r.DB).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "loyalty:"+clientId).Error
+}
+
+func (r LoyaltyRepositoryImpl) AddEntry(ctx context.Context, entry models.LoyaltyEntry) (models.LoyaltyEntry, error) {
+	res := conn(ctx, r.DB).Create(&entry)
+	return entry, res.Error
+}
+
+func (r LoyaltyRepositoryImpl) GetBalance(ctx context.Context, clientId string, now time.Time) (LoyaltyBalance, error) {
+	var balance LoyaltyBalance
+	res := conn(ctx, r.DB).Model(&models.LoyaltyEntry{}).
+		Select(`COALESCE(sum(points), 0) AS balance,
+			COALESCE(sum(points) FILTER (WHERE kind = ?), 0) AS earned,
+			COALESCE(sum(points) FILTER (WHERE kind = ? AND expires_at <= ?), 0) AS expirable,
+			COALESCE(-sum(points) FILTER (WHERE points < 0), 0) AS spent`,
+			models.LoyaltyKindEarn, models.LoyaltyKindEarn, now).
+		Where("client_id = ?", clientId).
+		Scan(&balance)
+	return balance, res.Error
+}
+
+func (r LoyaltyRepositoryImpl) FindEntries(ctx context.Context, clientId string, limit int) (
+	[]models.LoyaltyEntry, error) {
+	var entries []models.LoyaltyEntry
+	res := conn(ctx, r.DB).Where("client_id = ?", clientId).Order("id DESC").Limit(limit).Find(&entries)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return entries, nil
+}
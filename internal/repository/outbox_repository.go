@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+)
+
+type OutboxRepository interface {
+	// AddEvents stores the events, in the transaction of ctx when there is one
+	AddEvents(ctx context.Context, events []models.OutboxEvent) error
+	// LockPendingEvents returns the oldest unpublished events due at now, locked until the transaction
+	// of ctx ends. Events locked by another dispatcher are skipped.
+	LockPendingEvents(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error
+	// MarkFailed counts the failed attempt and postpones the next one
+	MarkFailed(ctx context.Context, id int64, reason string, nextAttemptAt time.Time) error
+	// DeletePublishedEvents deletes the events published before the time
+	DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewOutboxRepositoryImpl(db *gorm.DB) *OutboxRepositoryImpl {
+	return &OutboxRepositoryImpl{DB: db}
+}
+
+func (r OutboxRepositoryImpl) AddEvents(ctx context.Context, events []models.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return conn(ctx, r.DB).Create(&events).Error
+}
+
+func (r OutboxRepositoryImpl) LockPendingEvents(
+	ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	res := conn(ctx, r.DB).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("published_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id").
+		Limit(limit).
+		Find(&events)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return events, nil
+}
+
+func (r OutboxRepositoryImpl) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) error {
+	res := conn(ctx, r.DB).Model(&models.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"published_at": publishedAt,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   "",
+		})
+	return res.Error
+}
+
+func (r OutboxRepositoryImpl) MarkFailed(
+	ctx context.Context, id int64, reason string, nextAttemptAt time.Time) error {
+	res := conn(ctx, r.DB).Model(&models.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      reason,
+			"next_attempt_at": nextAttemptAt,
+		})
+	return res.Error
+}
+
+func (r OutboxRepositoryImpl) DeletePublishedEvents(ctx context.Context, before time.Time) (int64, error) {
+	res := conn(ctx, r.DB).Where("published_at < ?", before).Delete(&models.OutboxEvent{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+)
+
+type PaymentRepository interface {
+	CreatePayment(ctx context.Context, payment models.Payment) (models.Payment, error)
+	// FindPaymentsByRent returns the payments of the rent, the deposit first
+	FindPaymentsByRent(ctx context.Context, rentId int) ([]models.Payment, error)
+	// UpdatePayment stores the status and the captured amount
+	UpdatePayment(ctx context.Context, payment models.Payment) error
+}
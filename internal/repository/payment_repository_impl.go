@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+)
+
+type PaymentRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewPaymentRepositoryImpl(db *gorm.DB) *PaymentRepositoryImpl {
+	return &PaymentRepositoryImpl{DB: db}
+}
+
+func (r PaymentRepositoryImpl) CreatePayment(ctx context.Context, payment models.Payment) (models.Payment, error) {
+	res := conn(ctx, r.DB).Create(&payment)
+	return payment, res.Error
+}
+
+func (r PaymentRepositoryImpl) FindPaymentsByRent(ctx context.Context, rentId int) ([]models.Payment, error) {
+	var payments []models.Payment
+	if res := conn(ctx, r.DB).Where("rent_id = ?", rentId).Order("id").Find(&payments); res.Error != nil {
+		return nil, res.Error
+	}
+	return payments, nil
+}
+
+func (r PaymentRepositoryImpl) UpdatePayment(ctx context.Context, payment models.Payment) error {
+	res := conn(ctx, r.DB).Model(&models.Payment{}).
+		Where("id = ?", payment.ID).
+		Updates(map[string]interface{}{
+			"status":     payment.Status,
+			"captured":   payment.Captured,
+			"updated_at": time.Now(),
+		})
+	return res.Error
+}
@@ -1,10 +1,30 @@
 package repository
 
-import "car-rental/internal/models"
+import (
+	"context"
+
+	"car-rental/internal/models"
+)
+
+// RentQuery filters rents, zero values don't filter.
+type RentQuery struct {
+	Status   string
+	AutoID   string
+	ClientID string
+	Limit    int
+}
 
 type RentalRepository interface {
-	GetRentByAuto(autoId string) (models.AutoRent, error)
-	BindRent(autoId string, days int) error
-	ReleaseRent(autoId string) error
-	GetThresholdsByAutoType(autoType string) (models.RentThreshold, error)
+	// GetRentByAuto returns the active rent of the auto
+	GetRentByAuto(ctx context.Context, autoId string) (models.AutoRent, error)
+	GetRentById(ctx context.Context, rentId int) (models.AutoRent, error)
+	FindRents(ctx context.Context, query RentQuery) ([]models.AutoRent, error)
+	// CountActiveRentsByAutoType has every auto type, those without active rents at 0
+	CountActiveRentsByAutoType(ctx context.Context) (map[string]int, error)
+	// BindRent starts the rent of AutoID for ClientID of the days from now, keeping the promo code and demand
+	// it is priced with
+	BindRent(ctx context.Context, rent models.AutoRent, days int) (models.AutoRent, error)
+	// CloseRent stores the return details of the rent and marks it closed, closed is false when it wasn't active
+	CloseRent(ctx context.Context, rent models.AutoRent) (closed bool, err error)
+	GetThresholdsByAutoType(ctx context.Context, autoType string) (models.RentThreshold, error)
 }
@@ -2,22 +2,25 @@ package repository
 
 import (
 	"car-rental/internal/models"
+	"context"
 	"errors"
 	"gorm.io/gorm"
+	"log/slog"
 	"time"
 )
 
 type RentalRepositoryImpl struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Logger *slog.Logger
 }
 
-func NewRentalRepositoryImpl(db *gorm.DB) *RentalRepositoryImpl {
-	return &RentalRepositoryImpl{DB: db}
+func NewRentalRepositoryImpl(db *gorm.DB, logger *slog.Logger) *RentalRepositoryImpl {
+	return &RentalRepositoryImpl{DB: db, Logger: logger}
 }
 
-func (r RentalRepositoryImpl) GetRentByAuto(autoId string) (models.AutoRent, error) {
+func (r RentalRepositoryImpl) GetRentByAuto(ctx context.Context, autoId string) (models.AutoRent, error) {
 	var rent models.AutoRent
-	res := r.DB.Where("auto_id = ?", autoId).First(&rent)
+	res := conn(ctx, r.DB).Where("auto_id = ? AND status = ?", autoId, models.RentStatusActive).First(&rent)
 	if res.Error != nil {
 		return rent, res.Error
 	}
@@ -25,28 +28,97 @@ func (r RentalRepositoryImpl) GetRentByAuto(autoId string) (models.AutoRent, err
 	return rent, nil
 }
 
-func (r RentalRepositoryImpl) BindRent(autoId string, days int) error {
+func (r RentalRepositoryImpl) GetRentById(ctx context.Context, rentId int) (models.AutoRent, error) {
 	var rent models.AutoRent
-	rent.AutoID = autoId
+	res := conn(ctx, r.DB).Where("id = ?", rentId).First(&rent)
+	if res.Error != nil {
+		return rent, res.Error
+	}
+	return rent, nil
+}
+
+func (r RentalRepositoryImpl) FindRents(ctx context.Context, query RentQuery) ([]models.AutoRent, error) {
+	var rents []models.AutoRent
+	tx := conn(ctx, r.DB).Order("id DESC")
+	if query.Status != "" {
+		tx = tx.Where("status = ?", query.Status)
+	}
+	if query.AutoID != "" {
+		tx = tx.Where("auto_id = ?", query.AutoID)
+	}
+	if query.ClientID != "" {
+		tx = tx.Where("client_id = ?", query.ClientID)
+	}
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+	res := tx.Find(&rents)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return rents, nil
+}
+
+func (r RentalRepositoryImpl) CountActiveRentsByAutoType(ctx context.Context) (map[string]int, error) {
+	var rows []struct {
+		AutoType string
+		Active   int
+	}
+	res := conn(ctx, r.DB).Table("auto_type").
+		Select("auto_type.id AS auto_type, count(auto_rent.id) AS active").
+		Joins("LEFT JOIN auto ON auto.type = auto_type.id").
+		Joins("LEFT JOIN auto_rent ON auto_rent.auto_id = auto.id AND auto_rent.status = ?", models.RentStatusActive).
+		Group("auto_type.id").
+		Scan(&rows)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.AutoType] = row.Active
+	}
+	return counts, nil
+}
+
+func (r RentalRepositoryImpl) BindRent(ctx context.Context, rent models.AutoRent, days int) (models.AutoRent, error) {
 	rent.StartDate = time.Now()
 	rent.EndDate = time.Now().AddDate(0, 0, days)
-	res := r.DB.Create(&rent)
+	rent.Status = models.RentStatusActive
+	res := conn(ctx, r.DB).Create(&rent)
 	if res.Error != nil {
-		return errors.New("auto not found")
+		// a cancelled request isn't a missing auto
+		if err := ctx.Err(); err != nil {
+			return rent, err
+		}
+		// the foreign key to auto is the expected failure, anything else only shows in the log
+		r.Logger.WarnContext(ctx, "creating rent failed", slog.String("auto_id", rent.AutoID), slog.Any("error", res.Error))
+		return rent, errors.New("auto not found")
 	}
-	return nil
+	return rent, nil
 
 }
 
-func (r RentalRepositoryImpl) ReleaseRent(autoId string) error {
-	var rent models.AutoRent
-	res := r.DB.Where("auto_id = ?", autoId).Delete(&rent)
-	return res.Error
+func (r RentalRepositoryImpl) CloseRent(ctx context.Context, rent models.AutoRent) (bool, error) {
+	// the status is checked by the update, so a rent returned concurrently isn't closed twice
+	res := conn(ctx, r.DB).Model(&models.AutoRent{}).
+		Where("id = ? AND status = ?", rent.ID, models.RentStatusActive).
+		Updates(map[string]interface{}{
+			"status":       models.RentStatusClosed,
+			"returned_at":  rent.ReturnedAt,
+			"odometer":     rent.Odometer,
+			"notes":        rent.Notes,
+			"checkout":     rent.Checkout,
+			"checkout_tax": rent.CheckoutTax,
+			"discount":     rent.Discount,
+			"damage":       rent.Damage,
+		})
+	return res.RowsAffected == 1, res.Error
 }
 
-func (r RentalRepositoryImpl) GetThresholdsByAutoType(autoType string) (models.RentThreshold, error) {
+func (r RentalRepositoryImpl) GetThresholdsByAutoType(
+	ctx context.Context, autoType string) (models.RentThreshold, error) {
 	var thresholds models.RentThreshold
-	res := r.DB.Where("auto_type = ?", autoType).Find(&thresholds)
+	res := conn(ctx, r.DB).Where("auto_type = ?", autoType).Find(&thresholds)
 	if res.Error != nil {
 		return thresholds, res.Error
 	}
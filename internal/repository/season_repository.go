@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+)
+
+type SeasonRepository interface {
+	// GetSeasons returns the seasons pricing the auto type, those of every type included
+	GetSeasons(ctx context.Context, autoType string) ([]models.Season, error)
+	FindSeasons(ctx context.Context) ([]models.Season, error)
+	CreateSeason(ctx context.Context, season models.Season) (models.Season, error)
+	DeleteSeason(ctx context.Context, id int64) error
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+type SeasonRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewSeasonRepositoryImpl(db *gorm.DB) *SeasonRepositoryImpl {
+	return &SeasonRepositoryImpl{DB: db}
+}
+
+func (r SeasonRepositoryImpl) GetSeasons(ctx context.Context, autoType string) ([]models.Season, error) {
+	var seasons []models.Season
+	res := conn(ctx, r.DB).Where("auto_types = '{}' OR ? = ANY(auto_types)", autoType).
+		Order("start_date").Find(&seasons)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return seasons, nil
+}
+
+func (r SeasonRepositoryImpl) FindSeasons(ctx context.Context) ([]models.Season, error) {
+	var seasons []models.Season
+	if res := conn(ctx, r.DB).Order("start_date, id").Find(&seasons); res.Error != nil {
+		return nil, res.Error
+	}
+	return seasons, nil
+}
+
+func (r SeasonRepositoryImpl) CreateSeason(ctx context.Context, season models.Season) (models.Season, error) {
+	if season.AutoTypes == nil {
+		// a nil array is stored as NULL
+		season.AutoTypes = pq.StringArray{}
+	}
+	res := conn(ctx, r.DB).Create(&season)
+	return season, res.Error
+}
+
+func (r SeasonRepositoryImpl) DeleteSeason(ctx context.Context, id int64) error {
+	res := conn(ctx, r.DB).Delete(&models.Season{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
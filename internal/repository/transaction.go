@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Transactor runs a function in a database transaction. Repositories called with the context passed
+// to the function join the transaction, so the changes of several repositories commit or roll back together.
+type Transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactionKey struct{}
+
+type GormTransactor struct {
+	DB *gorm.DB
+}
+
+func NewGormTransactor(db *gorm.DB) *GormTransactor {
+	return &GormTransactor{DB: db}
+}
+
+// InTransaction commits when fn returns nil and rolls back otherwise. Called within a transaction,
+// fn joins the outer one.
+func (t GormTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(transactionKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return t.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, transactionKey{}, tx))
+	})
+}
+
+// conn is the transaction of ctx, or db outside of a transaction.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(transactionKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+)
+
+// DeliveryQuery filters the deliveries of a subscription, zero values don't filter.
+type DeliveryQuery struct {
+	SubscriptionID int
+	Status         string
+	Limit          int
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id int) (models.WebhookSubscription, error)
+	// FindSubscriptions returns the subscriptions of the owner, of every owner when empty
+	FindSubscriptions(ctx context.Context, owner string) ([]models.WebhookSubscription, error)
+	// DeleteSubscription deletes the subscription with its deliveries
+	DeleteSubscription(ctx context.Context, id int) error
+	// SubscriptionsForEvent returns the subscriptions to the event type
+	SubscriptionsForEvent(ctx context.Context, eventType string) ([]models.WebhookSubscription, error)
+	// AddDeliveries stores the deliveries, skipping events already queued for the subscription
+	AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	// ClaimDueDeliveries returns the pending deliveries due at now and postpones them to leaseUntil,
+	// so other deliverers skip them while they are sent
+	ClaimDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) (
+		[]models.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error)
+	// FindDeliveries returns the deliveries newest first
+	FindDeliveries(ctx context.Context, query DeliveryQuery) ([]models.WebhookDelivery, error)
+	// UpdateDelivery stores the status and the outcome of the last attempt
+	UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"car-rental/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewWebhookRepositoryImpl(db *gorm.DB) *WebhookRepositoryImpl {
+	return &WebhookRepositoryImpl{DB: db}
+}
+
+func (r WebhookRepositoryImpl) CreateSubscription(
+	ctx context.Context, subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+	res := conn(ctx, r.DB).Create(&subscription)
+	return subscription, res.Error
+}
+
+func (r WebhookRepositoryImpl) GetSubscription(ctx context.Context, id int) (models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	res := conn(ctx, r.DB).Where("id = ?", id).First(&subscription)
+	return subscription, res.Error
+}
+
+func (r WebhookRepositoryImpl) FindSubscriptions(
+	ctx context.Context, owner string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	tx := conn(ctx, r.DB).Order("id")
+	if owner != "" {
+		tx = tx.Where("owner = ?", owner)
+	}
+	if res := tx.Find(&subscriptions); res.Error != nil {
+		return nil, res.Error
+	}
+	return subscriptions, nil
+}
+
+func (r WebhookRepositoryImpl) DeleteSubscription(ctx context.Context, id int) error {
+	res := conn(ctx, r.DB).Where("id = ?", id).Delete(&models.WebhookSubscription{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r WebhookRepositoryImpl) SubscriptionsForEvent(
+	ctx context.Context, eventType string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	res := conn(ctx, r.DB).Where("? = ANY(event_types)", eventType).Order("id").Find(&subscriptions)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return subscriptions, nil
+}
+
+func (r WebhookRepositoryImpl) AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	res := conn(ctx, r.DB).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(&deliveries)
+	return res.Error
+}
+
+func (r WebhookRepositoryImpl) ClaimDueDeliveries(
+	ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := conn(ctx, r.DB).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, now).
+			Order("next_attempt_at, id").
+			Limit(limit).
+			Find(&deliveries)
+		if res.Error != nil || len(deliveries) == 0 {
+			return res.Error
+		}
+		ids := make([]int64, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", leaseUntil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r WebhookRepositoryImpl) GetDelivery(ctx context.Context, id int64) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	res := conn(ctx, r.DB).Where("id = ?", id).First(&delivery)
+	return delivery, res.Error
+}
+
+func (r WebhookRepositoryImpl) FindDeliveries(
+	ctx context.Context, query DeliveryQuery) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	tx := conn(ctx, r.DB).Order("id DESC")
+	if query.SubscriptionID != 0 {
+		tx = tx.Where("subscription_id = ?", query.SubscriptionID)
+	}
+	if query.Status != "" {
+		tx = tx.Where("status = ?", query.Status)
+	}
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+	if res := tx.Find(&deliveries); res.Error != nil {
+		return nil, res.Error
+	}
+	return deliveries, nil
+}
+
+func (r WebhookRepositoryImpl) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	res := conn(ctx, r.DB).Model(&models.WebhookDelivery{}).
+		Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"delivered_at":     delivery.DeliveredAt,
+		})
+	return res.Error
+}
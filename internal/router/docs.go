@@ -0,0 +1,309 @@
+package router
+
+import (
+	"path"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/controller"
+	"car-rental/internal/middleware"
+	"car-rental/internal/models"
+	"car-rental/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+// documentedGroup registers handlers on the gin group together with their
+// OpenAPI description, so the served spec can't miss a route.
+type documentedGroup struct {
+	group   *gin.RouterGroup
+	docs    *openapi.Spec
+	secured bool
+}
+
+func (g documentedGroup) Group(relativePath string, handlers ...gin.HandlerFunc) documentedGroup {
+	return documentedGroup{group: g.group.Group(relativePath, handlers...), docs: g.docs, secured: g.secured}
+}
+
+func (g documentedGroup) Handle(method, relativePath string, doc openapi.Route, handlers ...gin.HandlerFunc) {
+	g.group.Handle(method, relativePath, handlers...)
+	if g.secured {
+		doc.Secured = true
+		doc.Responses = openapi.MergeResponses(doc.Responses, securedResponses)
+	}
+	g.docs.Add(method, path.Join(g.group.BasePath(), relativePath), doc)
+}
+
+func newSpec() *openapi.Spec {
+	spec := openapi.NewSpec("car-rental", "2")
+	spec.AddSecurityScheme("apiKey", openapi.SecurityScheme{Type: "apiKey", Name: auth.APIKeyHeader, In: "header"})
+	spec.AddSecurityScheme("bearer", openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"})
+	return spec
+}
+
+var (
+	securedResponses = map[int]interface{}{
+		401: middleware.ErrorResponse{},
+		403: middleware.ErrorResponse{},
+	}
+	idempotentResponses = map[int]interface{}{
+		400: middleware.ErrorResponse{},
+		409: middleware.ErrorResponse{},
+		422: middleware.ErrorResponse{},
+	}
+	// v1 handlers report errors as a bare message string
+	messageResponse = ""
+)
+
+func idempotent(doc openapi.Route) openapi.Route {
+	doc.Headers = append(doc.Headers, middleware.IdempotencyKeyHeader)
+	doc.Responses = openapi.MergeResponses(doc.Responses, idempotentResponses)
+	return doc
+}
+
+var (
+	welcomeDoc = openapi.Route{
+		Summary:   "welcome page",
+		Responses: map[int]interface{}{200: messageResponse},
+	}
+	openAPIDoc = openapi.Route{
+		Summary:   "this OpenAPI document",
+		Responses: map[int]interface{}{200: map[string]interface{}{}},
+	}
+	metricsDoc = openapi.Route{
+		Summary:   "Prometheus metrics",
+		Responses: map[int]interface{}{200: openapi.Text("text/plain; version=0.0.4")},
+	}
+	livenessDoc = openapi.Route{
+		Summary:   "liveness probe, the process serves requests",
+		Responses: map[int]interface{}{200: controller.HealthResponse{}},
+	}
+	readinessDoc = openapi.Route{
+		Summary: "readiness probe, the database is reachable and migrated and the instance isn't shutting down",
+		Responses: map[int]interface{}{
+			200: controller.HealthResponse{},
+			503: controller.HealthResponse{},
+		},
+	}
+	getAvailableByTypeDoc = openapi.Route{
+		Summary: "available autos of the type",
+		Responses: map[int]interface{}{
+			200: []models.Auto{},
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	listAutosDoc = openapi.Route{
+		Summary: "auto catalogue, filtered, sorted and paginated by cursor",
+		Query:   controller.ListAutosQuery{},
+		Responses: map[int]interface{}{
+			200: controller.AutoListResponse{},
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	bindAutoDoc = idempotent(openapi.Route{
+		Summary:    "rent an auto",
+		Deprecated: true,
+		Request:    controller.CreateRentalInput{},
+		Responses: map[int]interface{}{
+			200: messageResponse,
+			400: openapi.OneOf(controller.ErrorResponse{}, messageResponse),
+			402: messageResponse,
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	})
+	releaseAutoDoc = idempotent(openapi.Route{
+		Summary:    "return an auto today and get the checkout",
+		Deprecated: true,
+		Responses: map[int]interface{}{
+			200: controller.CheckoutResponse{},
+			402: messageResponse,
+			404: messageResponse,
+			409: messageResponse,
+			500: messageResponse,
+		},
+	})
+	getCurrentCommissionDoc = openapi.Route{
+		Summary: "commission and insurance of the active rent of an auto, before and after tax",
+		Responses: map[int]interface{}{
+			200: controller.CommissionResponse{},
+			404: messageResponse,
+			500: messageResponse,
+		},
+	}
+	createRentalDoc = idempotent(openapi.Route{
+		Summary: "rent an auto",
+		Request: controller.CreateRentalInput{},
+		Responses: map[int]interface{}{
+			201: controller.RentalResponse{},
+			400: openapi.OneOf(controller.ErrorResponse{}, messageResponse),
+			402: messageResponse,
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	})
+	getRentalDoc = openapi.Route{
+		Summary: "a rental, active or closed",
+		Responses: map[int]interface{}{
+			200: controller.RentalResponse{},
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			500: messageResponse,
+		},
+	}
+	returnRentalDoc = idempotent(openapi.Route{
+		Summary: "return the auto of a rental and close it",
+		Request: controller.ReturnRentalInput{},
+		Responses: map[int]interface{}{
+			200: controller.RentalResponse{},
+			400: controller.ErrorResponse{},
+			402: messageResponse,
+			404: messageResponse,
+			409: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	})
+	createWebhookDoc = idempotent(openapi.Route{
+		Summary: "subscribe a URL to event types, the response holds the signing secret",
+		Request: controller.CreateWebhookInput{},
+		Responses: map[int]interface{}{
+			201: controller.WebhookResponse{},
+			400: controller.ErrorResponse{},
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	})
+	listWebhooksDoc = openapi.Route{
+		Summary: "webhook subscriptions",
+		Responses: map[int]interface{}{
+			200: controller.WebhookListResponse{},
+			500: messageResponse,
+		},
+	}
+	getWebhookDoc = openapi.Route{
+		Summary: "a webhook subscription",
+		Responses: map[int]interface{}{
+			200: controller.WebhookResponse{},
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	deleteWebhookDoc = idempotent(openapi.Route{
+		Summary: "unsubscribe, pending deliveries are dropped",
+		Responses: map[int]interface{}{
+			200: controller.WebhookResponse{},
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	})
+	listDeliveriesDoc = openapi.Route{
+		Summary: "delivery log of a webhook subscription, newest first",
+		Query:   controller.ListDeliveriesQuery{},
+		Responses: map[int]interface{}{
+			200: controller.DeliveryListResponse{},
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	retryDeliveryDoc = idempotent(openapi.Route{
+		Summary: "queue a dead delivery again",
+		Responses: map[int]interface{}{
+			200: controller.DeliveryResponse{},
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			409: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	})
+	listInvoicesDoc = openapi.Route{
+		Summary: "invoices and credit notes, newest first",
+		Query:   controller.ListInvoicesQuery{},
+		Responses: map[int]interface{}{
+			200: controller.InvoiceListResponse{},
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	getInvoiceDoc = openapi.Route{
+		Summary: "an invoice or credit note",
+		Responses: map[int]interface{}{
+			200: controller.InvoiceResponse{},
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	getInvoiceHTMLDoc = openapi.Route{
+		Summary: "an invoice or credit note as an HTML page",
+		Responses: map[int]interface{}{
+			200: openapi.Text("text/html"),
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	getInvoicePDFDoc = openapi.Route{
+		Summary: "an invoice or credit note as a PDF document",
+		Responses: map[int]interface{}{
+			200: openapi.Text("application/pdf"),
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	createCreditNoteDoc = idempotent(openapi.Route{
+		Summary: "correct an invoice, reversing its lines",
+		Request: controller.CreditNoteInput{},
+		Responses: map[int]interface{}{
+			201: controller.InvoiceResponse{},
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			409: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	})
+	listExtrasDoc = openapi.Route{
+		Summary: "the extras rented with the autos, with what is left of them at a location",
+		Query:   controller.ListExtrasQuery{},
+		Responses: map[int]interface{}{
+			200: controller.ExtraListResponse{},
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	listInsuranceDoc = openapi.Route{
+		Summary: "the insurance packages of an auto type, the cheapest first",
+		Query:   controller.ListInsuranceQuery{},
+		Responses: map[int]interface{}{
+			200: controller.InsuranceListResponse{},
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+	getLoyaltyDoc = openapi.Route{
+		Summary: "the loyalty points of a client, with the newest entries",
+		Query:   controller.LoyaltyQuery{},
+		Responses: map[int]interface{}{
+			200: controller.LoyaltyResponse{},
+			400: controller.ErrorResponse{},
+			404: messageResponse,
+			422: controller.ValidationErrorResponse{},
+			500: messageResponse,
+		},
+	}
+)
@@ -1,30 +1,147 @@
 package router
 
 import (
+	"log/slog"
 	"net/http"
+	"time"
 
+	"car-rental/internal/auth"
 	"car-rental/internal/controller"
+	"car-rental/internal/metrics"
+	"car-rental/internal/middleware"
+	"car-rental/internal/repository"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func NewRouter(controller controller.RentalController) *gin.Engine {
-	service := gin.Default()
+// v1 bind and release are replaced by the v2 rentals resource and removed at v1Sunset.
+var (
+	v1Deprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	v1Sunset     = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// Features turns optional parts of the API on and off.
+type Features struct {
+	// LegacyRentals serves the deprecated v1 bind and release endpoints
+	LegacyRentals bool
+	OpenAPI       bool
+	Metrics       bool
+	// IdempotencyTTL is how long the responses of idempotent requests are replayed
+	IdempotencyTTL time.Duration
+}
+
+// Controllers handle the routes, one per resource.
+type Controllers struct {
+	Rental  controller.RentalController
+	Health  controller.HealthController
+	Webhook controller.WebhookController
+	Invoice controller.InvoiceController
+	Loyalty controller.LoyaltyController
+}
+
+func NewRouter(
+	controllers Controllers,
+	authenticator *auth.Authenticator,
+	idempotencyRepository repository.IdempotencyRepository,
+	appMetrics *metrics.Metrics,
+	logger *slog.Logger,
+	tracer trace.Tracer,
+	features Features) *gin.Engine {
+	controller, healthController, webhookController := controllers.Rental, controllers.Health, controllers.Webhook
+	invoiceController, loyaltyController := controllers.Invoice, controllers.Loyalty
+	service := gin.New()
+	service.Use(
+		middleware.RequestID(),
+		middleware.Tracing(tracer),
+		middleware.AccessLog(logger),
+		// metrics wrap the recovery so recovered panics are counted with their 500
+		middleware.Metrics(appMetrics),
+		middleware.Recovery(logger))
+	docs := newSpec()
+	public := documentedGroup{group: &service.RouterGroup, docs: docs}
 
-	service.GET("", func(context *gin.Context) {
+	public.Handle(http.MethodGet, "", welcomeDoc, func(context *gin.Context) {
 		context.JSON(http.StatusOK, "welcome home")
 	})
+	if features.OpenAPI {
+		public.Handle(http.MethodGet, "/api/openapi.json", openAPIDoc, func(context *gin.Context) {
+			context.JSON(http.StatusOK, docs.Document())
+		})
+	}
+
+	if features.Metrics {
+		public.Handle(http.MethodGet, "/metrics", metricsDoc, gin.WrapH(appMetrics.Handler()))
+	}
+	// probes for orchestrators, outside /api and without authentication
+	public.Handle(http.MethodGet, "/healthz", livenessDoc, healthController.Liveness)
+	public.Handle(http.MethodGet, "/readyz", readinessDoc, healthController.Readiness)
 
 	service.NoRoute(func(c *gin.Context) {
-		c.JSON(404, gin.H{"code": "PAGE_NOT_FOUND", "message": "Page not found"})
+		c.JSON(404, middleware.ErrorResponse{Code: "PAGE_NOT_FOUND", Message: "Page not found"})
 	})
 
-	router := service.Group("/api/v1")
+	// customers only reach their own rents, invoices and points, checked in the controllers; agents and
+	// admins act for any client
+	anyRole := middleware.RequireRoles(auth.RoleCustomer, auth.RoleAgent, auth.RoleAdmin)
+	staff := middleware.RequireRoles(auth.RoleAgent, auth.RoleAdmin)
+	idempotent := middleware.Idempotency(idempotencyRepository, features.IdempotencyTTL, logger)
+
+	secured := documentedGroup{group: &service.RouterGroup, docs: docs, secured: true}
+	router := secured.Group("/api/v1", middleware.Authenticate(authenticator))
+	router.Handle(http.MethodGet, "/autos", listAutosDoc, anyRole, controller.ListAutos)
 	autoRouter := router.Group("/auto")
 	{
-		autoRouter.GET("/type/:type", controller.GetAvailableByType)
-		autoRouter.POST("/bind", controller.BindAuto)
-		autoRouter.GET("/release/:auto_id", controller.ReleaseAuto)
-		autoRouter.GET("/commission/:auto_id", controller.GetCurrentCommission)
+		autoRouter.Handle(http.MethodGet, "/type/:type", getAvailableByTypeDoc,
+			anyRole, controller.GetAvailableByType)
+		if features.LegacyRentals {
+			autoRouter.Handle(http.MethodPost, "/bind", bindAutoDoc,
+				middleware.Deprecated(v1Deprecated, v1Sunset, "/api/v2/rentals"),
+				anyRole, idempotent, controller.BindAuto)
+			// customers may only release and inspect their own rents, checked in the controller
+			autoRouter.Handle(http.MethodGet, "/release/:auto_id", releaseAutoDoc,
+				middleware.Deprecated(v1Deprecated, v1Sunset, "/api/v2/rentals/{id}/return"),
+				anyRole, idempotent, controller.ReleaseAuto)
+		}
+		autoRouter.Handle(http.MethodGet, "/commission/:auto_id", getCurrentCommissionDoc,
+			anyRole, controller.GetCurrentCommission)
+	}
+
+	v2 := secured.Group("/api/v2", middleware.Authenticate(authenticator))
+	rentalsRouter := v2.Group("/rentals")
+	{
+		rentalsRouter.Handle(http.MethodPost, "", createRentalDoc, anyRole, idempotent, controller.CreateRental)
+		rentalsRouter.Handle(http.MethodGet, "/:id", getRentalDoc, anyRole, controller.GetRental)
+		rentalsRouter.Handle(http.MethodPost, "/:id/return", returnRentalDoc,
+			anyRole, idempotent, controller.ReturnRental)
+	}
+
+	v2.Handle(http.MethodGet, "/extras", listExtrasDoc, anyRole, controller.ListExtras)
+	v2.Handle(http.MethodGet, "/insurance", listInsuranceDoc, anyRole, controller.ListInsurance)
+
+	invoicesRouter := v2.Group("/invoices")
+	{
+		// customers only see their own invoices, checked in the controller
+		invoicesRouter.Handle(http.MethodGet, "", listInvoicesDoc, anyRole, invoiceController.ListInvoices)
+		invoicesRouter.Handle(http.MethodGet, "/:id", getInvoiceDoc, anyRole, invoiceController.GetInvoice)
+		invoicesRouter.Handle(http.MethodGet, "/:id/html", getInvoiceHTMLDoc, anyRole, invoiceController.GetInvoiceHTML)
+		invoicesRouter.Handle(http.MethodGet, "/:id/pdf", getInvoicePDFDoc, anyRole, invoiceController.GetInvoicePDF)
+		invoicesRouter.Handle(http.MethodPost, "/:id/credit-notes", createCreditNoteDoc,
+			staff, idempotent, invoiceController.CreateCreditNote)
+	}
+
+	// customers only see their own points, checked in the controller
+	v2.Handle(http.MethodGet, "/clients/:client_id/loyalty", getLoyaltyDoc, anyRole, loyaltyController.GetAccount)
+
+	// partners subscribe to the domain events, agents see their own webhooks and admins every one
+	webhooksRouter := router.Group("/webhooks", staff)
+	{
+		webhooksRouter.Handle(http.MethodPost, "", createWebhookDoc, idempotent, webhookController.CreateWebhook)
+		webhooksRouter.Handle(http.MethodGet, "", listWebhooksDoc, webhookController.ListWebhooks)
+		webhooksRouter.Handle(http.MethodGet, "/:id", getWebhookDoc, webhookController.GetWebhook)
+		webhooksRouter.Handle(http.MethodDelete, "/:id", deleteWebhookDoc, idempotent, webhookController.DeleteWebhook)
+		webhooksRouter.Handle(http.MethodGet, "/:id/deliveries", listDeliveriesDoc, webhookController.ListDeliveries)
+		webhooksRouter.Handle(http.MethodPost, "/:id/deliveries/:delivery_id/retry", retryDeliveryDoc, idempotent,
+			webhookController.RetryDelivery)
 	}
 	return service
 }
@@ -0,0 +1,973 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"car-rental/internal/auth"
+	"car-rental/internal/controller"
+	"car-rental/internal/health"
+	"car-rental/internal/logging"
+	"car-rental/internal/loyalty"
+	"car-rental/internal/metrics"
+	"car-rental/internal/middleware"
+	"car-rental/internal/models"
+	"car-rental/internal/openapi"
+	"car-rental/internal/repository"
+	"car-rental/internal/service"
+	"car-rental/internal/tax"
+	"car-rental/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// fakeRentalService keeps autos and rents in memory, enough to drive every handler.
+type fakeRentalService struct {
+	autos map[string]models.Auto
+	rents []models.AutoRent
+	// err fails GetRent and GetAutoTypes, standing in for a broken database
+	err error
+}
+
+func (f *fakeRentalService) GetAutoTypes(ctx context.Context) ([]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []string{"special", "standard"}, nil
+}
+
+func (f *fakeRentalService) GetAvailableAutoByType(ctx context.Context, autoType string) ([]models.Auto, error) {
+	var autos []models.Auto
+	for _, auto := range f.autos {
+		if auto.Type == autoType && auto.Availability {
+			autos = append(autos, auto)
+		}
+	}
+	if len(autos) == 0 {
+		return nil, errors.New(service.NotFoundError)
+	}
+	return autos, nil
+}
+
+func (f *fakeRentalService) ListAutos(ctx context.Context, query repository.AutoQuery) (
+	[]repository.AutoListing, *repository.AutoCursor, error) {
+	autos := []repository.AutoListing{}
+	for _, auto := range f.autos {
+		if query.Type != "" && auto.Type != query.Type ||
+			query.After != nil && auto.ID <= query.After.ID {
+			continue
+		}
+		autos = append(autos, repository.AutoListing{Auto: auto, DailyPrice: 50})
+	}
+	sort.Slice(autos, func(i, j int) bool { return autos[i].ID < autos[j].ID })
+	if query.Limit > 0 && len(autos) > query.Limit {
+		autos = autos[:query.Limit]
+		return autos, &repository.AutoCursor{ID: autos[query.Limit-1].ID, Price: 50}, nil
+	}
+	return autos, nil, nil
+}
+
+func (f *fakeRentalService) GetRentByAuto(ctx context.Context, autoId string) (models.AutoRent, error) {
+	for _, rent := range f.rents {
+		if rent.AutoID == autoId && rent.Status == models.RentStatusActive {
+			return rent, nil
+		}
+	}
+	return models.AutoRent{}, gorm.ErrRecordNotFound
+}
+
+func (f *fakeRentalService) GetRent(ctx context.Context, rentId int) (models.AutoRent, error) {
+	if f.err != nil {
+		return models.AutoRent{}, f.err
+	}
+	if rentId < 1 || rentId > len(f.rents) {
+		return models.AutoRent{}, gorm.ErrRecordNotFound
+	}
+	return f.rents[rentId-1], nil
+}
+
+func (f *fakeRentalService) BindAuto(
+	ctx context.Context, autoId string, days int, clientId string, options service.RentalOptions) (
+	models.AutoRent, error) {
+	auto, ok := f.autos[autoId]
+	if !ok {
+		return models.AutoRent{}, gorm.ErrRecordNotFound
+	}
+	if options.PromoCode != "" && options.PromoCode != "SUMMER" {
+		return models.AutoRent{}, service.PromoCodeError{Reason: "has expired"}
+	}
+	if options.RedeemPoints > 500 {
+		return models.AutoRent{}, service.LoyaltyError{Reason: "exceed the balance of 500"}
+	}
+	for extraId, quantity := range options.Extras {
+		if extraId != "child-seat" || quantity > 2 {
+			return models.AutoRent{}, service.ExtraError{ExtraID: extraId, Reason: "has 2 left at Berlin"}
+		}
+	}
+	if options.Insurance != "" && options.Insurance != "basic" {
+		return models.AutoRent{}, service.InsuranceError{Reason: options.Insurance + " is unknown for auto type " + auto.Type}
+	}
+	if clientId == "declined" {
+		return models.AutoRent{}, service.PaymentError{Op: "authorization", Err: errors.New("payment declined")}
+	}
+	if !auto.Availability {
+		return models.AutoRent{}, errors.New(service.AlreadyRentedError)
+	}
+	if days > 90 {
+		return models.AutoRent{}, service.ThresholdError{Min: 1, Max: 90}
+	}
+	auto.Availability = false
+	f.autos[autoId] = auto
+	rent := models.AutoRent{
+		ID:             len(f.rents) + 1,
+		AutoID:         autoId,
+		ClientID:       clientId,
+		PromoCode:      options.PromoCode,
+		RedeemedPoints: options.RedeemPoints,
+		Insurance:      options.Insurance,
+		StartDate:      time.Now(),
+		EndDate:        time.Now().AddDate(0, 0, days),
+		Status:         models.RentStatusActive,
+	}
+	f.rents = append(f.rents, rent)
+	return rent, nil
+}
+
+func (f *fakeRentalService) ReturnRent(ctx context.Context, rentId int, rentalReturn service.RentalReturn) (models.AutoRent, error) {
+	rent, err := f.GetRent(ctx, rentId)
+	if err != nil {
+		return rent, err
+	}
+	if rent.Status == models.RentStatusClosed {
+		return rent, errors.New(service.AlreadyClosedError)
+	}
+	if rentalReturn.ReturnDate.IsZero() {
+		rentalReturn.ReturnDate = time.Now()
+	}
+	if rentalReturn.ReturnDate.Before(rent.StartDate.Truncate(24 * time.Hour)) {
+		return rent, service.ReturnDateError{Reason: "is before the rent started"}
+	}
+	rent.Status = models.RentStatusClosed
+	rent.ReturnedAt = &rentalReturn.ReturnDate
+	rent.Odometer = rentalReturn.Odometer
+	rent.Notes = rentalReturn.Notes
+	rent.Damage = rentalReturn.Damage
+	rent.Checkout = 100
+	rent.CheckoutTax = 19
+	f.rents[rentId-1] = rent
+	auto := f.autos[rent.AutoID]
+	auto.Availability = true
+	f.autos[rent.AutoID] = auto
+	return rent, nil
+}
+
+func (f *fakeRentalService) ReleaseAuto(ctx context.Context, autoId string, releaseDate time.Time) (int, error) {
+	rent, err := f.GetRentByAuto(ctx, autoId)
+	if err != nil {
+		return 0, err
+	}
+	rent, err = f.ReturnRent(ctx, rent.ID, service.RentalReturn{ReturnDate: releaseDate})
+	return rent.Checkout, err
+}
+
+func (f *fakeRentalService) GetCurrentCommission(ctx context.Context, autoId string, _ time.Time) (
+	tax.Totals, tax.Totals, error) {
+	if _, err := f.GetRentByAuto(ctx, autoId); err != nil {
+		return tax.Totals{}, tax.Totals{}, err
+	}
+	return tax.Totals{Net: 100, Tax: 19, Gross: 119}, tax.Totals{Net: 10, Gross: 10}, nil
+}
+
+func (f *fakeRentalService) Quote(
+	ctx context.Context, autoId string, days int, _ time.Time, _ time.Time, _ service.RentalOptions) (int, int, error) {
+	if _, ok := f.autos[autoId]; !ok {
+		return 0, 0, errors.New(service.NotFoundError)
+	}
+	return 100 * days, 10, nil
+}
+
+func (f *fakeRentalService) ListInsurance(ctx context.Context, autoType string) ([]models.InsurancePackage, error) {
+	return []models.InsurancePackage{
+		{AutoType: autoType, Code: "basic", Name: "Basic", DailyPrice: 15, Excess: 1000},
+		{AutoType: autoType, Code: "zero-excess", Name: "Zero excess", DailyPrice: 40},
+	}, nil
+}
+
+func (f *fakeRentalService) ListExtras(ctx context.Context, location string) ([]service.ExtraAvailability, error) {
+	extras := []service.ExtraAvailability{
+		{Extra: models.Extra{ID: "additional-driver", Name: "Additional driver", Pricing: models.ExtraPricingRental,
+			Price: 30}},
+		{Extra: models.Extra{ID: "child-seat", Name: "Child seat", Pricing: models.ExtraPricingDay, Price: 5,
+			Stocked: true}},
+	}
+	if location != "" {
+		available := 2
+		extras[1].Available = &available
+	}
+	return extras, nil
+}
+
+func (f *fakeRentalService) RetireAuto(ctx context.Context, autoId string) error {
+	if _, ok := f.autos[autoId]; !ok {
+		return errors.New(service.NotFoundError)
+	}
+	delete(f.autos, autoId)
+	return nil
+}
+
+func (f *fakeRentalService) SetCommission(ctx context.Context, commission models.Commission) error {
+	return nil
+}
+
+// fakeWebhookService keeps subscriptions in memory, each with one dead and one delivered delivery.
+type fakeWebhookService struct {
+	subscriptions []models.WebhookSubscription
+	deliveries    []models.WebhookDelivery
+}
+
+func (f *fakeWebhookService) CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (
+	models.WebhookSubscription, error) {
+	subscription.ID = len(f.subscriptions) + 1
+	subscription.Secret = "whsec_test"
+	subscription.CreatedAt = time.Now()
+	f.subscriptions = append(f.subscriptions, subscription)
+	for _, status := range []string{models.DeliveryStatusDead, models.DeliveryStatusDelivered} {
+		f.deliveries = append(f.deliveries, models.WebhookDelivery{
+			ID:             int64(len(f.deliveries) + 1),
+			SubscriptionID: subscription.ID,
+			EventID:        1,
+			EventType:      subscription.EventTypes[0],
+			Status:         status,
+			Attempts:       1,
+			CreatedAt:      time.Now(),
+		})
+	}
+	return subscription, nil
+}
+
+func (f *fakeWebhookService) ListSubscriptions(ctx context.Context, owner string) (
+	[]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	for _, subscription := range f.subscriptions {
+		if owner == "" || subscription.Owner == owner {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (f *fakeWebhookService) GetSubscription(ctx context.Context, owner string, id int) (
+	models.WebhookSubscription, error) {
+	for _, subscription := range f.subscriptions {
+		if subscription.ID == id && (owner == "" || subscription.Owner == owner) {
+			return subscription, nil
+		}
+	}
+	return models.WebhookSubscription{}, gorm.ErrRecordNotFound
+}
+
+func (f *fakeWebhookService) DeleteSubscription(ctx context.Context, owner string, id int) (
+	models.WebhookSubscription, error) {
+	subscription, err := f.GetSubscription(ctx, owner, id)
+	if err != nil {
+		return subscription, err
+	}
+	f.subscriptions[id-1].Owner = "deleted"
+	return subscription, nil
+}
+
+func (f *fakeWebhookService) ListDeliveries(ctx context.Context, owner string, query repository.DeliveryQuery) (
+	[]models.WebhookDelivery, error) {
+	if _, err := f.GetSubscription(ctx, owner, query.SubscriptionID); err != nil {
+		return nil, err
+	}
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range f.deliveries {
+		if delivery.SubscriptionID == query.SubscriptionID && (query.Status == "" || delivery.Status == query.Status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (f *fakeWebhookService) RedeliverDelivery(
+	ctx context.Context, owner string, subscriptionId int, deliveryId int64) (models.WebhookDelivery, error) {
+	if _, err := f.GetSubscription(ctx, owner, subscriptionId); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	for i, delivery := range f.deliveries {
+		if delivery.ID != deliveryId || delivery.SubscriptionID != subscriptionId {
+			continue
+		}
+		if delivery.Status != models.DeliveryStatusDead {
+			return models.WebhookDelivery{}, errors.New(service.NotDeadError)
+		}
+		f.deliveries[i].Status = models.DeliveryStatusPending
+		f.deliveries[i].Attempts = 0
+		f.deliveries[i].NextAttemptAt = time.Now()
+		return f.deliveries[i], nil
+	}
+	return models.WebhookDelivery{}, gorm.ErrRecordNotFound
+}
+
+// fakeInvoiceService has an invoice of client-1 with two lines, crediting them all once.
+type fakeInvoiceService struct {
+	invoices []models.Invoice
+}
+
+func newFakeInvoiceService() *fakeInvoiceService {
+	return &fakeInvoiceService{invoices: []models.Invoice{{
+		ID: 1, Number: "INV-2023-000001", Kind: models.InvoiceKindInvoice, RentID: 1, AutoID: "MINI",
+		AutoType: "standard", ClientID: "client-1", Currency: "EUR", IssuedAt: time.Now(),
+		Lines: []models.InvoiceLine{
+			{Position: 1, CommissionType: "daily", Description: "Rent of MINI, 3 days", Quantity: 3, UnitPrice: 100,
+				Amount: 300, TaxRate: 19},
+			{Position: 2, CommissionType: "agreement", Description: "Agreement fee", Quantity: 1, UnitPrice: 50,
+				Amount: 50, TaxRate: 19},
+		},
+		Taxes: []models.InvoiceTax{{Name: "VAT", Rate: 19, Base: 350, Amount: 67}},
+		Net:   350, Tax: 67, Gross: 417,
+	}}}
+}
+
+func (f *fakeInvoiceService) IssueInvoice(
+	ctx context.Context, rent models.AutoRent, autoType string, lines []models.InvoiceLine) (models.Invoice, error) {
+	return models.Invoice{}, errors.New("not issued by the router")
+}
+
+func (f *fakeInvoiceService) GetInvoice(ctx context.Context, id int64) (models.Invoice, error) {
+	if id < 1 || int(id) > len(f.invoices) {
+		return models.Invoice{}, gorm.ErrRecordNotFound
+	}
+	return f.invoices[id-1], nil
+}
+
+func (f *fakeInvoiceService) ListInvoices(ctx context.Context, query repository.InvoiceQuery) (
+	[]models.Invoice, error) {
+	var invoices []models.Invoice
+	for _, invoice := range f.invoices {
+		if query.ClientID == "" || invoice.ClientID == query.ClientID {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (f *fakeInvoiceService) IssueCreditNote(
+	ctx context.Context, invoiceId int64, positions []int, reason string) (models.Invoice, error) {
+	invoice, err := f.GetInvoice(ctx, invoiceId)
+	if err != nil {
+		return invoice, err
+	}
+	for _, position := range positions {
+		if position > len(invoice.Lines) {
+			return models.Invoice{}, errors.New(service.NoSuchLineError)
+		}
+	}
+	if invoice.Kind == models.InvoiceKindCreditNote || len(f.invoices) > 1 {
+		return models.Invoice{}, errors.New(service.AlreadyCreditedError)
+	}
+	creditNote := invoice
+	creditNote.ID = 2
+	creditNote.Number = "CN-2023-000001"
+	creditNote.Kind = models.InvoiceKindCreditNote
+	creditNote.CreditedInvoiceID = &invoice.ID
+	creditNote.CreditedInvoiceNumber = invoice.Number
+	creditNote.Reason = reason
+	creditNote.Net, creditNote.Tax, creditNote.Gross = -invoice.Net, -invoice.Tax, -invoice.Gross
+	f.invoices = append(f.invoices, creditNote)
+	return creditNote, nil
+}
+
+// fakeLoyaltyService has a gold account of client-1 with one entry, every other client has no points.
+type fakeLoyaltyService struct{}
+
+func (fakeLoyaltyService) Program() loyalty.Program {
+	return loyalty.Program{EarnPercent: 10, PointValue: 1}
+}
+
+func (fakeLoyaltyService) Account(ctx context.Context, clientId string, limit int) (service.LoyaltyAccount, error) {
+	if clientId != "client-1" {
+		return service.LoyaltyAccount{ClientID: clientId}, nil
+	}
+	rentId := 1
+	return service.LoyaltyAccount{ClientID: clientId, Balance: 500, Earned: 5000,
+		Tier: loyalty.Tier{Name: "gold", MinPoints: 5000, Waived: []string{"agreement"}},
+		Entries: []models.LoyaltyEntry{{ID: 1, ClientID: clientId, Kind: models.LoyaltyKindEarn, Points: 35,
+			RentID: &rentId, CreatedAt: time.Now()}}}, nil
+}
+
+func (fakeLoyaltyService) Tier(ctx context.Context, clientId string) (loyalty.Tier, error) {
+	return loyalty.Tier{}, nil
+}
+
+func (fakeLoyaltyService) Redeem(ctx context.Context, rent models.AutoRent) error {
+	return nil
+}
+
+func (fakeLoyaltyService) Earn(ctx context.Context, rent models.AutoRent, unused int) error {
+	return nil
+}
+
+func (fakeLoyaltyService) Adjust(
+	ctx context.Context, clientId string, points int, note string) (models.LoyaltyEntry, error) {
+	return models.LoyaltyEntry{}, errors.New("not adjusted by the router")
+}
+
+func TestMain(m *testing.M) {
+	if err := controller.RegisterValidations(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestRouter(t *testing.T) *gin.Engine {
+	checker := health.NewChecker()
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	return newTestRouterWithChecker(t, checker)
+}
+
+func newTestRouterWithChecker(t *testing.T, checker *health.Checker) *gin.Engine {
+	return newTestRouterWithService(t, checker, newFakeRentalService(), logging.Discard(), tracing.NoopTracer())
+}
+
+func newFakeRentalService() *fakeRentalService {
+	return &fakeRentalService{autos: map[string]models.Auto{
+		"MINI":  {ID: "MINI", Type: "standard", Availability: true},
+		"DEERE": {ID: "DEERE", Type: "special", Availability: true},
+	}}
+}
+
+func newTestRouterWithService(t *testing.T,
+	checker *health.Checker, svc *fakeRentalService, logger *slog.Logger, tracer trace.Tracer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	authenticator, err := auth.NewAuthenticator(auth.Config{APIKeys: []auth.APIKey{
+		{Key: "agent", Principal: auth.Principal{Subject: "agent", Role: auth.RoleAgent}},
+		{Key: "customer", Principal: auth.Principal{Subject: "client-1", Role: auth.RoleCustomer}},
+		{Key: "partner", Principal: auth.Principal{Subject: "partner", Role: auth.RoleAgent}},
+		{Key: "admin", Principal: auth.Principal{Subject: "admin", Role: auth.RoleAdmin}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewRouter(Controllers{
+		Rental:  *controller.NewRentalController(svc, logger),
+		Health:  *controller.NewHealthController(checker),
+		Webhook: *controller.NewWebhookController(&fakeWebhookService{}, logger),
+		Invoice: *controller.NewInvoiceController(newFakeInvoiceService(), logger),
+		Loyalty: *controller.NewLoyaltyController(fakeLoyaltyService{}, logger),
+	}, authenticator, &noIdempotencyRepository{}, metrics.New(nil), logger, tracer,
+		Features{LegacyRentals: true, OpenAPI: true, Metrics: true})
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	engine := newTestRouter(t)
+	doc := fetchSpec(t, engine)
+	registered := map[string]bool{}
+	for _, route := range engine.Routes() {
+		path, _ := openapi.TemplatePath(route.Path)
+		registered[route.Method+" "+path] = true
+		if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("%s %s is not documented", route.Method, path)
+		}
+	}
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if !registered[strings.ToUpper(method)+" "+path] {
+				t.Errorf("%s %s is documented but not routed", method, path)
+			}
+		}
+	}
+}
+
+// TestContract drives every route through success and failure paths and checks
+// each response status and body against the served spec.
+func TestContract(t *testing.T) {
+	engine := newTestRouter(t)
+	doc := fetchSpec(t, engine)
+	today := time.Now().Format(time.DateOnly)
+	tests := []struct {
+		method, path, route, key, body string
+		status                         int
+	}{
+		{"GET", "/", "/", "", "", 200},
+		{"GET", "/healthz", "/healthz", "", "", 200},
+		{"GET", "/readyz", "/readyz", "", "", 200},
+		{"GET", "/api/v1/auto/type/standard", "/api/v1/auto/type/{type}", "agent", "", 200},
+		{"GET", "/api/v1/auto/type/standard", "/api/v1/auto/type/{type}", "", "", 401},
+		{"GET", "/api/v1/auto/type/unknown", "/api/v1/auto/type/{type}", "agent", "", 422},
+		{"GET", "/api/v1/autos?type=standard&status=available&min_price=10&sort=-price", "/api/v1/autos", "agent", "", 200},
+		{"GET", "/api/v1/autos?location=nowhere&available_from=2023-10-01", "/api/v1/autos", "agent", "", 200},
+		{"GET", "/api/v1/autos?status=lost&min_price=20&max_price=10", "/api/v1/autos", "agent", "", 422},
+		{"GET", "/api/v1/autos?cursor=bm9wZQ", "/api/v1/autos", "agent", "", 422},
+		{"GET", "/api/v1/autos?make=MINI&fuel_type=electric&features=navigation,heated-seats&min_year=2020", "/api/v1/autos", "customer", "", 200},
+		{"GET", "/api/v1/autos?fuel_type=steam&min_year=2030&max_year=2020", "/api/v1/autos", "agent", "", 422},
+		{"POST", "/api/v1/auto/bind", "/api/v1/auto/bind", "customer", `{"auto_id": "MINI", "days": 3}`, 200},
+		{"POST", "/api/v1/auto/bind", "/api/v1/auto/bind", "agent", `{"auto_id": "MINI", "days": 3}`, 400},
+		{"POST", "/api/v1/auto/bind", "/api/v1/auto/bind", "agent", `{"auto_id": "MINI", "days": 0}`, 422},
+		{"POST", "/api/v1/auto/bind", "/api/v1/auto/bind", "agent", `{"days": "ten"}`, 422},
+		{"POST", "/api/v1/auto/bind", "/api/v1/auto/bind", "agent", `{"auto_id": "DEERE", "days": 91}`, 422},
+		{"POST", "/api/v1/auto/bind", "/api/v1/auto/bind", "agent", `{"auto_id": "NONE", "days": 3}`, 404},
+		{"POST", "/api/v1/auto/bind", "/api/v1/auto/bind", "agent", `{"auto_id": `, 400},
+		{"GET", "/api/v1/auto/commission/MINI", "/api/v1/auto/commission/{auto_id}", "customer", "", 200},
+		{"GET", "/api/v1/auto/commission/DEERE", "/api/v1/auto/commission/{auto_id}", "customer", "", 404},
+		{"GET", "/api/v1/auto/release/MINI", "/api/v1/auto/release/{auto_id}", "customer", "", 200},
+		{"GET", "/api/v1/auto/release/MINI", "/api/v1/auto/release/{auto_id}", "customer", "", 404},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10, "client_id": "declined"}`, 402},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10, "promo_code": "OLD"}`, 422},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10, "redeem_points": 900}`, 422},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10, "redeem_points": -1}`, 422},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10, "extras": {"child-seat": 3}}`, 422},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10, "extras": {"gps": 0}}`, 422},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10, "insurance": "premium"}`, 422},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10, "client_id": "client-2", "extras": {"child-seat": 1}, "insurance": "basic"}`, 201},
+		{"POST", "/api/v2/rentals", "/api/v2/rentals", "agent", `{"auto_id": "DEERE", "days": 10}`, 400},
+		{"GET", "/api/v2/rentals/2", "/api/v2/rentals/{id}", "agent", "", 200},
+		{"GET", "/api/v2/rentals/2", "/api/v2/rentals/{id}", "customer", "", 404},
+		{"GET", "/api/v2/rentals/x", "/api/v2/rentals/{id}", "agent", "", 400},
+		{"POST", "/api/v2/rentals/2/return", "/api/v2/rentals/{id}/return", "agent", `{"return_date": "15.10.2023"}`, 422},
+		{"POST", "/api/v2/rentals/2/return", "/api/v2/rentals/{id}/return", "agent", `{"odometer": -1}`, 422},
+		{"POST", "/api/v2/rentals/2/return", "/api/v2/rentals/{id}/return", "agent", `{"damage": -1}`, 422},
+		{"POST", "/api/v2/rentals/2/return", "/api/v2/rentals/{id}/return", "agent", `{"return_date": "2023-10-15"}`, 422},
+		{"POST", "/api/v2/rentals/2/return", "/api/v2/rentals/{id}/return", "customer", `{"return_date": "` + today + `"}`, 422},
+		{"POST", "/api/v2/rentals/2/return", "/api/v2/rentals/{id}/return", "customer", `{"damage": 1500}`, 422},
+		{"POST", "/api/v2/rentals/2/return", "/api/v2/rentals/{id}/return", "agent", `{"return_date": "` + today + `", "odometer": 10, "damage": 1500}`, 200},
+		{"POST", "/api/v2/rentals/2/return", "/api/v2/rentals/{id}/return", "agent", `{}`, 409},
+		{"GET", "/api/v2/rentals/2", "/api/v2/rentals/{id}", "agent", "", 200},
+		{"POST", "/api/v1/webhooks", "/api/v1/webhooks", "partner", `{"url": "https://partner.example/hooks", "event_types": ["rental.started", "rental.closed"]}`, 201},
+		{"POST", "/api/v1/webhooks", "/api/v1/webhooks", "partner", `{"url": "ftp://partner.example", "event_types": ["rental.extended"]}`, 422},
+		{"POST", "/api/v1/webhooks", "/api/v1/webhooks", "partner", `{"url": "https://partner.example/hooks", "event_types": []}`, 422},
+		{"POST", "/api/v1/webhooks", "/api/v1/webhooks", "customer", `{"url": "https://partner.example/hooks", "event_types": ["rental.started"]}`, 403},
+		{"GET", "/api/v1/webhooks", "/api/v1/webhooks", "partner", "", 200},
+		{"GET", "/api/v1/webhooks/1", "/api/v1/webhooks/{id}", "partner", "", 200},
+		{"GET", "/api/v1/webhooks/1", "/api/v1/webhooks/{id}", "agent", "", 404},
+		{"GET", "/api/v1/webhooks/1", "/api/v1/webhooks/{id}", "admin", "", 200},
+		{"GET", "/api/v1/webhooks/x", "/api/v1/webhooks/{id}", "partner", "", 400},
+		{"GET", "/api/v1/webhooks/1/deliveries?status=dead", "/api/v1/webhooks/{id}/deliveries", "partner", "", 200},
+		{"GET", "/api/v1/webhooks/1/deliveries?status=lost&limit=500", "/api/v1/webhooks/{id}/deliveries", "partner", "", 422},
+		{"POST", "/api/v1/webhooks/1/deliveries/2/retry", "/api/v1/webhooks/{id}/deliveries/{delivery_id}/retry", "partner", "", 409},
+		{"POST", "/api/v1/webhooks/1/deliveries/1/retry", "/api/v1/webhooks/{id}/deliveries/{delivery_id}/retry", "partner", "", 200},
+		{"POST", "/api/v1/webhooks/1/deliveries/9/retry", "/api/v1/webhooks/{id}/deliveries/{delivery_id}/retry", "partner", "", 404},
+		{"DELETE", "/api/v1/webhooks/1", "/api/v1/webhooks/{id}", "partner", "", 200},
+		{"DELETE", "/api/v1/webhooks/1", "/api/v1/webhooks/{id}", "partner", "", 404},
+		{"GET", "/api/v2/invoices", "/api/v2/invoices", "customer", "", 200},
+		{"GET", "/api/v2/invoices?rent_id=0&limit=500", "/api/v2/invoices", "agent", "", 422},
+		{"GET", "/api/v2/invoices/1", "/api/v2/invoices/{id}", "customer", "", 200},
+		{"GET", "/api/v2/invoices/9", "/api/v2/invoices/{id}", "agent", "", 404},
+		{"GET", "/api/v2/invoices/x", "/api/v2/invoices/{id}", "agent", "", 400},
+		{"GET", "/api/v2/invoices/9/pdf", "/api/v2/invoices/{id}/pdf", "agent", "", 404},
+		{"POST", "/api/v2/invoices/1/credit-notes", "/api/v2/invoices/{id}/credit-notes", "customer", `{"reason": "goodwill"}`, 403},
+		{"POST", "/api/v2/invoices/1/credit-notes", "/api/v2/invoices/{id}/credit-notes", "agent", `{"lines": [0]}`, 422},
+		{"POST", "/api/v2/invoices/1/credit-notes", "/api/v2/invoices/{id}/credit-notes", "agent", `{"reason": "typo", "lines": [3]}`, 422},
+		{"POST", "/api/v2/invoices/1/credit-notes", "/api/v2/invoices/{id}/credit-notes", "agent", `{"reason": "goodwill"}`, 201},
+		{"POST", "/api/v2/invoices/1/credit-notes", "/api/v2/invoices/{id}/credit-notes", "agent", `{"reason": "goodwill"}`, 409},
+		{"GET", "/api/v2/invoices/2", "/api/v2/invoices/{id}", "agent", "", 200},
+		{"GET", "/api/v2/extras?location=Berlin", "/api/v2/extras", "customer", "", 200},
+		{"GET", "/api/v2/extras", "/api/v2/extras", "agent", "", 200},
+		{"GET", "/api/v2/insurance?auto_type=standard", "/api/v2/insurance", "customer", "", 200},
+		{"GET", "/api/v2/insurance?auto_type=boat", "/api/v2/insurance", "customer", "", 422},
+		{"GET", "/api/v2/insurance", "/api/v2/insurance", "agent", "", 422},
+		{"GET", "/api/v2/clients/client-1/loyalty", "/api/v2/clients/{client_id}/loyalty", "customer", "", 200},
+		{"GET", "/api/v2/clients/client-2/loyalty", "/api/v2/clients/{client_id}/loyalty", "customer", "", 404},
+		{"GET", "/api/v2/clients/client-2/loyalty?limit=5", "/api/v2/clients/{client_id}/loyalty", "agent", "", 200},
+		{"GET", "/api/v2/clients/client-1/loyalty?limit=500", "/api/v2/clients/{client_id}/loyalty", "agent", "", 422},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		if tt.key != "" {
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		name := tt.method + " " + tt.path + " " + tt.body
+		if rec.Code != tt.status {
+			t.Errorf("%s: want status %d, got %d: %s", name, tt.status, rec.Code, rec.Body.String())
+			continue
+		}
+		schema, err := doc.ResponseSchema(tt.method, tt.route, rec.Code)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var body interface{}
+		if err = json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: response is not JSON: %v", name, err)
+			continue
+		}
+		if err = doc.Validate(schema, body); err != nil {
+			t.Errorf("%s: response diverges from the spec: %v", name, err)
+		}
+	}
+}
+
+// TestOperatorRoles checks the roles of the routes reaching more than the rents of the caller: customers reach
+// none of them, agents and admins act for every client.
+func TestOperatorRoles(t *testing.T) {
+	tests := []struct {
+		method, path, body string
+		// allowed are the roles getting past the route, the others get 403
+		allowed []string
+	}{
+		{"POST", "/api/v2/invoices/1/credit-notes", `{"reason": "goodwill"}`, []string{"agent", "admin"}},
+		{"GET", "/api/v1/webhooks", "", []string{"agent", "admin"}},
+	}
+	for _, tt := range tests {
+		for _, key := range []string{"customer", "agent", "admin"} {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(auth.APIKeyHeader, key)
+			rec := httptest.NewRecorder()
+			// a fresh router each time, so a change by an allowed role doesn't fail the next one
+			newTestRouter(t).ServeHTTP(rec, req)
+			if forbidden := !slices.Contains(tt.allowed, key); forbidden != (rec.Code == http.StatusForbidden) {
+				t.Errorf("%s %s as %s: want forbidden %v, got %d: %s",
+					tt.method, tt.path, key, forbidden, rec.Code, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestInvoiceDocuments(t *testing.T) {
+	engine := newTestRouter(t)
+	tests := []struct {
+		path, key   string
+		status      int
+		contentType string
+		prefix      string
+	}{
+		{"/api/v2/invoices/1/html", "customer", 200, "text/html; charset=utf-8", "<!DOCTYPE html>"},
+		{"/api/v2/invoices/1/pdf", "customer", 200, "application/pdf", "%PDF-"},
+		{"/api/v2/invoices/1/pdf", "", 401, "application/json; charset=utf-8", "{"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != tt.status || rec.Header().Get("Content-Type") != tt.contentType ||
+			!strings.HasPrefix(rec.Body.String(), tt.prefix) {
+			t.Errorf("%s: want %d %s, got %d %s", tt.path, tt.status, tt.contentType, rec.Code,
+				rec.Header().Get("Content-Type"))
+		}
+	}
+}
+
+func TestReadinessFailure(t *testing.T) {
+	checker := health.NewChecker()
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.Add("migrations", func(ctx context.Context) error { return errors.New("schema at version 5, want 6") })
+	engine := newTestRouterWithChecker(t, checker)
+	doc := fetchSpec(t, engine)
+
+	for _, drain := range []bool{false, true} {
+		if drain {
+			checker.Drain()
+		}
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+		var response controller.HealthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusServiceUnavailable || response.Checks["migrations"] != "schema at version 5, want 6" {
+			t.Errorf("want 503 naming the failed check, got %d %s", rec.Code, rec.Body.String())
+		}
+		if drain && response.Checks["shutdown"] != health.StatusDraining {
+			t.Errorf("want draining reported, got %s", rec.Body.String())
+		}
+		schema, err := doc.ResponseSchema("GET", "/readyz", rec.Code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+		if err = doc.Validate(schema, body); err != nil {
+			t.Errorf("response diverges from the spec: %v", err)
+		}
+	}
+	// liveness doesn't depend on the checks
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("want 200, got %d", rec.Code)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	engine := newTestRouter(t)
+	for _, path := range []string{"/api/v2/rentals/1", "/api/v2/rentals/2", "/wp-admin"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(auth.APIKeyHeader, "agent")
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d", rec.Code)
+	}
+	// requests are labelled by route template, unknown paths share one label
+	for _, want := range []string{
+		`car_rental_http_requests_total{method="GET",route="/api/v2/rentals/:id",status="404"} 2`,
+		`car_rental_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`car_rental_http_request_duration_seconds_count{method="GET",route="/api/v2/rentals/:id"} 2`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics miss %s", want)
+		}
+	}
+}
+
+func TestMetricsCountPanics(t *testing.T) {
+	engine := newTestRouter(t)
+	engine.GET("/panic", func(*gin.Context) {
+		panic("handler failed")
+	})
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest("GET", "/panic", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("want 500, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	want := `car_rental_http_requests_total{method="GET",route="/panic",status="500"} 1`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("metrics miss %s", want)
+	}
+}
+
+func TestRequestLogging(t *testing.T) {
+	svc := newFakeRentalService()
+	svc.err = errors.New("connection reset by peer")
+	var logs bytes.Buffer
+	logger, err := logging.New(&logs, "info", logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := newTestRouterWithService(t, health.NewChecker(), svc, logger, tracing.NoopTracer())
+
+	req := httptest.NewRequest("GET", "/api/v2/rentals/1", nil)
+	req.Header.Set(auth.APIKeyHeader, "agent")
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "connection reset") {
+		t.Errorf("want a generic 500, got %d %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get(middleware.RequestIDHeader); got != "req-42" {
+		t.Errorf("want the request id echoed, got %q", got)
+	}
+
+	var failure map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var record map[string]interface{}
+		if err = json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		if record["msg"] == "finding rent failed" {
+			failure = record
+		}
+	}
+	if failure == nil {
+		t.Fatalf("the cause of the 500 is not logged: %s", logs.String())
+	}
+	actor, _ := failure["actor"].(map[string]interface{})
+	if failure["request_id"] != "req-42" || failure["error"] != "connection reset by peer" || actor["subject"] != "agent" {
+		t.Errorf("want the failure logged with request id, cause and actor, got %v", failure)
+	}
+
+	// ids that could forge log lines are replaced by a generated one
+	req = httptest.NewRequest("GET", "/healthz", nil)
+	req.Header.Set(middleware.RequestIDHeader, "forged\nline")
+	rec = httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	if got := rec.Header().Get(middleware.RequestIDHeader); len(got) != 32 {
+		t.Errorf("want a generated request id, got %q", got)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	if _, err := tracing.NewProvider(context.Background(), tracing.Options{Exporter: tracing.ExporterNone}); err != nil {
+		t.Fatal(err) // installs the trace context propagator
+	}
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(tracing.InstrumentationName)
+	svc := newFakeRentalService()
+	svc.err = errors.New("connection reset by peer")
+	var logs bytes.Buffer
+	logger, err := logging.New(&logs, "info", logging.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := newTestRouterWithService(t, health.NewChecker(), svc, logger, tracer)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/api/v2/rentals/1", nil)
+	req.Header.Set(auth.APIKeyHeader, "agent")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("want one server span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /api/v2/rentals/:id" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("want a server span named by route, got %q %v", span.Name(), span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != traceID {
+		t.Errorf("want the caller's trace continued, got %s", span.SpanContext().TraceID())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("want the 500 marked as an error, got %v", span.Status())
+	}
+	// log lines of the request carry the trace, to jump from a log to its trace
+	if !strings.Contains(logs.String(), `"trace_id":"`+traceID+`"`) {
+		t.Errorf("want the trace id logged, got %s", logs.String())
+	}
+}
+
+func TestValidationReport(t *testing.T) {
+	engine := newTestRouter(t)
+	req := httptest.NewRequest("POST", "/api/v2/rentals", strings.NewReader(`{"days": 0}`))
+	req.Header.Set(auth.APIKeyHeader, "agent")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	var response controller.ValidationErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	want := []controller.FieldError{
+		{Field: "auto_id", Reason: "is required"},
+		{Field: "days", Reason: "must be at least 1"},
+	}
+	if rec.Code != http.StatusUnprocessableEntity || len(response.Errors) != len(want) {
+		t.Fatalf("want %d with %v, got %d %s", http.StatusUnprocessableEntity, want, rec.Code, rec.Body.String())
+	}
+	for i := range want {
+		if response.Errors[i] != want[i] {
+			t.Errorf("want %v, got %v", want[i], response.Errors[i])
+		}
+	}
+}
+
+// customers return their autos today, without reporting damage
+func TestCustomerReturn(t *testing.T) {
+	engine := newTestRouter(t)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(auth.APIKeyHeader, "customer")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := do("POST", "/api/v2/rentals", `{"auto_id": "MINI", "days": 3}`); rec.Code != http.StatusCreated {
+		t.Fatalf("want %d, got %d %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	rec := do("POST", "/api/v2/rentals/1/return", `{"odometer": 10, "damage": 1500}`)
+	var validation controller.ValidationErrorResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &validation)
+	if rec.Code != http.StatusUnprocessableEntity || len(validation.Errors) != 1 || validation.Errors[0].Field != "damage" {
+		t.Fatalf("want the damage rejected, got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = do("POST", "/api/v2/rentals/1/return", `{"odometer": 10}`)
+	var rental controller.RentalResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &rental)
+	if rec.Code != http.StatusOK || rental.Status != models.RentStatusClosed || rental.Damage != 0 {
+		t.Errorf("want the rental closed without damage, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// a failed lookup of the auto types is the server's fault, not an unknown auto type
+func TestAutoTypeLookupFailure(t *testing.T) {
+	svc := newFakeRentalService()
+	svc.err = errors.New("connection reset by peer")
+	engine := newTestRouterWithService(t, health.NewChecker(), svc, logging.Discard(), tracing.NoopTracer())
+	for _, path := range []string{"/api/v1/auto/type/standard", "/api/v1/autos?type=standard",
+		"/api/v2/insurance?auto_type=standard"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(auth.APIKeyHeader, "agent")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("%s: want %d, got %d %s", path, http.StatusInternalServerError, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestListAutosPagination(t *testing.T) {
+	engine := newTestRouter(t)
+	var ids []string
+	path := "/api/v1/autos?limit=1"
+	for page := 0; page < 3; page++ {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(auth.APIKeyHeader, "agent")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		var response controller.AutoListResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range response.Items {
+			ids = append(ids, item.ID)
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		path = "/api/v1/autos?limit=1&cursor=" + response.NextCursor
+	}
+	if strings.Join(ids, ",") != "DEERE,MINI" {
+		t.Errorf("want %s, got %v", "DEERE,MINI", ids)
+	}
+}
+
+func fetchSpec(t *testing.T, engine *gin.Engine) *openapi.Document {
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("want status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+// noIdempotencyRepository lets every request through as a first attempt
+type noIdempotencyRepository struct{}
+
+func (noIdempotencyRepository) GetKey(context.Context, string, string) (models.IdempotencyKey, error) {
+	return models.IdempotencyKey{}, gorm.ErrRecordNotFound
+}
+
+func (noIdempotencyRepository) CreateKey(context.Context, models.IdempotencyKey) (bool, error) {
+	return true, nil
+}
+
+func (noIdempotencyRepository) CompleteKey(context.Context, models.IdempotencyKey) error {
+	return nil
+}
+
+func (noIdempotencyRepository) DeleteKey(context.Context, string, string) error {
+	return nil
+}
+
+func (noIdempotencyRepository) DeleteExpiredKeys(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"gorm.io/gorm"
+)
+
+// extraRepositories rent autos at Berlin from start with the extras of the catalogue in memory,
+// a failed transaction restores the rents and the reservations.
+type extraRepositories struct {
+	*paymentRepositories
+	repository.ExtraRepository
+	start    time.Time
+	catalog  map[string]models.Extra
+	stock    map[string]int
+	reserved []models.RentExtra
+}
+
+func (r *extraRepositories) GetAutoById(ctx context.Context, autoId string) (models.Auto, error) {
+	return models.Auto{ID: autoId, Type: "standard", Location: "Berlin", Availability: true}, nil
+}
+
+func (r *extraRepositories) BindRent(ctx context.Context, rent models.AutoRent, days int) (models.AutoRent, error) {
+	rent, err := r.paymentRepositories.BindRent(ctx, rent, days)
+	rent.StartDate = r.start
+	rent.EndDate = r.start.AddDate(0, 0, days)
+	r.rents[rent.ID-1] = rent
+	return rent, err
+}
+
+func (r *extraRepositories) GetExtra(ctx context.Context, id string) (models.Extra, error) {
+	extra, ok := r.catalog[id]
+	if !ok {
+		return extra, gorm.ErrRecordNotFound
+	}
+	return extra, nil
+}
+
+func (r *extraRepositories) LockStock(ctx context.Context, extraId string, location string) (int, error) {
+	return r.stock[extraId+"@"+location], nil
+}
+
+func (r *extraRepositories) CountReserved(
+	ctx context.Context, extraId string, location string, from time.Time, until time.Time) (int, error) {
+	reserved := 0
+	for _, extra := range r.reserved {
+		if extra.ExtraID == extraId && extra.Location == location && extra.Stocked &&
+			extra.ReservedFrom.Before(until) && (extra.ReservedUntil.After(from) || extra.ReleasedAt == nil) {
+			reserved += extra.Quantity
+		}
+	}
+	return reserved, nil
+}
+
+func (r *extraRepositories) ReserveExtra(ctx context.Context, extra models.RentExtra) (models.RentExtra, error) {
+	extra.ID = int64(len(r.reserved) + 1)
+	r.reserved = append(r.reserved, extra)
+	return extra, nil
+}
+
+func (r *extraRepositories) GetRentExtras(ctx context.Context, rentId int) ([]models.RentExtra, error) {
+	var extras []models.RentExtra
+	for _, extra := range r.reserved {
+		if extra.RentID == rentId {
+			extras = append(extras, extra)
+		}
+	}
+	return extras, nil
+}
+
+func (r *extraRepositories) ReleaseExtras(ctx context.Context, rentId int, releasedAt time.Time) error {
+	for i, extra := range r.reserved {
+		if extra.RentID == rentId && extra.ReleasedAt == nil {
+			r.reserved[i].ReleasedAt = &releasedAt
+			if releasedAt.Before(extra.ReservedUntil) {
+				r.reserved[i].ReservedUntil = releasedAt
+			}
+		}
+	}
+	return nil
+}
+
+func (r *extraRepositories) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	reserved := append([]models.RentExtra(nil), r.reserved...)
+	err := r.paymentRepositories.InTransaction(ctx, fn)
+	if err != nil {
+		r.reserved = reserved
+	}
+	return err
+}
+
+func TestExtrasReservedForTheRent(t *testing.T) {
+	ctx := context.Background()
+	repositories := &extraRepositories{
+		paymentRepositories: &paymentRepositories{},
+		start:               time.Date(2023, time.October, 2, 0, 0, 0, 0, time.UTC),
+		catalog: map[string]models.Extra{
+			"child-seat": {ID: "child-seat", Name: "Child seat", Pricing: models.ExtraPricingDay, Price: 5,
+				Stocked: true},
+			"additional-driver": {ID: "additional-driver", Name: "Additional driver",
+				Pricing: models.ExtraPricingRental, Price: 30},
+		},
+		stock: map[string]int{"child-seat@Berlin": 2},
+	}
+	svc := NewRentalServiceImpl(repositories, repositories, repositories,
+		WithEvents(repositories, nil), WithExtras(repositories))
+
+	rent, err := svc.BindAuto(ctx, "MINI", 5, "client-1",
+		RentalOptions{Extras: map[string]int{"child-seat": 2, "additional-driver": 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var extraError ExtraError
+	_, err = svc.BindAuto(ctx, "DEERE", 5, "client-2", RentalOptions{Extras: map[string]int{"child-seat": 1}})
+	if !errors.As(err, &extraError) || extraError.ExtraID != "child-seat" || len(repositories.rents) != 1 {
+		t.Fatalf("want the child seats of the first rent to leave none, got %v", err)
+	}
+	_, err = svc.BindAuto(ctx, "DEERE", 5, "client-2", RentalOptions{Extras: map[string]int{"gps": 1}})
+	if !errors.As(err, &extraError) || extraError.Reason != "is unknown" {
+		t.Errorf("want an unknown extra rejected, got %v", err)
+	}
+
+	// 3 days at 200 and the agreement of 200, the two seats for 3 days and the driver once
+	rent, err = svc.ReturnRent(ctx, rent.ID, paymentReturn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rent.Checkout != 800+2*5*3+30 {
+		t.Errorf("want a checkout of %d, got %d", 800+2*5*3+30, rent.Checkout)
+	}
+	lines := checkoutLines("MINI", checkoutBreakdown{Days: 3}, repositories.reserved)
+	if len(lines) != 2 || lines[0].Description != "Additional driver x1" || lines[0].Amount != 30 ||
+		lines[1].Description != "Child seat x2, 3 days" || lines[1].Quantity != 6 || lines[1].Amount != 30 {
+		t.Errorf("want a line per extra, got %+v", lines)
+	}
+
+	// the seats returned with the auto are free for the rents starting after
+	repositories.start = paymentReturn.ReturnDate
+	if _, err = svc.BindAuto(ctx, "DEERE", 5, "client-2",
+		RentalOptions{Extras: map[string]int{"child-seat": 2}}); err != nil {
+		t.Errorf("want the returned seats reserved again, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+)
+
+type InvoiceService interface {
+	// IssueInvoice numbers the checkout lines of a closed rent and sums their taxes, in the transaction closing it
+	IssueInvoice(ctx context.Context, rent models.AutoRent, autoType string, lines []models.InvoiceLine) (
+		models.Invoice, error)
+	GetInvoice(ctx context.Context, id int64) (models.Invoice, error)
+	// ListInvoices returns invoices and credit notes, newest first
+	ListInvoices(ctx context.Context, query repository.InvoiceQuery) ([]models.Invoice, error)
+	// IssueCreditNote reverses the invoice lines at the positions, every line not credited yet when empty
+	IssueCreditNote(ctx context.Context, invoiceId int64, positions []int, reason string) (models.Invoice, error)
+}
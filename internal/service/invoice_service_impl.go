@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"car-rental/internal/invoicing"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+)
+
+const (
+	AlreadyCreditedError = "invoice is already credited"
+	NoSuchLineError      = "invoice has no such line"
+	CreditNoteError      = "credit notes can't be credited"
+)
+
+type InvoiceServiceImpl struct {
+	invoiceRepository repository.InvoiceRepository
+	transactor        repository.Transactor
+	settings          invoicing.Settings
+	logger            *slog.Logger
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewInvoiceServiceImpl logs every invoice and credit note issued.
+func NewInvoiceServiceImpl(invoiceRepository repository.InvoiceRepository, transactor repository.Transactor,
+	settings invoicing.Settings, logger *slog.Logger) *InvoiceServiceImpl {
+	return &InvoiceServiceImpl{
+		invoiceRepository: invoiceRepository,
+		transactor:        transactor,
+		settings:          settings,
+		logger:            logger,
+		now:               time.Now,
+	}
+}
+
+func (s InvoiceServiceImpl) IssueInvoice(
+	ctx context.Context, rent models.AutoRent, autoType string, lines []models.InvoiceLine) (models.Invoice, error) {
+	invoice := models.Invoice{
+		Kind:          models.InvoiceKindInvoice,
+		RentID:        rent.ID,
+		AutoID:        rent.AutoID,
+		AutoType:      autoType,
+		ClientID:      rent.ClientID,
+		IssuerName:    s.settings.IssuerName,
+		IssuerAddress: s.settings.IssuerAddress,
+		IssuerTaxID:   s.settings.IssuerTaxID,
+		Currency:      s.settings.Currency,
+	}
+	for i, line := range lines {
+		line.Position = i + 1
+		invoice.Lines = append(invoice.Lines, line)
+	}
+	err := s.transactor.InTransaction(ctx, func(ctx context.Context) (err error) {
+		invoice, err = s.issue(ctx, invoice, s.settings.NumberPrefix)
+		return err
+	})
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	s.logger.InfoContext(ctx, "invoice issued", slog.String("number", invoice.Number),
+		slog.Int("rent_id", rent.ID), slog.String("client_id", rent.ClientID), slog.Int("gross", invoice.Gross))
+	return invoice, nil
+}
+
+func (s InvoiceServiceImpl) GetInvoice(ctx context.Context, id int64) (models.Invoice, error) {
+	return s.invoiceRepository.GetInvoice(ctx, id)
+}
+
+func (s InvoiceServiceImpl) ListInvoices(ctx context.Context, query repository.InvoiceQuery) (
+	[]models.Invoice, error) {
+	return s.invoiceRepository.FindInvoices(ctx, query)
+}
+
+func (s InvoiceServiceImpl) IssueCreditNote(
+	ctx context.Context, invoiceId int64, positions []int, reason string) (models.Invoice, error) {
+	invoice, err := s.invoiceRepository.GetInvoice(ctx, invoiceId)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	if invoice.Kind == models.InvoiceKindCreditNote {
+		return models.Invoice{}, errors.New(CreditNoteError)
+	}
+	creditNote := models.Invoice{
+		Kind:                  models.InvoiceKindCreditNote,
+		CreditedInvoiceID:     &invoice.ID,
+		CreditedInvoiceNumber: invoice.Number,
+		Reason:                reason,
+		RentID:                invoice.RentID,
+		AutoID:                invoice.AutoID,
+		AutoType:              invoice.AutoType,
+		ClientID:              invoice.ClientID,
+		IssuerName:            invoice.IssuerName,
+		IssuerAddress:         invoice.IssuerAddress,
+		IssuerTaxID:           invoice.IssuerTaxID,
+		Currency:              invoice.Currency,
+	}
+	err = s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		// taking the number locks the credit note series, so the credited lines can't change until commit
+		series := invoicing.Series(s.settings.CreditNotePrefix, s.now().Year())
+		number, err := s.invoiceRepository.NextNumber(ctx, series)
+		if err != nil {
+			return err
+		}
+		creditNote.Lines, err = s.creditLines(ctx, invoice, positions)
+		if err != nil {
+			return err
+		}
+		creditNote, err = s.store(ctx, creditNote, invoicing.Number(series, number))
+		return err
+	})
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	s.logger.InfoContext(ctx, "credit note issued", slog.String("number", creditNote.Number),
+		slog.String("invoice", invoice.Number), slog.Int("gross", creditNote.Gross), slog.String("reason", reason))
+	return creditNote, nil
+}
+
+// creditLines reverses the lines at the positions that no credit note of the invoice reversed yet.
+func (s InvoiceServiceImpl) creditLines(
+	ctx context.Context, invoice models.Invoice, positions []int) ([]models.InvoiceLine, error) {
+	creditNotes, err := s.invoiceRepository.FindInvoices(ctx, repository.InvoiceQuery{CreditedInvoiceID: invoice.ID})
+	if err != nil {
+		return nil, err
+	}
+	credited := map[int]bool{}
+	for _, creditNote := range creditNotes {
+		for _, line := range creditNote.Lines {
+			credited[line.CreditedPosition] = true
+		}
+	}
+	selected := map[int]bool{}
+	for _, position := range positions {
+		if position < 1 || position > len(invoice.Lines) {
+			return nil, errors.New(NoSuchLineError)
+		}
+		if credited[position] {
+			return nil, errors.New(AlreadyCreditedError)
+		}
+		selected[position] = true
+	}
+	var lines []models.InvoiceLine
+	for _, line := range invoice.Lines {
+		if credited[line.Position] || (len(positions) != 0 && !selected[line.Position]) {
+			continue
+		}
+		lines = append(lines, models.InvoiceLine{
+			Position:         len(lines) + 1,
+			CommissionType:   line.CommissionType,
+			Description:      line.Description,
+			Quantity:         line.Quantity,
+			UnitPrice:        -line.UnitPrice,
+			Amount:           -line.Amount,
+			TaxRate:          line.TaxRate,
+			CreditedPosition: line.Position,
+		})
+	}
+	if len(lines) == 0 {
+		return nil, errors.New(AlreadyCreditedError)
+	}
+	return lines, nil
+}
+
+// issue numbers the invoice in the series of the prefix and stores it.
+func (s InvoiceServiceImpl) issue(ctx context.Context, invoice models.Invoice, prefix string) (models.Invoice, error) {
+	series := invoicing.Series(prefix, s.now().Year())
+	number, err := s.invoiceRepository.NextNumber(ctx, series)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+	return s.store(ctx, invoice, invoicing.Number(series, number))
+}
+
+func (s InvoiceServiceImpl) store(ctx context.Context, invoice models.Invoice, number string) (models.Invoice, error) {
+	invoice.Number = number
+	invoice.IssuedAt = s.now()
+	invoice.Taxes = invoicing.Taxes(invoice.Lines, s.settings.TaxName)
+	invoicing.Total(&invoice)
+	return s.invoiceRepository.CreateInvoice(ctx, invoice)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"car-rental/internal/invoicing"
+	"car-rental/internal/logging"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"car-rental/internal/tax"
+	"gorm.io/gorm"
+)
+
+// invoiceRepositories keep one rent and the invoices in memory, a failed transaction restores the invoices.
+type invoiceRepositories struct {
+	repository.AutoRepository
+	repository.RentalRepository
+	repository.CommissionRepository
+	rent      models.AutoRent
+	closeErr  error
+	invoices  []models.Invoice
+	sequences map[string]int
+}
+
+func (r *invoiceRepositories) GetRentById(ctx context.Context, rentId int) (models.AutoRent, error) {
+	return r.rent, nil
+}
+
+func (r *invoiceRepositories) GetAutoById(ctx context.Context, autoId string) (models.Auto, error) {
+	return models.Auto{ID: autoId, Type: "standard", Location: "Berlin"}, nil
+}
+
+func (r *invoiceRepositories) GetCommissionsByType(ctx context.Context, _ string) []models.Commission {
+	return []models.Commission{
+		{Type: commissionTypeDaily, Value: 200},
+		{Type: commissionTypeWeekend, Value: 20},
+		{Type: commissionTypeAgreement, Value: 200},
+		{Type: commissionTypePenalty, Value: 5, MinThreshold: 10},
+	}
+}
+
+func (r *invoiceRepositories) ReleaseAuto(ctx context.Context, _ string) error {
+	return nil
+}
+
+func (r *invoiceRepositories) CloseRent(ctx context.Context, rent models.AutoRent) (bool, error) {
+	return r.closeErr == nil, r.closeErr
+}
+
+func (r *invoiceRepositories) NextNumber(ctx context.Context, series string) (int, error) {
+	r.sequences[series]++
+	return r.sequences[series], nil
+}
+
+func (r *invoiceRepositories) CreateInvoice(ctx context.Context, invoice models.Invoice) (models.Invoice, error) {
+	invoice.ID = int64(len(r.invoices) + 1)
+	r.invoices = append(r.invoices, invoice)
+	return invoice, nil
+}
+
+func (r *invoiceRepositories) GetInvoice(ctx context.Context, id int64) (models.Invoice, error) {
+	if id < 1 || int(id) > len(r.invoices) {
+		return models.Invoice{}, gorm.ErrRecordNotFound
+	}
+	return r.invoices[id-1], nil
+}
+
+func (r *invoiceRepositories) FindInvoices(ctx context.Context, query repository.InvoiceQuery) (
+	[]models.Invoice, error) {
+	var invoices []models.Invoice
+	for _, invoice := range r.invoices {
+		if invoice.CreditedInvoiceID != nil && *invoice.CreditedInvoiceID == query.CreditedInvoiceID {
+			invoices = append(invoices, invoice)
+		}
+	}
+	return invoices, nil
+}
+
+func (r *invoiceRepositories) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	invoices := r.invoices
+	sequences := map[string]int{}
+	for series, number := range r.sequences {
+		sequences[series] = number
+	}
+	if err := fn(ctx); err != nil {
+		r.invoices, r.sequences = invoices, sequences
+		return err
+	}
+	return nil
+}
+
+func TestReturnRentIssuesInvoice(t *testing.T) {
+	ctx := context.Background()
+	repositories := &invoiceRepositories{
+		rent: models.AutoRent{ID: 7, AutoID: "MINI", ClientID: "client-1",
+			StartDate: time.Date(2023, time.September, 22, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2023, time.October, 4, 0, 0, 0, 0, time.UTC)},
+		sequences: map[string]int{},
+	}
+	invoices := NewInvoiceServiceImpl(repositories, repositories, invoicing.Settings{Currency: "EUR",
+		NumberPrefix: "INV", CreditNotePrefix: "CN", TaxName: "VAT"}, logging.Discard())
+	invoices.now = func() time.Time { return time.Date(2023, time.October, 1, 12, 0, 0, 0, time.UTC) }
+	svc := NewRentalServiceImpl(repositories, repositories, repositories,
+		WithEvents(repositories, nil), WithInvoices(invoices),
+		WithTaxes(tax.NewRates(19, tax.Rate{Jurisdiction: "Berlin", CommissionType: commissionTypeAgreement, Percent: 7})))
+
+	// a failed close issues no invoice and takes no number
+	repositories.closeErr = errors.New("connection reset by peer")
+	returned := RentalReturn{ReturnDate: time.Date(2023, time.October, 1, 0, 0, 0, 0, time.UTC)}
+	if _, err := svc.ReturnRent(ctx, 7, returned); err == nil {
+		t.Fatal("want the return to fail")
+	}
+	if len(repositories.invoices) != 0 || repositories.sequences["INV-2023"] != 0 {
+		t.Fatalf("want no invoice of a failed return, got %v", repositories.invoices)
+	}
+
+	repositories.closeErr = nil
+	rent, err := svc.ReturnRent(ctx, 7, returned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repositories.invoices) != 1 {
+		t.Fatalf("want one invoice, got %v", repositories.invoices)
+	}
+	invoice := repositories.invoices[0]
+	// 10 days, 4 of them on weekends, 3 days of penalty and the agreement, as in TestReleaseAuto,
+	// 2190 taxed at 19% and the agreement at 7%
+	if invoice.Number != "INV-2023-000001" || invoice.RentID != 7 || invoice.ClientID != "client-1" ||
+		len(invoice.Lines) != 4 || invoice.Net != rent.Checkout || invoice.Net != 2390 ||
+		invoice.Tax != 430 || invoice.Tax != rent.CheckoutTax || invoice.Gross != 2820 || len(invoice.Taxes) != 2 {
+		t.Errorf("unexpected invoice %+v", invoice)
+	}
+	if line := invoice.Lines[0]; line.Quantity != 10 || line.UnitPrice != 200 || line.Amount != 2000 ||
+		line.CommissionType != commissionTypeDaily || line.TaxRate != 19 {
+		t.Errorf("unexpected daily line %+v", line)
+	}
+
+	// the penalty is credited first, the rest with a second credit note
+	creditNote, err := invoices.IssueCreditNote(ctx, invoice.ID, []int{3}, "penalty waived")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creditNote.Number != "CN-2023-000001" || creditNote.Net != -30 || creditNote.Tax != -6 ||
+		creditNote.CreditedInvoiceNumber != invoice.Number || creditNote.Lines[0].CreditedPosition != 3 {
+		t.Errorf("unexpected credit note %+v", creditNote)
+	}
+	if _, err = invoices.IssueCreditNote(ctx, invoice.ID, []int{3}, "again"); err == nil ||
+		err.Error() != AlreadyCreditedError {
+		t.Errorf("want %s, got %v", AlreadyCreditedError, err)
+	}
+	if _, err = invoices.IssueCreditNote(ctx, invoice.ID, []int{5}, "unknown"); err == nil ||
+		err.Error() != NoSuchLineError {
+		t.Errorf("want %s, got %v", NoSuchLineError, err)
+	}
+	rest, err := invoices.IssueCreditNote(ctx, invoice.ID, nil, "rent cancelled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Number != "CN-2023-000002" || len(rest.Lines) != 3 || rest.Net+creditNote.Net != -invoice.Net {
+		t.Errorf("want the remaining lines credited, got %+v", rest)
+	}
+	if _, err = invoices.IssueCreditNote(ctx, invoice.ID, nil, "again"); err == nil ||
+		err.Error() != AlreadyCreditedError {
+		t.Errorf("want %s, got %v", AlreadyCreditedError, err)
+	}
+	if _, err = invoices.IssueCreditNote(ctx, rest.ID, nil, "credit of a credit"); err == nil ||
+		err.Error() != CreditNoteError {
+		t.Errorf("want %s, got %v", CreditNoteError, err)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+
+	"car-rental/internal/loyalty"
+	"car-rental/internal/models"
+)
+
+// LoyaltyAccount is the balance and tier of a client with the newest entries of the ledger.
+type LoyaltyAccount struct {
+	ClientID string
+	Balance  int
+	Earned   int
+	Tier     loyalty.Tier
+	Entries  []models.LoyaltyEntry
+}
+
+type LoyaltyService interface {
+	Program() loyalty.Program
+	// Account expires the points due and returns the account of the client with up to limit entries
+	Account(ctx context.Context, clientId string, limit int) (LoyaltyAccount, error)
+	// Tier is the tier the client reached, the zero Tier below every tier
+	Tier(ctx context.Context, clientId string) (loyalty.Tier, error)
+	// Redeem takes the redeemed points of a rent being bound off the balance, in its transaction
+	Redeem(ctx context.Context, rent models.AutoRent) error
+	// Earn credits the points of the checkout of a rent being closed and those it redeemed but didn't use,
+	// in its transaction
+	Earn(ctx context.Context, rent models.AutoRent, unused int) error
+	// Adjust corrects the balance of the client, it can't go below 0
+	Adjust(ctx context.Context, clientId string, points int, note string) (models.LoyaltyEntry, error)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"car-rental/internal/loyalty"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+)
+
+// LoyaltyError is returned when points can't be redeemed or taken off, Reason says why.
+type LoyaltyError struct {
+	Reason string
+}
+
+func (e LoyaltyError) Error() string {
+	return "loyalty points " + e.Reason
+}
+
+type LoyaltyServiceImpl struct {
+	loyaltyRepository repository.LoyaltyRepository
+	transactor        repository.Transactor
+	program           loyalty.Program
+	logger            *slog.Logger
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewLoyaltyServiceImpl logs the points earned, redeemed and adjusted.
+func NewLoyaltyServiceImpl(loyaltyRepository repository.LoyaltyRepository, transactor repository.Transactor,
+	program loyalty.Program, logger *slog.Logger) *LoyaltyServiceImpl {
+	return &LoyaltyServiceImpl{
+		loyaltyRepository: loyaltyRepository,
+		transactor:        transactor,
+		program:           program,
+		logger:            logger,
+		now:               time.Now,
+	}
+}
+
+func (s LoyaltyServiceImpl) Program() loyalty.Program {
+	return s.program
+}
+
+func (s LoyaltyServiceImpl) Account(ctx context.Context, clientId string, limit int) (LoyaltyAccount, error) {
+	account := LoyaltyAccount{ClientID: clientId}
+	err := s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		balance, err := s.expire(ctx, clientId)
+		if err != nil {
+			return err
+		}
+		account.Balance, account.Earned = balance.Balance, balance.Earned
+		account.Entries, err = s.loyaltyRepository.FindEntries(ctx, clientId, limit)
+		return err
+	})
+	if err != nil {
+		return LoyaltyAccount{}, err
+	}
+	account.Tier = s.program.Tier(account.Earned)
+	return account, nil
+}
+
+func (s LoyaltyServiceImpl) Tier(ctx context.Context, clientId string) (loyalty.Tier, error) {
+	balance, err := s.loyaltyRepository.GetBalance(ctx, clientId, s.now())
+	if err != nil {
+		return loyalty.Tier{}, err
+	}
+	return s.program.Tier(balance.Earned), nil
+}
+
+func (s LoyaltyServiceImpl) Redeem(ctx context.Context, rent models.AutoRent) error {
+	if rent.RedeemedPoints == 0 {
+		return nil
+	}
+	return s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		balance, err := s.expire(ctx, rent.ClientID)
+		if err != nil {
+			return err
+		}
+		if balance.Balance < rent.RedeemedPoints {
+			return LoyaltyError{Reason: fmt.Sprintf("exceed the balance of %d", balance.Balance)}
+		}
+		_, err = s.loyaltyRepository.AddEntry(ctx, models.LoyaltyEntry{ClientID: rent.ClientID,
+			Kind: models.LoyaltyKindRedeem, Points: -rent.RedeemedPoints, RentID: &rent.ID})
+		return err
+	})
+}
+
+func (s LoyaltyServiceImpl) Earn(ctx context.Context, rent models.AutoRent, unused int) error {
+	points := s.program.Points(rent.Checkout)
+	if points == 0 && unused == 0 {
+		return nil
+	}
+	entries := make([]models.LoyaltyEntry, 0, 2)
+	if unused > 0 {
+		entries = append(entries, models.LoyaltyEntry{ClientID: rent.ClientID, Kind: models.LoyaltyKindAdjust,
+			Points: unused, RentID: &rent.ID, Note: "redeemed but not used"})
+	}
+	if points > 0 {
+		earned := models.LoyaltyEntry{ClientID: rent.ClientID, Kind: models.LoyaltyKindEarn, Points: points,
+			RentID: &rent.ID}
+		if s.program.ExpireAfter > 0 {
+			expiresAt := s.now().Add(s.program.ExpireAfter)
+			earned.ExpiresAt = &expiresAt
+		}
+		entries = append(entries, earned)
+	}
+	err := s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.loyaltyRepository.LockClient(ctx, rent.ClientID); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if _, err := s.loyaltyRepository.AddEntry(ctx, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "loyalty points earned", slog.String("client_id", rent.ClientID),
+		slog.Int("rent_id", rent.ID), slog.Int("points", points), slog.Int("unused", unused))
+	return nil
+}
+
+func (s LoyaltyServiceImpl) Adjust(
+	ctx context.Context, clientId string, points int, note string) (entry models.LoyaltyEntry, err error) {
+	err = s.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		balance, err := s.expire(ctx, clientId)
+		if err != nil {
+			return err
+		}
+		if balance.Balance+points < 0 {
+			return LoyaltyError{Reason: fmt.Sprintf("exceed the balance of %d", balance.Balance)}
+		}
+		entry, err = s.loyaltyRepository.AddEntry(ctx, models.LoyaltyEntry{ClientID: clientId,
+			Kind: models.LoyaltyKindAdjust, Points: points, Note: note})
+		return err
+	})
+	if err != nil {
+		return models.LoyaltyEntry{}, err
+	}
+	s.logger.InfoContext(ctx, "loyalty points adjusted", slog.String("client_id", clientId),
+		slog.Int("points", points), slog.String("note", note))
+	return entry, nil
+}
+
+// expire locks the points of the client and takes the earned points past their expiry off the balance.
+// Points are spent in the order they expire, so what is spent counts against the expired points first.
+func (s LoyaltyServiceImpl) expire(ctx context.Context, clientId string) (repository.LoyaltyBalance, error) {
+	if err := s.loyaltyRepository.LockClient(ctx, clientId); err != nil {
+		return repository.LoyaltyBalance{}, err
+	}
+	balance, err := s.loyaltyRepository.GetBalance(ctx, clientId, s.now())
+	if err != nil {
+		return balance, err
+	}
+	due := min(balance.Expirable-balance.Spent, balance.Balance)
+	if due <= 0 {
+		return balance, nil
+	}
+	if _, err = s.loyaltyRepository.AddEntry(ctx, models.LoyaltyEntry{ClientID: clientId,
+		Kind: models.LoyaltyKindExpire, Points: -due}); err != nil {
+		return balance, err
+	}
+	balance.Balance -= due
+	balance.Spent += due
+	return balance, nil
+}
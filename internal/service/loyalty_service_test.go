@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"car-rental/internal/logging"
+	"car-rental/internal/loyalty"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+)
+
+// loyaltyRepositories keep the entries in memory, a failed transaction restores them.
+type loyaltyRepositories struct {
+	entries []models.LoyaltyEntry
+}
+
+func (r *loyaltyRepositories) LockClient(ctx context.Context, clientId string) error {
+	return nil
+}
+
+func (r *loyaltyRepositories) AddEntry(ctx context.Context, entry models.LoyaltyEntry) (models.LoyaltyEntry, error) {
+	entry.ID = int64(len(r.entries) + 1)
+	r.entries = append(r.entries, entry)
+	return entry, nil
+}
+
+func (r *loyaltyRepositories) GetBalance(
+	ctx context.Context, clientId string, now time.Time) (repository.LoyaltyBalance, error) {
+	var balance repository.LoyaltyBalance
+	for _, entry := range r.entries {
+		if entry.ClientID != clientId {
+			continue
+		}
+		balance.Balance += entry.Points
+		if entry.Kind == models.LoyaltyKindEarn {
+			balance.Earned += entry.Points
+			if entry.ExpiresAt != nil && !entry.ExpiresAt.After(now) {
+				balance.Expirable += entry.Points
+			}
+		}
+		if entry.Points < 0 {
+			balance.Spent -= entry.Points
+		}
+	}
+	return balance, nil
+}
+
+func (r *loyaltyRepositories) FindEntries(ctx context.Context, clientId string, limit int) (
+	[]models.LoyaltyEntry, error) {
+	var entries []models.LoyaltyEntry
+	for i := len(r.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		if r.entries[i].ClientID == clientId {
+			entries = append(entries, r.entries[i])
+		}
+	}
+	return entries, nil
+}
+
+func (r *loyaltyRepositories) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	entries := r.entries
+	if err := fn(ctx); err != nil {
+		r.entries = entries
+		return err
+	}
+	return nil
+}
+
+func TestLoyaltyService(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC)
+	expired, valid := now.AddDate(0, 0, -9), now.AddDate(1, 0, 0)
+	repositories := &loyaltyRepositories{entries: []models.LoyaltyEntry{
+		{ID: 1, ClientID: "client-1", Kind: models.LoyaltyKindEarn, Points: 300, ExpiresAt: &expired},
+		{ID: 2, ClientID: "client-1", Kind: models.LoyaltyKindEarn, Points: 200, ExpiresAt: &valid},
+		{ID: 3, ClientID: "client-1", Kind: models.LoyaltyKindRedeem, Points: -100},
+		{ID: 4, ClientID: "client-2", Kind: models.LoyaltyKindEarn, Points: 900},
+	}}
+	program := loyalty.Program{EarnPercent: 10, PointValue: 1, ExpireAfter: 30 * 24 * time.Hour,
+		Tiers: []loyalty.Tier{{Name: "silver", MinPoints: 400}, {Name: "gold", MinPoints: 1000}}}
+	svc := NewLoyaltyServiceImpl(repositories, repositories, program, logging.Discard())
+	svc.now = func() time.Time { return now }
+
+	// the 100 redeemed are spent from the expired 300 first, the other 200 expire
+	account, err := svc.Account(ctx, "client-1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 200 || account.Earned != 500 || account.Tier.Name != "silver" || len(account.Entries) != 4 ||
+		account.Entries[0].Kind != models.LoyaltyKindExpire || account.Entries[0].Points != -200 {
+		t.Errorf("want 200 points left after expiring 200 at silver, got %+v", account)
+	}
+	// expiring again takes nothing more
+	if account, _ = svc.Account(ctx, "client-1", 10); account.Balance != 200 || len(account.Entries) != 4 {
+		t.Errorf("want the expired points taken once, got %+v", account)
+	}
+
+	var loyaltyError LoyaltyError
+	rent := models.AutoRent{ID: 7, ClientID: "client-1", RedeemedPoints: 250}
+	if err = svc.Redeem(ctx, rent); !errors.As(err, &loyaltyError) {
+		t.Errorf("want redeeming more than the balance rejected, got %v", err)
+	}
+	rent.RedeemedPoints = 150
+	if err = svc.Redeem(ctx, rent); err != nil {
+		t.Fatal(err)
+	}
+
+	// 100 earned on the checkout expire after 30 days, the 20 redeemed but not used are credited back
+	if err = svc.Earn(ctx, models.AutoRent{ID: 7, ClientID: "client-1", Checkout: 1000}, 20); err != nil {
+		t.Fatal(err)
+	}
+	earned := repositories.entries[len(repositories.entries)-1]
+	if earned.Kind != models.LoyaltyKindEarn || earned.Points != 100 || earned.ExpiresAt == nil ||
+		!earned.ExpiresAt.Equal(now.AddDate(0, 0, 30)) || *earned.RentID != 7 {
+		t.Errorf("want 100 points earned on rent 7, got %+v", earned)
+	}
+	if account, _ = svc.Account(ctx, "client-1", 10); account.Balance != 170 || account.Earned != 600 {
+		t.Errorf("want 170 points of 600 earned, got %+v", account)
+	}
+
+	if _, err = svc.Adjust(ctx, "client-1", -171, "correction"); !errors.As(err, &loyaltyError) {
+		t.Errorf("want an adjustment below 0 rejected, got %v", err)
+	}
+	if _, err = svc.Adjust(ctx, "client-1", -170, "correction"); err != nil {
+		t.Fatal(err)
+	}
+	if tier, _ := svc.Tier(ctx, "client-2"); tier.Name != "silver" {
+		t.Errorf("want client-2 at silver, got %+v", tier)
+	}
+}
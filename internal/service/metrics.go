@@ -0,0 +1,14 @@
+package service
+
+// MetricsRecorder is told about the business events of the rental flow.
+type MetricsRecorder interface {
+	RentalStarted(autoType string)
+	RentalClosed(autoType string, checkout int, penalty bool)
+	ThresholdRejected(autoType string)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) RentalStarted(string)           {}
+func (noopMetrics) RentalClosed(string, int, bool) {}
+func (noopMetrics) ThresholdRejected(string)       {}
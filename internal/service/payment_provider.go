@@ -0,0 +1,31 @@
+package service
+
+import "context"
+
+// PaymentProvider moves the money of the rents, payments.Memory stands in for a gateway.
+// Amounts are in the unit of the checkout. The reference names the operation, so a provider
+// returns the same authorization for a retried one.
+type PaymentProvider interface {
+	// Authorize holds the amount on the payment method of the client and returns the authorization id
+	Authorize(ctx context.Context, clientId string, amount int, reference string) (string, error)
+	// Capture collects up to the authorized amount and releases the rest of the hold
+	Capture(ctx context.Context, authorizationId string, amount int) error
+	// Refund pays back up to the captured amount
+	Refund(ctx context.Context, authorizationId string, amount int) error
+	// Void releases an authorization that wasn't captured
+	Void(ctx context.Context, authorizationId string) error
+}
+
+// PaymentError is returned when the payment provider fails an operation of a rent, the rent is left as it was.
+type PaymentError struct {
+	Op  string
+	Err error
+}
+
+func (e PaymentError) Error() string {
+	return "payment " + e.Op + " failed: " + e.Err.Error()
+}
+
+func (e PaymentError) Unwrap() error {
+	return e.Err
+}
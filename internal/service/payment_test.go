@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"car-rental/internal/models"
+	"car-rental/internal/payments"
+	"car-rental/internal/repository"
+)
+
+// paymentRepositories keep the rents and payments in memory, a failed transaction restores them.
+type paymentRepositories struct {
+	repository.AutoRepository
+	repository.RentalRepository
+	repository.CommissionRepository
+	deposit  int
+	rents    []models.AutoRent
+	payments []models.Payment
+}
+
+func (r *paymentRepositories) GetAutoById(ctx context.Context, autoId string) (models.Auto, error) {
+	return models.Auto{ID: autoId, Type: "standard", Availability: true}, nil
+}
+
+func (r *paymentRepositories) BindAuto(ctx context.Context, _ string) error {
+	return nil
+}
+
+func (r *paymentRepositories) ReleaseAuto(ctx context.Context, _ string) error {
+	return nil
+}
+
+func (r *paymentRepositories) GetCommissionsByType(ctx context.Context, _ string) []models.Commission {
+	return []models.Commission{
+		{Type: commissionTypeDaily, Value: 200},
+		{Type: commissionTypeAgreement, Value: 200},
+		{Type: commissionTypeDeposit, Value: r.deposit},
+	}
+}
+
+func (r *paymentRepositories) GetThresholdsByAutoType(ctx context.Context, _ string) (models.RentThreshold, error) {
+	return models.RentThreshold{}, nil
+}
+
+func (r *paymentRepositories) GetRentByAuto(ctx context.Context, autoId string) (models.AutoRent, error) {
+	for _, rent := range r.rents {
+		if rent.AutoID == autoId && rent.Status == models.RentStatusActive {
+			return rent, nil
+		}
+	}
+	return models.AutoRent{}, nil
+}
+
+func (r *paymentRepositories) GetRentById(ctx context.Context, rentId int) (models.AutoRent, error) {
+	return r.rents[rentId-1], nil
+}
+
+func (r *paymentRepositories) BindRent(ctx context.Context, rent models.AutoRent, days int) (models.AutoRent, error) {
+	rent.ID = len(r.rents) + 1
+	rent.StartDate = time.Date(2023, time.October, 2, 0, 0, 0, 0, time.UTC)
+	rent.EndDate = rent.StartDate.AddDate(0, 0, days)
+	rent.Status = models.RentStatusActive
+	r.rents = append(r.rents, rent)
+	return rent, nil
+}
+
+func (r *paymentRepositories) CloseRent(ctx context.Context, rent models.AutoRent) (bool, error) {
+	if r.rents[rent.ID-1].Status != models.RentStatusActive {
+		return false, nil
+	}
+	rent.Status = models.RentStatusClosed
+	r.rents[rent.ID-1] = rent
+	return true, nil
+}
+
+func (r *paymentRepositories) CreatePayment(ctx context.Context, payment models.Payment) (models.Payment, error) {
+	payment.ID = int64(len(r.payments) + 1)
+	r.payments = append(r.payments, payment)
+	return payment, nil
+}
+
+func (r *paymentRepositories) FindPaymentsByRent(ctx context.Context, rentId int) ([]models.Payment, error) {
+	var payments []models.Payment
+	for _, payment := range r.payments {
+		if payment.RentID == rentId {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+func (r *paymentRepositories) UpdatePayment(ctx context.Context, payment models.Payment) error {
+	r.payments[payment.ID-1] = payment
+	return nil
+}
+
+func (r *paymentRepositories) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	rents := append([]models.AutoRent(nil), r.rents...)
+	payments := append([]models.Payment(nil), r.payments...)
+	if err := fn(ctx); err != nil {
+		r.rents, r.payments = rents, payments
+		return err
+	}
+	return nil
+}
+
+func newPaymentTest(deposit int) (*paymentRepositories, *payments.Memory, *RentalServiceImpl) {
+	repositories := &paymentRepositories{deposit: deposit}
+	provider := payments.NewMemory()
+	svc := NewRentalServiceImpl(repositories, repositories, repositories,
+		WithPayments(repositories, provider, repositories))
+	return repositories, provider, svc
+}
+
+// the rent of 3 days at 200 and the agreement of 200 checks out 800
+var paymentReturn = RentalReturn{ReturnDate: time.Date(2023, time.October, 4, 0, 0, 0, 0, time.UTC)}
+
+func TestPaymentsCaptureCheckoutFromDeposit(t *testing.T) {
+	ctx := context.Background()
+	repositories, provider, svc := newPaymentTest(1000)
+	rent, err := svc.BindAuto(ctx, "MINI", 5, "client-1", RentalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repositories.payments) != 1 || repositories.payments[0].Kind != models.PaymentKindDeposit ||
+		repositories.payments[0].Authorized != 1000 {
+		t.Fatalf("want the deposit authorized, got %+v", repositories.payments)
+	}
+	if _, err = svc.ReturnRent(ctx, rent.ID, paymentReturn); err != nil {
+		t.Fatal(err)
+	}
+	deposit := repositories.payments[0]
+	authorization, _ := provider.Authorization(deposit.AuthorizationID)
+	if deposit.Status != models.PaymentStatusCaptured || deposit.Captured != 800 ||
+		authorization.Status != payments.StatusCaptured || authorization.Captured != 800 {
+		t.Errorf("want 800 of the deposit captured, got %+v and %+v", deposit, authorization)
+	}
+}
+
+func TestPaymentsChargeWhatTheDepositDoesNotCover(t *testing.T) {
+	ctx := context.Background()
+	repositories, provider, svc := newPaymentTest(500)
+	rent, err := svc.BindAuto(ctx, "MINI", 5, "client-1", RentalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = svc.ReturnRent(ctx, rent.ID, paymentReturn); err != nil {
+		t.Fatal(err)
+	}
+	if len(repositories.payments) != 2 {
+		t.Fatalf("want the deposit and a charge, got %+v", repositories.payments)
+	}
+	deposit, charge := repositories.payments[0], repositories.payments[1]
+	if deposit.Captured != 500 || charge.Kind != models.PaymentKindCharge || charge.Authorized != 300 ||
+		charge.Captured != 300 || charge.Status != models.PaymentStatusCaptured {
+		t.Errorf("want 500 of the deposit and 300 charged, got %+v and %+v", deposit, charge)
+	}
+	if authorization, _ := provider.Authorization(charge.AuthorizationID); authorization.Captured != 300 {
+		t.Errorf("want the charge captured, got %+v", authorization)
+	}
+}
+
+func TestPaymentsDeclinedLeaveTheRentAsItWas(t *testing.T) {
+	ctx := context.Background()
+	repositories, provider, svc := newPaymentTest(500)
+	provider.Decline("client-2")
+	var paymentError PaymentError
+	if _, err := svc.BindAuto(ctx, "MINI", 5, "client-2", RentalOptions{}); !errors.As(err, &paymentError) ||
+		!errors.Is(err, payments.ErrDeclined) {
+		t.Fatalf("want the declined deposit reported, got %v", err)
+	}
+	if len(repositories.rents) != 0 || len(repositories.payments) != 0 {
+		t.Fatalf("want the bind rolled back, got %+v and %+v", repositories.rents, repositories.payments)
+	}
+
+	// the charge is declined before anything is captured
+	rent, err := svc.BindAuto(ctx, "MINI", 5, "client-1", RentalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Decline("client-1")
+	if _, err = svc.ReturnRent(ctx, rent.ID, paymentReturn); !errors.As(err, &paymentError) {
+		t.Fatalf("want the declined charge reported, got %v", err)
+	}
+	deposit := repositories.payments[0]
+	authorization, _ := provider.Authorization(deposit.AuthorizationID)
+	if repositories.rents[0].Status != models.RentStatusActive || len(repositories.payments) != 1 ||
+		deposit.Status != models.PaymentStatusAuthorized || authorization.Status != payments.StatusAuthorized {
+		t.Errorf("want the return rolled back with the deposit held, got %+v, %+v and %+v",
+			repositories.rents[0], repositories.payments, authorization)
+	}
+}
+
+// staleRents reads every rent as active, as a return racing another return of the rent does
+type staleRents struct {
+	*paymentRepositories
+}
+
+func (r staleRents) GetRentById(ctx context.Context, rentId int) (models.AutoRent, error) {
+	rent := r.rents[rentId-1]
+	rent.Status = models.RentStatusActive
+	return rent, nil
+}
+
+func TestPaymentsCapturedOnceByConcurrentReturns(t *testing.T) {
+	ctx := context.Background()
+	repositories, provider, _ := newPaymentTest(1000)
+	svc := NewRentalServiceImpl(repositories, staleRents{repositories}, repositories,
+		WithPayments(repositories, provider, repositories))
+	rent, err := svc.BindAuto(ctx, "MINI", 5, "client-1", RentalOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = svc.ReturnRent(ctx, rent.ID, paymentReturn); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = svc.ReturnRent(ctx, rent.ID, paymentReturn); err == nil || err.Error() != AlreadyClosedError {
+		t.Fatalf("want %s, got %v", AlreadyClosedError, err)
+	}
+	if deposit := repositories.payments[0]; len(repositories.payments) != 1 || deposit.Captured != 800 {
+		t.Errorf("want 800 of the deposit captured once, got %+v", repositories.payments)
+	}
+}
@@ -1,14 +1,71 @@
 package service
 
 import (
+	"context"
 	"time"
 
 	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"car-rental/internal/tax"
 )
 
+// RentalReturn describes how an auto was handed back.
+type RentalReturn struct {
+	// ReturnDate is from the day the rent started to today, today when zero
+	ReturnDate time.Time
+	Odometer   int
+	Notes      string
+	// Damage is the repair cost of the damage found, charged up to the excess of the insurance of the rent
+	Damage int
+}
+
+// RentalOptions are what the client asks for when binding or quoting a rent, none by default.
+type RentalOptions struct {
+	// PromoCode is applied to the checkout when it isn't empty
+	PromoCode string
+	// RedeemPoints of the loyalty balance of the client are taken off the checkout
+	RedeemPoints int
+	// Extras are the quantities of the extras rented with the auto, by extra id
+	Extras map[string]int
+	// Insurance is the code of the insurance package of the auto type, none when empty
+	Insurance string
+}
+
+// ExtraAvailability is an extra of the catalogue with the quantity left at a location now,
+// nil for extras that aren't stocked or when no location is asked for.
+type ExtraAvailability struct {
+	models.Extra
+	Available *int
+}
+
 type RentalService interface {
-	GetAvailableAutoByType(autoType string) ([]models.Auto, error)
-	BindAuto(autoId string, days int) error
-	ReleaseAuto(autoId string, releaseDate time.Time) (checkout int, err error)
-	GetCurrentCommission(autoId string, calculationDate time.Time) (commission int, insurance int, err error)
+	GetAutoTypes(ctx context.Context) ([]string, error)
+	GetAvailableAutoByType(ctx context.Context, autoType string) ([]models.Auto, error)
+	// ListAutos returns a page of the catalogue and the cursor of the next page, nil on the last page
+	ListAutos(ctx context.Context, query repository.AutoQuery) ([]repository.AutoListing, *repository.AutoCursor, error)
+	GetRentByAuto(ctx context.Context, autoId string) (models.AutoRent, error)
+	GetRent(ctx context.Context, rentId int) (models.AutoRent, error)
+	// BindAuto rents the auto to the client with the options applied to the checkout
+	BindAuto(ctx context.Context, autoId string, days int, clientId string, options RentalOptions) (
+		models.AutoRent, error)
+	// ReturnRent closes the rent, calculates the checkout and makes the auto available again
+	ReturnRent(ctx context.Context, rentId int, rentalReturn RentalReturn) (models.AutoRent, error)
+	ReleaseAuto(ctx context.Context, autoId string, releaseDate time.Time) (checkout int, err error)
+	// GetCurrentCommission is what the active rent of the auto costs so far, taxed in the location of the auto,
+	// with its insurance apart
+	GetCurrentCommission(ctx context.Context, autoId string, calculationDate time.Time) (
+		commission tax.Totals, insurance tax.Totals, err error)
+	// Quote prices a rent of the auto without binding it, returnDate is the last day the auto is kept.
+	// The points to redeem are priced without checking a balance and the extras without reserving them.
+	// The checkout includes the insurance.
+	Quote(ctx context.Context, autoId string, days int, startDate time.Time, returnDate time.Time,
+		options RentalOptions) (checkout int, insurance int, err error)
+	// ListInsurance returns the insurance packages of the auto type, the cheapest first
+	ListInsurance(ctx context.Context, autoType string) ([]models.InsurancePackage, error)
+	// ListExtras returns the catalogue of extras with what is left of them at the location
+	ListExtras(ctx context.Context, location string) ([]ExtraAvailability, error)
+	// RetireAuto takes an auto that isn't rented out of the fleet
+	RetireAuto(ctx context.Context, autoId string) error
+	// SetCommission replaces the commission of the auto type
+	SetCommission(ctx context.Context, commission models.Commission) error
 }
@@ -1,44 +1,259 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math"
+	"slices"
 	"time"
 
+	"car-rental/internal/events"
+	"car-rental/internal/logging"
+	"car-rental/internal/loyalty"
 	"car-rental/internal/models"
+	"car-rental/internal/pricing"
 	"car-rental/internal/repository"
+	"car-rental/internal/tax"
+	"car-rental/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 const (
-	commissionTypeDaily      = "daily"
-	commissionTypeAgreement  = "agreement"
-	commissionTypeWeekend    = "weekend"
-	commissionTypePenalty    = "penalty"
-	commissionTypeInsurance  = "insurance"
-	NotFoundError            = "record not found"
-	AlreadyRentedError       = "auto is already rented"
-	ThresholdValidationError = "days should be between min and max threshold"
+	commissionTypeDaily     = "daily"
+	commissionTypeAgreement = "agreement"
+	commissionTypeWeekend   = "weekend"
+	commissionTypePenalty   = "penalty"
+	commissionTypeInsurance = "insurance"
+	commissionTypeDeposit   = "deposit"
+	commissionTypeDiscount  = "discount"
+	commissionTypeExtra     = "extra"
+	commissionTypeDamage    = "damage"
+	NotFoundError           = "record not found"
+	AlreadyRentedError      = "auto is already rented"
+	AlreadyClosedError      = "rent is already closed"
+	RetiredError            = "auto is retired"
+	defaultPageSize         = 20
 )
 
+// ThresholdError is returned when the rent days are out of the rent_threshold of the auto type.
+type ThresholdError struct {
+	Min int
+	Max int
+}
+
+func (e ThresholdError) Error() string {
+	return fmt.Sprint("days should be between ", e.Min, " and ", e.Max)
+}
+
+// PromoCodeError is returned when the promo code of a rent can't be applied, Reason says why.
+type PromoCodeError struct {
+	Reason string
+}
+
+func (e PromoCodeError) Error() string {
+	return "promo code " + e.Reason
+}
+
+// ExtraError is returned when an extra asked for can't be rented with the auto, Reason says why.
+type ExtraError struct {
+	ExtraID string
+	Reason  string
+}
+
+func (e ExtraError) Error() string {
+	return "extra " + e.ExtraID + " " + e.Reason
+}
+
+// InsuranceError is returned when the insurance package asked for can't be chosen, Reason says why.
+type InsuranceError struct {
+	Reason string
+}
+
+func (e InsuranceError) Error() string {
+	return "insurance " + e.Reason
+}
+
+// ReturnDateError is returned when an auto is returned before its rent started or after today, Reason says which.
+type ReturnDateError struct {
+	Reason string
+}
+
+func (e ReturnDateError) Error() string {
+	return "return date " + e.Reason
+}
+
 type RentalServiceImpl struct {
 	autoRepository       repository.AutoRepository
 	rentalRepository     repository.RentalRepository
 	commissionRepository repository.CommissionRepository
+	metrics              MetricsRecorder
+	logger               *slog.Logger
+	tracer               trace.Tracer
+	transactor           repository.Transactor
+	// outbox is nil when events aren't recorded
+	outbox repository.OutboxRepository
+	// invoices is nil when closed rents aren't invoiced
+	invoices InvoiceService
+	taxes    tax.Rates
+	// payments is nil when no money is collected
+	payments          PaymentProvider
+	paymentRepository repository.PaymentRepository
+	// discounts is nil when no discounts are given
+	discounts repository.DiscountRepository
+	// seasons is nil when the daily prices don't change by season
+	seasons repository.SeasonRepository
+	demand  pricing.Demand
+	// loyalty is nil when clients don't earn points
+	loyalty LoyaltyService
+	// extras is nil when no extras are rented with the autos
+	extras repository.ExtraRepository
+	// insurance is nil when no insurance packages are sold
+	insurance repository.InsuranceRepository
+}
+
+// Option configures the optional collaborators of RentalServiceImpl.
+type Option func(*RentalServiceImpl)
+
+func WithMetrics(metrics MetricsRecorder) Option {
+	return func(a *RentalServiceImpl) {
+		a.metrics = metrics
+	}
+}
+
+// WithLogger logs the rentals started and closed and the autos retired, the audit trail of the rental flow.
+func WithLogger(logger *slog.Logger) Option {
+	return func(a *RentalServiceImpl) {
+		a.logger = logger
+	}
+}
+
+// WithTracer starts a span for every method, with the auto, rent and checkout as attributes.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(a *RentalServiceImpl) {
+		a.tracer = tracer
+	}
+}
+
+// WithEvents makes every change a transaction that also stores its domain events in the outbox.
+func WithEvents(transactor repository.Transactor, outbox repository.OutboxRepository) Option {
+	return func(a *RentalServiceImpl) {
+		a.transactor = transactor
+		a.outbox = outbox
+	}
+}
+
+// WithInvoices issues the invoice of every closed rent, in the transaction closing it when there is one.
+func WithInvoices(invoices InvoiceService) Option {
+	return func(a *RentalServiceImpl) {
+		a.invoices = invoices
+	}
+}
+
+// WithTaxes adds the tax rates of the location of the auto to the checkout and the current commission,
+// without it nothing is taxed.
+func WithTaxes(taxes tax.Rates) Option {
+	return func(a *RentalServiceImpl) {
+		a.taxes = taxes
+	}
+}
+
+// WithPayments authorizes the deposit of the auto type when an auto is bound and captures the checkout
+// when it is returned, in the transaction of the change so a failed payment leaves the rent as it was.
+func WithPayments(transactor repository.Transactor, payments PaymentProvider,
+	paymentRepository repository.PaymentRepository) Option {
+	return func(a *RentalServiceImpl) {
+		a.transactor = transactor
+		a.payments = payments
+		a.paymentRepository = paymentRepository
+	}
+}
+
+// WithDiscounts takes the long rental tiers of the auto type and the promo code of the rent off the rent days.
+func WithDiscounts(discounts repository.DiscountRepository) Option {
+	return func(a *RentalServiceImpl) {
+		a.discounts = discounts
+	}
+}
+
+// WithSeasons prices each day of a rent at the percent of the season it falls in.
+func WithSeasons(seasons repository.SeasonRepository) Option {
+	return func(a *RentalServiceImpl) {
+		a.seasons = seasons
+	}
+}
+
+// WithDemand raises the daily prices of a rent by the share of the autos of its type rented when it is bound,
+// the rent keeps the percent until it is returned.
+func WithDemand(demand pricing.Demand) Option {
+	return func(a *RentalServiceImpl) {
+		a.demand = demand
+	}
+}
+
+// WithLoyalty earns clients points on their checkouts, lets them redeem points when binding an auto
+// and applies the benefits of their tier to the checkout.
+func WithLoyalty(loyalty LoyaltyService) Option {
+	return func(a *RentalServiceImpl) {
+		a.loyalty = loyalty
+	}
+}
+
+// WithExtras rents the extras of the catalogue with the autos, reserving the stocked ones at the location
+// of the auto for the days of the rent, and charges them on the checkout.
+func WithExtras(extras repository.ExtraRepository) Option {
+	return func(a *RentalServiceImpl) {
+		a.extras = extras
+	}
+}
+
+// WithInsurance lets clients choose an insurance package of the auto type when binding an auto, priced per day
+// on the checkout and capping the damage charged at its excess.
+func WithInsurance(insurance repository.InsuranceRepository) Option {
+	return func(a *RentalServiceImpl) {
+		a.insurance = insurance
+	}
 }
 
 func NewRentalServiceImpl(autoRepository repository.AutoRepository,
 	rentalRepository repository.RentalRepository,
-	commissionRepository repository.CommissionRepository) *RentalServiceImpl {
-	return &RentalServiceImpl{
+	commissionRepository repository.CommissionRepository,
+	options ...Option) *RentalServiceImpl {
+	a := &RentalServiceImpl{
 		autoRepository:       autoRepository,
 		rentalRepository:     rentalRepository,
 		commissionRepository: commissionRepository,
+		metrics:              noopMetrics{},
+		logger:               logging.Discard(),
+		tracer:               tracing.NoopTracer(),
+		transactor:           noTransaction{},
+	}
+	for _, option := range options {
+		option(a)
 	}
+	return a
 }
 
-func (a RentalServiceImpl) GetAvailableAutoByType(autoType string) ([]models.Auto, error) {
-	autos, err := a.autoRepository.GetAvailableAutoByType(autoType)
+func (a RentalServiceImpl) GetAutoTypes(ctx context.Context) (_ []string, err error) {
+	ctx, span := a.startSpan(ctx, "GetAutoTypes")
+	defer func() { endSpan(span, err) }()
+	autoTypes, err := a.autoRepository.GetAutoTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(autoTypes))
+	for _, autoType := range autoTypes {
+		ids = append(ids, autoType.ID)
+	}
+	return ids, nil
+}
+
+func (a RentalServiceImpl) GetAvailableAutoByType(ctx context.Context, autoType string) (_ []models.Auto, err error) {
+	ctx, span := a.startSpan(ctx, "GetAvailableAutoByType", attrAutoType.String(autoType))
+	defer func() { endSpan(span, err) }()
+	autos, err := a.autoRepository.GetAvailableAutoByType(ctx, autoType)
 	if err != nil {
 		return nil, err
 	}
@@ -48,98 +263,1011 @@ func (a RentalServiceImpl) GetAvailableAutoByType(autoType string) ([]models.Aut
 	return autos, nil
 }
 
-func (a RentalServiceImpl) BindAuto(autoId string, days int) error {
-	rent, err := a.rentalRepository.GetRentByAuto(autoId)
+func (a RentalServiceImpl) ListAutos(ctx context.Context, query repository.AutoQuery) (
+	_ []repository.AutoListing, _ *repository.AutoCursor, err error) {
+	ctx, span := a.startSpan(ctx, "ListAutos", attrAutoType.String(query.Type))
+	defer func() { endSpan(span, err) }()
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	// one more than requested tells whether there is a next page
+	query.Limit = limit + 1
+	autos, err := a.autoRepository.FindAutos(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(autos) <= limit {
+		return autos, nil, nil
+	}
+	autos = autos[:limit]
+	last := autos[limit-1]
+	return autos, &repository.AutoCursor{ID: last.ID, Price: last.DailyPrice}, nil
+}
+
+func (a RentalServiceImpl) GetRentByAuto(ctx context.Context, autoId string) (_ models.AutoRent, err error) {
+	ctx, span := a.startSpan(ctx, "GetRentByAuto", attrAutoID.String(autoId))
+	defer func() { endSpan(span, err) }()
+	rent, err := a.rentalRepository.GetRentByAuto(ctx, autoId)
+	if err != nil {
+		return rent, err
+	}
+	return rent, nil
+}
+
+func (a RentalServiceImpl) GetRent(ctx context.Context, rentId int) (_ models.AutoRent, err error) {
+	ctx, span := a.startSpan(ctx, "GetRent", attrRentID.Int(rentId))
+	defer func() { endSpan(span, err) }()
+	rent, err := a.rentalRepository.GetRentById(ctx, rentId)
+	if err != nil {
+		return rent, err
+	}
+	return rent, nil
+}
+
+func (a RentalServiceImpl) BindAuto(
+	ctx context.Context, autoId string, days int, clientId string, options RentalOptions) (
+	_ models.AutoRent, err error) {
+	ctx, span := a.startSpan(ctx, "BindAuto", attrAutoID.String(autoId), attrRentDays.Int(days))
+	defer func() { endSpan(span, err) }()
+	rent, err := a.rentalRepository.GetRentByAuto(ctx, autoId)
 	if err != nil && err.Error() != NotFoundError {
-		return err
+		return models.AutoRent{}, err
 	}
 	if rent != (models.AutoRent{}) {
-		return errors.New("auto is already rented")
+		return models.AutoRent{}, errors.New("auto is already rented")
+	}
+	auto, err := a.autoRepository.GetAutoById(ctx, autoId)
+	if err != nil {
+		return models.AutoRent{}, err
+	}
+	span.SetAttributes(attrAutoType.String(auto.Type))
+	if auto.RetiredAt != nil {
+		return models.AutoRent{}, errors.New(NotFoundError)
+	}
+	err = a.checkThreshold(ctx, auto.Type, days)
+	if err != nil {
+		var thresholdError ThresholdError
+		if errors.As(err, &thresholdError) {
+			a.metrics.ThresholdRejected(auto.Type)
+			a.logger.InfoContext(ctx, "rental rejected by threshold",
+				slog.String("auto_id", autoId), slog.String("auto_type", auto.Type), slog.Int("days", days))
+		}
+		return models.AutoRent{}, err
+	}
+	if options.PromoCode != "" {
+		if err = a.checkPromoCode(ctx, options.PromoCode, auto.Type, time.Now()); err != nil {
+			return models.AutoRent{}, err
+		}
+	}
+	if err = a.checkRedeemPoints(options.RedeemPoints); err != nil {
+		return models.AutoRent{}, err
 	}
-	auto, err := a.autoRepository.GetAutoById(autoId)
+	extras, err := a.chooseExtras(ctx, options.Extras)
 	if err != nil {
+		return models.AutoRent{}, err
+	}
+	insurance, err := a.choosePackage(ctx, auto.Type, options.Insurance)
+	if err != nil {
+		return models.AutoRent{}, err
+	}
+	demand, err := a.demandPercent(ctx, auto.Type)
+	if err != nil {
+		return models.AutoRent{}, err
+	}
+	draft := models.AutoRent{AutoID: autoId, ClientID: clientId, PromoCode: options.PromoCode, DemandPercent: demand,
+		RedeemedPoints: options.RedeemPoints, Insurance: insurance.Code, InsuranceDailyPrice: insurance.DailyPrice,
+		InsuranceRentalPrice: insurance.RentalPrice, InsuranceExcess: insurance.Excess}
+	if a.loyalty != nil {
+		tier, err := a.loyalty.Tier(ctx, clientId)
+		if err != nil {
+			return models.AutoRent{}, err
+		}
+		draft.LoyaltyTier = tier.Name
+	}
+	deposit := 0
+	if a.payments != nil {
+		deposit = depositCommission(a.commissionRepository.GetCommissionsByType(ctx, auto.Type))
+	}
+	var steps paymentSteps
+	err = a.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		rent, err = a.rentalRepository.BindRent(ctx, draft, days)
+		if err != nil {
+			return err
+		}
+		if options.PromoCode != "" {
+			redeemed, err := a.discounts.RedeemPromoCode(ctx, options.PromoCode)
+			if err != nil {
+				return err
+			}
+			if !redeemed {
+				return PromoCodeError{Reason: "is used up"}
+			}
+		}
+		if rent.RedeemedPoints > 0 {
+			if err = a.loyalty.Redeem(ctx, rent); err != nil {
+				return err
+			}
+		}
+		if err = a.reserveExtras(ctx, rent, auto.Location, extras); err != nil {
+			return err
+		}
+		err = a.autoRepository.BindAuto(ctx, autoId)
+		if err != nil {
+			a.logger.ErrorContext(ctx, "marking auto as rented failed",
+				slog.Int("rent_id", rent.ID), slog.String("auto_id", autoId), slog.Any("error", err))
+			return err
+		}
+		if deposit > 0 {
+			if _, err = a.authorize(ctx, rent, models.PaymentKindDeposit, deposit, &steps); err != nil {
+				return err
+			}
+		}
+		return a.recordEvents(ctx,
+			events.RentalStarted{RentID: rent.ID, AutoID: autoId, AutoType: auto.Type, ClientID: clientId,
+				Days: days, StartDate: rent.StartDate, EndDate: rent.EndDate},
+			events.AutoStatusChanged{AutoID: autoId, AutoType: auto.Type,
+				From: models.AutoStatusAvailable, To: models.AutoStatusRented})
+	})
+	if err != nil {
+		a.undoPayments(ctx, rent.ID, steps)
+		return models.AutoRent{}, err
+	}
+	span.SetAttributes(attrRentID.Int(rent.ID))
+	a.metrics.RentalStarted(auto.Type)
+	a.logger.InfoContext(ctx, "rental started",
+		slog.Int("rent_id", rent.ID), slog.String("auto_id", autoId), slog.String("auto_type", auto.Type),
+		slog.String("client_id", clientId), slog.Int("days", days))
+	return rent, nil
+}
+
+// demandPercent is the percent of the daily prices charged to a rent of the auto type bound now,
+// 0 without demand levels.
+func (a RentalServiceImpl) demandPercent(ctx context.Context, autoType string) (int, error) {
+	if len(a.demand) == 0 {
+		return 0, nil
+	}
+	fleet, rented, err := a.autoRepository.CountFleet(ctx, autoType)
+	if err != nil {
+		return 0, err
+	}
+	return a.demand.Percent(rented, fleet), nil
+}
+
+// seasonsOf returns the seasons pricing the auto type, none without WithSeasons.
+func (a RentalServiceImpl) seasonsOf(ctx context.Context, autoType string) ([]models.Season, error) {
+	if a.seasons == nil {
+		return nil, nil
+	}
+	return a.seasons.GetSeasons(ctx, autoType)
+}
+
+// checkPromoCode tells whether the promo code can be applied to a rent of the auto type at now,
+// its uses are counted when the rent is bound.
+func (a RentalServiceImpl) checkPromoCode(ctx context.Context, code string, autoType string, now time.Time) error {
+	if a.discounts == nil {
+		return PromoCodeError{Reason: "is unknown"}
+	}
+	promo, err := a.discounts.GetPromoCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return PromoCodeError{Reason: "is unknown"}
+		}
 		return err
 	}
-	threshold, err := a.rentalRepository.GetThresholdsByAutoType(auto.Type)
+	switch {
+	case promo.ValidFrom != nil && now.Before(*promo.ValidFrom):
+		return PromoCodeError{Reason: "is not valid yet"}
+	case promo.ValidUntil != nil && !now.Before(*promo.ValidUntil):
+		return PromoCodeError{Reason: "has expired"}
+	case promo.MaxUses != 0 && promo.Uses >= promo.MaxUses:
+		return PromoCodeError{Reason: "is used up"}
+	case len(promo.AutoTypes) != 0 && !slices.Contains(promo.AutoTypes, autoType):
+		return PromoCodeError{Reason: "is not valid for auto type " + autoType}
+	}
+	return nil
+}
+
+// chooseExtras looks up the extras of the catalogue by id, in the order of their ids so their stock
+// is always locked in the same order.
+func (a RentalServiceImpl) chooseExtras(ctx context.Context, quantities map[string]int) ([]models.RentExtra, error) {
+	ids := make([]string, 0, len(quantities))
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	extras := make([]models.RentExtra, 0, len(ids))
+	for _, id := range ids {
+		if quantities[id] <= 0 {
+			return nil, ExtraError{ExtraID: id, Reason: "quantity must be positive"}
+		}
+		if a.extras == nil {
+			return nil, ExtraError{ExtraID: id, Reason: "is unknown"}
+		}
+		extra, err := a.extras.GetExtra(ctx, id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ExtraError{ExtraID: id, Reason: "is unknown"}
+			}
+			return nil, err
+		}
+		extras = append(extras, models.RentExtra{ExtraID: extra.ID, Name: extra.Name, Pricing: extra.Pricing,
+			Price: extra.Price, Quantity: quantities[id], Stocked: extra.Stocked})
+	}
+	return extras, nil
+}
+
+// reserveExtras reserves the extras for the days of the rent at the location, the stocked ones only
+// while the stock left lasts.
+func (a RentalServiceImpl) reserveExtras(
+	ctx context.Context, rent models.AutoRent, location string, extras []models.RentExtra) error {
+	for _, extra := range extras {
+		extra.RentID, extra.Location = rent.ID, location
+		extra.ReservedFrom, extra.ReservedUntil = rent.StartDate, rent.EndDate
+		if extra.Stocked {
+			stock, err := a.extras.LockStock(ctx, extra.ExtraID, location)
+			if err != nil {
+				return err
+			}
+			reserved, err := a.extras.CountReserved(ctx, extra.ExtraID, location, rent.StartDate, rent.EndDate)
+			if err != nil {
+				return err
+			}
+			if reserved+extra.Quantity > stock {
+				return ExtraError{ExtraID: extra.ExtraID,
+					Reason: fmt.Sprintf("has %d left at %s", max(stock-reserved, 0), location)}
+			}
+		}
+		if _, err := a.extras.ReserveExtra(ctx, extra); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// choosePackage looks up the insurance package of the auto type by code, none for an empty code.
+func (a RentalServiceImpl) choosePackage(
+	ctx context.Context, autoType string, code string) (models.InsurancePackage, error) {
+	if code == "" {
+		return models.InsurancePackage{}, nil
+	}
+	if a.insurance == nil {
+		return models.InsurancePackage{}, InsuranceError{Reason: code + " is unknown"}
+	}
+	insurance, err := a.insurance.GetPackage(ctx, autoType, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return insurance, InsuranceError{Reason: code + " is unknown for auto type " + autoType}
+		}
+		return insurance, err
+	}
+	return insurance, nil
+}
+
+// rentExtras returns the extras rented with the rent, none without WithExtras.
+func (a RentalServiceImpl) rentExtras(ctx context.Context, rentId int) ([]models.RentExtra, error) {
+	if a.extras == nil {
+		return nil, nil
+	}
+	return a.extras.GetRentExtras(ctx, rentId)
+}
+
+// checkRedeemPoints tells whether points can be redeemed, the balance is checked when they are.
+func (a RentalServiceImpl) checkRedeemPoints(points int) error {
+	if points == 0 {
+		return nil
+	}
+	if a.loyalty == nil || !a.loyalty.Program().Enabled() {
+		return LoyaltyError{Reason: "can't be redeemed, there is no loyalty programme"}
+	}
+	return nil
+}
+
+func (a RentalServiceImpl) checkThreshold(ctx context.Context, autoType string, days int) error {
+	threshold, err := a.rentalRepository.GetThresholdsByAutoType(ctx, autoType)
 	if err != nil {
 		return err
 	}
 	if threshold != (models.RentThreshold{}) {
 		if days < threshold.MinThreshold || days > threshold.MaxThreshold {
-			return errors.New(fmt.Sprint(
-				"days should be between ", threshold.MinThreshold, " and ", threshold.MaxThreshold))
+			return ThresholdError{Min: threshold.MinThreshold, Max: threshold.MaxThreshold}
 		}
 	}
-	err = a.rentalRepository.BindRent(autoId, days)
+	return nil
+}
+
+func (a RentalServiceImpl) ReturnRent(
+	ctx context.Context, rentId int, rentalReturn RentalReturn) (_ models.AutoRent, err error) {
+	ctx, span := a.startSpan(ctx, "ReturnRent", attrRentID.Int(rentId))
+	defer func() { endSpan(span, err) }()
+	rent, err := a.rentalRepository.GetRentById(ctx, rentId)
 	if err != nil {
-		return err
+		return rent, err
+	}
+	if rent.Status == models.RentStatusClosed {
+		return rent, errors.New(AlreadyClosedError)
+	}
+	if rentalReturn.ReturnDate.IsZero() {
+		rentalReturn.ReturnDate = time.Now()
+	}
+	if err = checkReturnDate(rent, rentalReturn.ReturnDate, time.Now()); err != nil {
+		return rent, err
 	}
-	err = a.autoRepository.BindAuto(autoId)
+	auto, err := a.autoRepository.GetAutoById(ctx, rent.AutoID)
 	if err != nil {
-		return err
+		return rent, err
+	}
+	span.SetAttributes(attrAutoID.String(auto.ID), attrAutoType.String(auto.Type))
+	commissions := a.commissionRepository.GetCommissionsByType(ctx, auto.Type)
+	seasons, err := a.seasonsOf(ctx, auto.Type)
+	if err != nil {
+		return rent, err
+	}
+	extras, err := a.rentExtras(ctx, rent.ID)
+	if err != nil {
+		return rent, err
+	}
+	rent.Damage = rentalReturn.Damage
+	breakdown := commissionBreakdown(rent, commissions, seasons, rentalReturn.ReturnDate, true)
+	breakdown.Extras = chargeExtras(extras, breakdown.Days)
+	if breakdown, err = a.discount(ctx, breakdown, rent, auto.Type); err != nil {
+		return rent, err
+	}
+	rent.Checkout = breakdown.Total()
+	rent.Discount = breakdown.TierDiscount + breakdown.PromoDiscount + breakdown.PointsDiscount
+	rent.CheckoutTax = a.taxes.Apply(auto.Location, breakdown.Amounts()).Tax
+	rent.ReturnedAt = &rentalReturn.ReturnDate
+	rent.Odometer = rentalReturn.Odometer
+	rent.Notes = rentalReturn.Notes
+	penalty := penaltyApplied(rent, commissions, rentalReturn.ReturnDate)
+	var steps paymentSteps
+	err = a.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		// closed first and only while active, so of concurrent returns one checks out and the others fail
+		closed, err := a.rentalRepository.CloseRent(ctx, rent)
+		if err != nil {
+			a.logger.ErrorContext(ctx, "closing rent failed",
+				slog.Int("rent_id", rent.ID), slog.String("auto_id", rent.AutoID), slog.Any("error", err))
+			return err
+		}
+		if !closed {
+			return errors.New(AlreadyClosedError)
+		}
+		if err = a.autoRepository.ReleaseAuto(ctx, rent.AutoID); err != nil {
+			return err
+		}
+		if len(extras) > 0 {
+			if err = a.extras.ReleaseExtras(ctx, rent.ID, rentalReturn.ReturnDate); err != nil {
+				return err
+			}
+		}
+		if a.loyalty != nil {
+			if err = a.loyalty.Earn(ctx, rent, rent.RedeemedPoints-breakdown.PointsUsed); err != nil {
+				return err
+			}
+		}
+		if a.invoices != nil {
+			lines := checkoutLines(rent.AutoID, breakdown, extras)
+			for i := range lines {
+				lines[i].TaxRate = a.taxes.Percent(auto.Location, lines[i].CommissionType)
+			}
+			if _, err = a.invoices.IssueInvoice(ctx, rent, auto.Type, lines); err != nil {
+				return err
+			}
+		}
+		err = a.recordEvents(ctx,
+			events.RentalClosed{RentID: rent.ID, AutoID: rent.AutoID, AutoType: auto.Type, ClientID: rent.ClientID,
+				ReturnedAt: rentalReturn.ReturnDate, Odometer: rent.Odometer, Checkout: rent.Checkout,
+				CheckoutTax: rent.CheckoutTax, Penalty: penalty},
+			events.AutoStatusChanged{AutoID: rent.AutoID, AutoType: auto.Type,
+				From: models.AutoStatusRented, To: models.AutoStatusAvailable})
+		if err != nil || a.payments == nil {
+			return err
+		}
+		return a.settle(ctx, rent, &steps)
+	})
+	if err != nil {
+		a.undoPayments(ctx, rent.ID, steps)
+		return rent, err
+	}
+	a.releasePayments(ctx, rent.ID, steps)
+	rent.Status = models.RentStatusClosed
+	span.SetAttributes(attrCheckout.Int(rent.Checkout), attrPenalty.Bool(penalty))
+	a.metrics.RentalClosed(auto.Type, rent.Checkout, penalty)
+	a.logger.InfoContext(ctx, "rental closed",
+		slog.Int("rent_id", rent.ID), slog.String("auto_id", rent.AutoID), slog.String("auto_type", auto.Type),
+		slog.String("client_id", rent.ClientID), slog.Int("checkout", rent.Checkout), slog.Bool("penalty", penalty))
+	return rent, nil
+}
+
+// checkReturnDate accepts return dates from the day the rent started to today.
+func checkReturnDate(rent models.AutoRent, returnDate time.Time, now time.Time) error {
+	day := returnDate.Truncate(24 * time.Hour)
+	switch {
+	case day.Before(rent.StartDate.Truncate(24 * time.Hour)):
+		return ReturnDateError{Reason: "is before the rent started"}
+	case day.After(now.Truncate(24 * time.Hour)):
+		return ReturnDateError{Reason: "is after today"}
 	}
 	return nil
 }
 
 func (a RentalServiceImpl) ReleaseAuto(
-	autoId string, releaseDate time.Time) (checkout int, err error) {
-	rent, err := a.rentalRepository.GetRentByAuto(autoId)
+	ctx context.Context, autoId string, releaseDate time.Time) (checkout int, err error) {
+	ctx, span := a.startSpan(ctx, "ReleaseAuto", attrAutoID.String(autoId))
+	defer func() { endSpan(span, err) }()
+	rent, err := a.rentalRepository.GetRentByAuto(ctx, autoId)
 	if err != nil {
 		return 0, err
 	}
 	if rent == (models.AutoRent{}) {
 		return 0, errors.New(NotFoundError)
 	}
-	auto, err := a.autoRepository.GetAutoById(autoId)
-	commissions := a.commissionRepository.GetCommissionsByType(auto.Type)
-	checkout, _ = calculateCommissions(rent, commissions, releaseDate, true)
-	err = a.autoRepository.ReleaseAuto(autoId)
+	rent, err = a.ReturnRent(ctx, rent.ID, RentalReturn{ReturnDate: releaseDate})
 	if err != nil {
 		return 0, err
 	}
-	err = a.rentalRepository.ReleaseRent(autoId)
+	span.SetAttributes(attrCheckout.Int(rent.Checkout))
+	return rent.Checkout, nil
+}
+
+func (a RentalServiceImpl) GetCurrentCommission(ctx context.Context, autoId string, calculationDate time.Time) (
+	commission tax.Totals, insurance tax.Totals, err error) {
+	ctx, span := a.startSpan(ctx, "GetCurrentCommission", attrAutoID.String(autoId))
+	defer func() { endSpan(span, err) }()
+	rent, err := a.rentalRepository.GetRentByAuto(ctx, autoId)
 	if err != nil {
-		return 0, err
+		return commission, insurance, err
+	}
+	if rent == (models.AutoRent{}) {
+		return commission, insurance, err
+	}
+	auto, err := a.autoRepository.GetAutoById(ctx, autoId)
+	if err != nil {
+		return commission, insurance, err
+	}
+	span.SetAttributes(attrAutoType.String(auto.Type))
+	necessaryCommissions := a.commissionRepository.GetCommissionsByType(ctx, auto.Type)
+	if necessaryCommissions == nil {
+		return commission, insurance, errors.New("no commissions found for auto type")
+	}
+	seasons, err := a.seasonsOf(ctx, auto.Type)
+	if err != nil {
+		return commission, insurance, err
+	}
+	extras, err := a.rentExtras(ctx, rent.ID)
+	if err != nil {
+		return commission, insurance, err
 	}
-	return checkout, nil
+	breakdown := commissionBreakdown(rent, necessaryCommissions, seasons, calculationDate.AddDate(0, 0, -1), false)
+	breakdown.Extras = chargeExtras(extras, breakdown.Days)
+	if breakdown, err = a.discount(ctx, breakdown, rent, auto.Type); err != nil {
+		return commission, insurance, err
+	}
+	amounts := breakdown.Amounts()
+	delete(amounts, commissionTypeInsurance)
+	commission = a.taxes.Apply(auto.Location, amounts)
+	insurance = a.taxes.Apply(auto.Location, map[string]int{commissionTypeInsurance: breakdown.Insurance})
+	span.SetAttributes(attrCheckout.Int(commission.Net))
+	return commission, insurance, nil
 }
 
-func (a RentalServiceImpl) GetCurrentCommission(autoId string, calculationDate time.Time) (
-	commission int, insurance int, err error) {
-	rent, err := a.rentalRepository.GetRentByAuto(autoId)
+func (a RentalServiceImpl) Quote(
+	ctx context.Context, autoId string, days int, startDate time.Time, returnDate time.Time, options RentalOptions) (
+	checkout int, insurance int, err error) {
+	ctx, span := a.startSpan(ctx, "Quote", attrAutoID.String(autoId), attrRentDays.Int(days))
+	defer func() { endSpan(span, err) }()
+	auto, err := a.autoRepository.GetAutoById(ctx, autoId)
 	if err != nil {
 		return 0, 0, err
 	}
-	if rent == (models.AutoRent{}) {
+	span.SetAttributes(attrAutoType.String(auto.Type))
+	err = a.checkThreshold(ctx, auto.Type, days)
+	if err != nil {
 		return 0, 0, err
 	}
-	auto, err := a.autoRepository.GetAutoById(autoId)
+	if options.PromoCode != "" {
+		if err = a.checkPromoCode(ctx, options.PromoCode, auto.Type, startDate); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err = a.checkRedeemPoints(options.RedeemPoints); err != nil {
+		return 0, 0, err
+	}
+	extras, err := a.chooseExtras(ctx, options.Extras)
 	if err != nil {
 		return 0, 0, err
 	}
-	necessaryCommissions := a.commissionRepository.GetCommissionsByType(auto.Type)
-	if necessaryCommissions == nil {
+	insured, err := a.choosePackage(ctx, auto.Type, options.Insurance)
+	if err != nil {
+		return 0, 0, err
+	}
+	commissions := a.commissionRepository.GetCommissionsByType(ctx, auto.Type)
+	if commissions == nil {
 		return 0, 0, errors.New("no commissions found for auto type")
 	}
-	currentCommission, insurance := calculateCommissions(
-		rent, necessaryCommissions, calculationDate.AddDate(0, 0, -1), false)
-	return currentCommission, insurance, nil
+	seasons, err := a.seasonsOf(ctx, auto.Type)
+	if err != nil {
+		return 0, 0, err
+	}
+	demand, err := a.demandPercent(ctx, auto.Type)
+	if err != nil {
+		return 0, 0, err
+	}
+	// same dates BindRent would store for a rent started at startDate, at the demand of now
+	rent := models.AutoRent{AutoID: autoId, StartDate: startDate, EndDate: startDate.AddDate(0, 0, days),
+		PromoCode: options.PromoCode, DemandPercent: demand, RedeemedPoints: options.RedeemPoints,
+		Insurance: insured.Code, InsuranceDailyPrice: insured.DailyPrice, InsuranceRentalPrice: insured.RentalPrice,
+		InsuranceExcess: insured.Excess}
+	breakdown := commissionBreakdown(rent, commissions, seasons, returnDate, true)
+	breakdown.Extras = chargeExtras(extras, breakdown.Days)
+	if breakdown, err = a.discount(ctx, breakdown, rent, auto.Type); err != nil {
+		return 0, 0, err
+	}
+	checkout = breakdown.Total()
+	span.SetAttributes(attrCheckout.Int(checkout))
+	return checkout, breakdown.Insurance, nil
+}
+
+func (a RentalServiceImpl) ListInsurance(
+	ctx context.Context, autoType string) (_ []models.InsurancePackage, err error) {
+	ctx, span := a.startSpan(ctx, "ListInsurance", attrAutoType.String(autoType))
+	defer func() { endSpan(span, err) }()
+	if a.insurance == nil {
+		return nil, nil
+	}
+	return a.insurance.FindPackages(ctx, autoType)
+}
+
+func (a RentalServiceImpl) ListExtras(ctx context.Context, location string) (_ []ExtraAvailability, err error) {
+	ctx, span := a.startSpan(ctx, "ListExtras")
+	defer func() { endSpan(span, err) }()
+	if a.extras == nil {
+		return nil, nil
+	}
+	extras, err := a.extras.FindExtras(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stock := map[string]int{}
+	if location != "" {
+		found, err := a.extras.FindStock(ctx, location)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range found {
+			stock[s.ExtraID] = s.Quantity
+		}
+	}
+	now := time.Now()
+	availability := make([]ExtraAvailability, 0, len(extras))
+	for _, extra := range extras {
+		listed := ExtraAvailability{Extra: extra}
+		if location != "" && extra.Stocked {
+			reserved, err := a.extras.CountReserved(ctx, extra.ID, location, now, now)
+			if err != nil {
+				return nil, err
+			}
+			available := max(stock[extra.ID]-reserved, 0)
+			listed.Available = &available
+		}
+		availability = append(availability, listed)
+	}
+	return availability, nil
+}
+
+func (a RentalServiceImpl) RetireAuto(ctx context.Context, autoId string) (err error) {
+	ctx, span := a.startSpan(ctx, "RetireAuto", attrAutoID.String(autoId))
+	defer func() { endSpan(span, err) }()
+	auto, err := a.autoRepository.GetAutoById(ctx, autoId)
+	if err != nil {
+		return err
+	}
+	if auto.RetiredAt != nil {
+		return errors.New(RetiredError)
+	}
+	rent, err := a.rentalRepository.GetRentByAuto(ctx, autoId)
+	if err != nil && err.Error() != NotFoundError {
+		return err
+	}
+	if rent != (models.AutoRent{}) {
+		return errors.New(AlreadyRentedError)
+	}
+	err = a.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		if err := a.autoRepository.RetireAuto(ctx, autoId); err != nil {
+			return err
+		}
+		return a.recordEvents(ctx, events.AutoStatusChanged{AutoID: autoId, AutoType: auto.Type,
+			From: auto.Status(), To: models.AutoStatusRetired})
+	})
+	if err != nil {
+		return err
+	}
+	a.logger.InfoContext(ctx, "auto retired", slog.String("auto_id", autoId), slog.String("auto_type", auto.Type))
+	return nil
+}
+
+func (a RentalServiceImpl) SetCommission(ctx context.Context, commission models.Commission) (err error) {
+	ctx, span := a.startSpan(ctx, "SetCommission", attrAutoType.String(commission.AutoType))
+	defer func() { endSpan(span, err) }()
+	changed := events.PricingChanged{AutoType: commission.AutoType, Commission: commission.Type,
+		Value: commission.Value, MinThreshold: commission.MinThreshold}
+	for _, previous := range a.commissionRepository.GetCommissionsByType(ctx, commission.AutoType) {
+		if previous.Type == commission.Type {
+			value := previous.Value
+			changed.PreviousValue = &value
+		}
+	}
+	err = a.transactor.InTransaction(ctx, func(ctx context.Context) error {
+		if err := a.commissionRepository.SetCommission(ctx, commission); err != nil {
+			return err
+		}
+		return a.recordEvents(ctx, changed)
+	})
+	if err != nil {
+		return err
+	}
+	a.logger.InfoContext(ctx, "commission set", slog.String("auto_type", commission.AutoType),
+		slog.String("commission", commission.Type), slog.Int("value", commission.Value))
+	return nil
+}
+
+// recordEvents stores the events in the outbox, in the transaction of the change when there is one.
+func (a RentalServiceImpl) recordEvents(ctx context.Context, payloads ...events.Payload) error {
+	if a.outbox == nil {
+		return nil
+	}
+	now := time.Now()
+	records := make([]models.OutboxEvent, 0, len(payloads))
+	for _, payload := range payloads {
+		record, err := events.NewOutboxEvent(payload, now)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+	return a.outbox.AddEvents(ctx, records)
+}
+
+// paymentSteps are the provider operations of a change, undone when the change fails.
+type paymentSteps struct {
+	// authorized are the ids of the authorizations not captured
+	authorized []string
+	captured   []models.Payment
+	// release are the deposits to void once the change is committed
+	release []models.Payment
+}
+
+// authorize holds the amount for the rent and stores the payment.
+func (a RentalServiceImpl) authorize(
+	ctx context.Context, rent models.AutoRent, kind string, amount int, steps *paymentSteps) (models.Payment, error) {
+	authorizationId, err := a.payments.Authorize(ctx, rent.ClientID, amount, fmt.Sprintf("rent-%d-%s", rent.ID, kind))
+	if err != nil {
+		a.logger.WarnContext(ctx, "payment authorization failed", slog.Int("rent_id", rent.ID),
+			slog.String("client_id", rent.ClientID), slog.String("kind", kind), slog.Int("amount", amount),
+			slog.Any("error", err))
+		return models.Payment{}, PaymentError{Op: "authorization", Err: err}
+	}
+	steps.authorized = append(steps.authorized, authorizationId)
+	return a.paymentRepository.CreatePayment(ctx, models.Payment{RentID: rent.ID, Kind: kind,
+		AuthorizationID: authorizationId, Status: models.PaymentStatusAuthorized, Authorized: amount})
+}
+
+// settle captures the checkout from the deposit and charges what the deposit doesn't cover,
+// the rest of the deposit is released. The charge is authorized first, so a declined charge captures nothing.
+func (a RentalServiceImpl) settle(ctx context.Context, rent models.AutoRent, steps *paymentSteps) error {
+	payments, err := a.paymentRepository.FindPaymentsByRent(ctx, rent.ID)
+	if err != nil {
+		return err
+	}
+	due := rent.Checkout + rent.CheckoutTax
+	var deposits []models.Payment
+	uncovered := due
+	for _, payment := range payments {
+		if payment.Kind == models.PaymentKindDeposit && payment.Status == models.PaymentStatusAuthorized {
+			deposits = append(deposits, payment)
+			uncovered -= payment.Authorized
+		}
+	}
+	if uncovered > 0 {
+		charge, err := a.authorize(ctx, rent, models.PaymentKindCharge, uncovered, steps)
+		if err != nil {
+			return err
+		}
+		deposits = append(deposits, charge)
+	}
+	for _, payment := range deposits {
+		if due == 0 {
+			payment.Status = models.PaymentStatusVoided
+			steps.release = append(steps.release, payment)
+		} else {
+			payment.Captured = min(due, payment.Authorized)
+			if err := a.payments.Capture(ctx, payment.AuthorizationID, payment.Captured); err != nil {
+				a.logger.ErrorContext(ctx, "payment capture failed", slog.Int("rent_id", rent.ID),
+					slog.String("authorization_id", payment.AuthorizationID), slog.Int("amount", payment.Captured),
+					slog.Any("error", err))
+				return PaymentError{Op: "capture", Err: err}
+			}
+			payment.Status = models.PaymentStatusCaptured
+			steps.captured = append(steps.captured, payment)
+			due -= payment.Captured
+		}
+		if err := a.paymentRepository.UpdatePayment(ctx, payment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// undoPayments voids the authorizations and refunds the captures of a failed change.
+func (a RentalServiceImpl) undoPayments(ctx context.Context, rentId int, steps paymentSteps) {
+	captured := map[string]bool{}
+	for _, payment := range steps.captured {
+		captured[payment.AuthorizationID] = true
+		if err := a.payments.Refund(ctx, payment.AuthorizationID, payment.Captured); err != nil {
+			a.logger.ErrorContext(ctx, "refunding the capture of a failed change failed", slog.Int("rent_id", rentId),
+				slog.String("authorization_id", payment.AuthorizationID), slog.Int("amount", payment.Captured),
+				slog.Any("error", err))
+		}
+	}
+	for _, authorizationId := range steps.authorized {
+		if captured[authorizationId] {
+			continue
+		}
+		if err := a.payments.Void(ctx, authorizationId); err != nil {
+			a.logger.ErrorContext(ctx, "voiding the authorization of a failed change failed",
+				slog.Int("rent_id", rentId), slog.String("authorization_id", authorizationId), slog.Any("error", err))
+		}
+	}
+}
+
+// releasePayments voids the deposits a committed return didn't capture, the provider lets
+// a hold expire when voiding fails.
+func (a RentalServiceImpl) releasePayments(ctx context.Context, rentId int, steps paymentSteps) {
+	for _, payment := range steps.release {
+		if err := a.payments.Void(ctx, payment.AuthorizationID); err != nil {
+			a.logger.WarnContext(ctx, "releasing deposit failed", slog.Int("rent_id", rentId),
+				slog.String("authorization_id", payment.AuthorizationID), slog.Any("error", err))
+		}
+	}
+}
+
+// noTransaction runs the changes one by one, for a service without WithEvents.
+type noTransaction struct{}
+
+func (noTransaction) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// checkoutBreakdown itemizes a checkout, the invoice lines are made from it.
+type checkoutBreakdown struct {
+	Days      int
+	DailyRate int
+	// Daily is the price of the days, each at the daily rate changed by its season and the demand
+	Daily       int
+	WeekendDays int
+	// WeekendPercent is the surcharge on the price of weekend days
+	WeekendPercent int
+	Weekend        int
+	// PenaltyDays are the rented days left at an early return, PenaltyPercent of their price is charged
+	PenaltyDays    int
+	PenaltyPercent int
+	Penalty        int
+	Agreement      int
+	// TierDiscount is TierPercent off the rent days of a rent booked for TierMinDays or more
+	TierMinDays  int
+	TierPercent  int
+	TierDiscount int
+	// PromoDiscount is PromoPercent, or a fixed amount when 0, off the rent days left after the tier
+	PromoCode     string
+	PromoPercent  int
+	PromoDiscount int
+	// LoyaltyTier of the rent waives its agreement fee and early return penalty when it lists them
+	LoyaltyTier     string
+	WaivedAgreement int
+	WaivedPenalty   int
+	// PointsDiscount is what PointsUsed of the points redeemed take off the rent days left after the promo code
+	PointsUsed     int
+	PointsDiscount int
+	// Extras is the price of the extras rented with the auto for the Days, they aren't discounted
+	Extras int
+	// Insurance is the daily price of the InsurancePackage for the Days and its rental price, it isn't discounted
+	InsurancePackage     string
+	InsuranceDailyPrice  int
+	InsuranceRentalPrice int
+	Insurance            int
+	// DamageCharged is the repair cost of the Damage up to the Excess of the package, all of it without one
+	Damage        int
+	Excess        int
+	DamageCharged int
+}
+
+func (b checkoutBreakdown) Total() int {
+	return b.Daily + b.Weekend + b.Penalty - b.WaivedPenalty + b.Agreement - b.WaivedAgreement -
+		b.TierDiscount - b.PromoDiscount - b.PointsDiscount + b.Extras + b.Insurance + b.DamageCharged
+}
+
+// Amounts are the charges of the breakdown per commission type, as they are taxed.
+func (b checkoutBreakdown) Amounts() map[string]int {
+	return map[string]int{
+		commissionTypeDaily:     b.Daily,
+		commissionTypeWeekend:   b.Weekend,
+		commissionTypePenalty:   b.Penalty - b.WaivedPenalty,
+		commissionTypeAgreement: b.Agreement - b.WaivedAgreement,
+		commissionTypeDiscount:  -b.TierDiscount - b.PromoDiscount - b.PointsDiscount,
+		commissionTypeExtra:     b.Extras,
+		commissionTypeInsurance: b.Insurance,
+		commissionTypeDamage:    b.DamageCharged,
+	}
+}
+
+// checkoutLines are the invoice lines of the charges of the breakdown and of each of its extras,
+// adding up to the checkout.
+func checkoutLines(autoId string, b checkoutBreakdown, extras []models.RentExtra) []models.InvoiceLine {
+	var lines []models.InvoiceLine
+	charge := func(commissionType string, description string, quantity int, unitPrice int, amount int) {
+		if amount != 0 {
+			lines = append(lines, models.InvoiceLine{CommissionType: commissionType, Description: description,
+				Quantity: quantity, UnitPrice: unitPrice, Amount: amount})
+		}
+	}
+	rent := b.Days * b.DailyRate
+	charge(commissionTypeDaily, fmt.Sprintf("Rent of %s, %d days", autoId, b.Days), b.Days, b.DailyRate, rent)
+	charge(commissionTypeDaily, "Seasonal and demand price adjustment", 1, b.Daily-rent, b.Daily-rent)
+	charge(commissionTypeWeekend, fmt.Sprintf("Weekend surcharge, %d%% on %d days", b.WeekendPercent, b.WeekendDays),
+		1, b.Weekend, b.Weekend)
+	charge(commissionTypePenalty,
+		fmt.Sprintf("Early return penalty, %d%% of %d days left", b.PenaltyPercent, b.PenaltyDays),
+		1, b.Penalty, b.Penalty)
+	charge(commissionTypePenalty, fmt.Sprintf("Early return penalty waived, %s tier", b.LoyaltyTier),
+		1, -b.WaivedPenalty, -b.WaivedPenalty)
+	charge(commissionTypeAgreement, "Agreement fee", 1, b.Agreement, b.Agreement)
+	charge(commissionTypeAgreement, fmt.Sprintf("Agreement fee waived, %s tier", b.LoyaltyTier),
+		1, -b.WaivedAgreement, -b.WaivedAgreement)
+	for _, extra := range extras {
+		if extra.Pricing == models.ExtraPricingDay {
+			charge(commissionTypeExtra, fmt.Sprintf("%s x%d, %d days", extra.Name, extra.Quantity, b.Days),
+				extra.Quantity*b.Days, extra.Price, extra.Charge(b.Days))
+		} else {
+			charge(commissionTypeExtra, fmt.Sprintf("%s x%d", extra.Name, extra.Quantity),
+				extra.Quantity, extra.Price, extra.Charge(b.Days))
+		}
+	}
+	charge(commissionTypeInsurance, fmt.Sprintf("Insurance %s, %d days", b.InsurancePackage, b.Days),
+		b.Days, b.InsuranceDailyPrice, b.Insurance-b.InsuranceRentalPrice)
+	charge(commissionTypeInsurance, fmt.Sprintf("Insurance %s", b.InsurancePackage),
+		1, b.InsuranceRentalPrice, b.InsuranceRentalPrice)
+	damage := fmt.Sprintf("Damage, repair cost of %d", b.Damage)
+	if b.InsurancePackage != "" {
+		damage += fmt.Sprintf(" up to the %s excess", b.InsurancePackage)
+	}
+	charge(commissionTypeDamage, damage, 1, b.DamageCharged, b.DamageCharged)
+	charge(commissionTypeDiscount,
+		fmt.Sprintf("Long rental discount, %d%% from %d days", b.TierPercent, b.TierMinDays),
+		1, -b.TierDiscount, -b.TierDiscount)
+	promo := "Promo code " + b.PromoCode
+	if b.PromoPercent != 0 {
+		promo += fmt.Sprintf(", %d%%", b.PromoPercent)
+	}
+	charge(commissionTypeDiscount, promo, 1, -b.PromoDiscount, -b.PromoDiscount)
+	charge(commissionTypeDiscount, fmt.Sprintf("Loyalty points, %d redeemed", b.PointsUsed),
+		1, -b.PointsDiscount, -b.PointsDiscount)
+	return lines
+}
+
+// discount applies the long rental tiers of the auto type, the promo code of the rent and its loyalty tier
+// and points to the breakdown. A promo code deleted since the rent was bound no longer applies.
+func (a RentalServiceImpl) discount(
+	ctx context.Context, breakdown checkoutBreakdown, rent models.AutoRent, autoType string) (checkoutBreakdown, error) {
+	if a.discounts != nil {
+		var err error
+		if breakdown, err = a.promoDiscount(ctx, breakdown, rent, autoType); err != nil {
+			return breakdown, err
+		}
+	}
+	if a.loyalty != nil {
+		program := a.loyalty.Program()
+		breakdown = applyLoyalty(breakdown, program.TierNamed(rent.LoyaltyTier), rent.RedeemedPoints, program.PointValue)
+	}
+	return breakdown, nil
+}
+
+func (a RentalServiceImpl) promoDiscount(
+	ctx context.Context, breakdown checkoutBreakdown, rent models.AutoRent, autoType string) (checkoutBreakdown, error) {
+	tiers, err := a.discounts.GetDiscountTiers(ctx, autoType)
+	if err != nil {
+		return breakdown, err
+	}
+	var promo *models.PromoCode
+	if rent.PromoCode != "" {
+		found, err := a.discounts.GetPromoCode(ctx, rent.PromoCode)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return breakdown, err
+		}
+		if err == nil {
+			promo = &found
+		}
+	}
+	bookedDays := int(math.Round(rent.EndDate.Sub(rent.StartDate).Hours() / 24))
+	return applyDiscounts(breakdown, bookedDays, tiers, promo), nil
+}
+
+// applyDiscounts takes the tier with the most days up to the booked days off the rent days,
+// then the promo code off what is left. The penalty and the agreement aren't discounted.
+func applyDiscounts(
+	b checkoutBreakdown, bookedDays int, tiers []models.DiscountTier, promo *models.PromoCode) checkoutBreakdown {
+	for _, tier := range tiers {
+		if tier.MinDays <= bookedDays && tier.MinDays > b.TierMinDays {
+			b.TierMinDays, b.TierPercent = tier.MinDays, tier.Percent
+		}
+	}
+	rentDays := b.Daily + b.Weekend
+	b.TierDiscount = rentDays * b.TierPercent / 100
+	rentDays -= b.TierDiscount
+	if promo != nil {
+		b.PromoCode = promo.Code
+		switch promo.Kind {
+		case models.PromoKindPercent:
+			b.PromoPercent = promo.Value
+			b.PromoDiscount = rentDays * promo.Value / 100
+		case models.PromoKindFixed:
+			b.PromoDiscount = min(promo.Value, rentDays)
+		}
+	}
+	return b
+}
+
+// chargeExtras is the price of the extras for a rent charged for days.
+func chargeExtras(extras []models.RentExtra, days int) int {
+	total := 0
+	for _, extra := range extras {
+		total += extra.Charge(days)
+	}
+	return total
+}
+
+// applyLoyalty waives what the tier waives and takes the redeemed points, at pointValue each, off the rent days
+// left after the discounts. Only the points needed to cover them are used.
+func applyLoyalty(b checkoutBreakdown, tier loyalty.Tier, redeemed int, pointValue int) checkoutBreakdown {
+	b.LoyaltyTier = tier.Name
+	if tier.Waives(commissionTypeAgreement) {
+		b.WaivedAgreement = b.Agreement
+	}
+	if tier.Waives(commissionTypePenalty) {
+		b.WaivedPenalty = b.Penalty
+	}
+	if redeemed > 0 && pointValue > 0 {
+		rentDays := b.Daily + b.Weekend - b.TierDiscount - b.PromoDiscount
+		b.PointsDiscount = min(redeemed*pointValue, max(rentDays, 0))
+		b.PointsUsed = (b.PointsDiscount + pointValue - 1) / pointValue
+	}
+	return b
+}
+
+// applyInsurance charges the insurance package of the rent for the days of the breakdown and once for the rent,
+// and the damage found at the return up to its excess.
+func applyInsurance(b checkoutBreakdown, rent models.AutoRent) checkoutBreakdown {
+	b.InsurancePackage, b.InsuranceDailyPrice = rent.Insurance, rent.InsuranceDailyPrice
+	b.InsuranceRentalPrice = rent.InsuranceRentalPrice
+	b.Insurance = rent.InsuranceDailyPrice*b.Days + rent.InsuranceRentalPrice
+	b.Damage, b.DamageCharged = rent.Damage, rent.Damage
+	if rent.Insurance != "" {
+		b.Excess = rent.InsuranceExcess
+		b.DamageCharged = min(rent.Damage, rent.InsuranceExcess)
+	}
+	return b
 }
 
 // Left some flexibility, for example we can add weekend/penalty commission for standard auto
 // or add commissions to new auto types via DB, without changing code
 // left cases like penalty + businessday commissions without weekend commission out of scope to keep it short
-func calculateCommissions(
-	rent models.AutoRent, commissions []models.Commission, releaseDate time.Time, checkout bool) (
-	totalCommission int, insuranceCommission int) {
+func commissionBreakdown(rent models.AutoRent, commissions []models.Commission, seasons []models.Season,
+	releaseDate time.Time, checkout bool) checkoutBreakdown {
 	releaseDate = releaseDate.Round(0)
-	total := 0
-	weekendComm := 0
-	dailyCommission, weekendCommission,
-		insuranceCommission, agreementCommission, penaltyPercentCommission := getCommissions(commissions)
+	dailyCommission, weekendCommission, agreementCommission, penaltyPercentCommission := getCommissions(commissions)
+	daily := dailyPricing{rate: dailyCommission, demand: rent.DemandPercent, seasons: seasons}
 	if checkout && penaltyPercentCommission.Value != 0 && penaltyPercentCommission.MinThreshold != 0 {
-		return calculateCheckouts(
-			rent, releaseDate, dailyCommission, weekendCommission, agreementCommission, insuranceCommission, penaltyPercentCommission)
+		return applyInsurance(calculateCheckouts(
+			rent, releaseDate, daily, weekendCommission, agreementCommission, penaltyPercentCommission), rent)
 	} else {
 		// get current commission, same as checkout without commission
 		newD1 := rent.StartDate.Truncate(time.Hour * 24)
@@ -149,23 +1277,89 @@ func calculateCommissions(
 		if !checkout && complete == 0 {
 			complete = 1
 		}
-		_, weekEnd := calculateWeekends(rent.StartDate, complete)
-		if dailyCommission != 0 {
-			daily := calculateDailyCommission(complete, dailyCommission)
-			total += daily
+		var breakdown checkoutBreakdown
+		breakdown.priceRentDays(daily, rent.StartDate, complete, weekendCommission)
+		breakdown.Agreement = agreementCommission
+		return applyInsurance(breakdown, rent)
+	}
+}
+
+// dailyPricing prices the days of a rent: the daily commission at the percent of the season of the day
+// and of the demand the rent was bound with.
+type dailyPricing struct {
+	rate int
+	// demand is 0 for rents priced without demand
+	demand  int
+	seasons []models.Season
+}
+
+// dayRate is the price of the day, the season starting last wins where seasons overlap.
+func (p dailyPricing) dayRate(day time.Time) int {
+	percent, start := 100, time.Time{}
+	for _, season := range p.seasons {
+		if season.Contains(day) && !season.StartDate.Before(start) {
+			percent, start = season.Percent, season.StartDate
 		}
-		if weekendCommission != 0 {
-			weekendComm = calculateWeekendCommission(weekEnd, dailyCommission, weekendCommission)
-			total += weekendComm
+	}
+	demand := p.demand
+	if demand == 0 {
+		demand = 100
+	}
+	return p.rate * percent * demand / 10000
+}
+
+// priceDays sums the rates of numDays days from startDate, and again those of the weekend days
+// for their surcharge.
+func (p dailyPricing) priceDays(startDate time.Time, numDays int) (rates int, weekendRates int) {
+	for i := 0; i < numDays; i++ {
+		day := startDate.AddDate(0, 0, i)
+		rate := p.dayRate(day)
+		rates += rate
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			weekendRates += rate
+		}
+	}
+	return rates, weekendRates
+}
+
+// priceRentDays charges the days rented from startDate with the weekend surcharge on the weekend days.
+func (b *checkoutBreakdown) priceRentDays(daily dailyPricing, startDate time.Time, days int, weekendPercent int) {
+	rates, weekendRates := daily.priceDays(startDate, days)
+	b.Days = days
+	b.DailyRate = daily.rate
+	b.Daily = rates
+	if weekendPercent != 0 {
+		_, b.WeekendDays = calculateWeekends(startDate, days)
+		b.WeekendPercent = weekendPercent
+		b.Weekend = calculateWeekendCommission(weekendRates, weekendPercent)
+	}
+}
+
+// penaltyApplied tells whether the checkoutBreakdown of calculateCheckouts charges the early return penalty
+// on top of the days priced by priceRentDays.
+func penaltyApplied(rent models.AutoRent, commissions []models.Commission, releaseDate time.Time) bool {
+	_, _, _, penaltyPercentCommission := getCommissions(commissions)
+	if penaltyPercentCommission.Value == 0 || penaltyPercentCommission.MinThreshold == 0 {
+		return false
+	}
+	_, left := calculateDays(rent.StartDate, rent.EndDate.AddDate(0, 0, 1),
+		releaseDate.Round(0), penaltyPercentCommission.MinThreshold)
+	return left > 0
+}
+
+// depositCommission is the deposit authorized when an auto of the type is bound, 0 for none.
+func depositCommission(commissions []models.Commission) int {
+	for _, commission := range commissions {
+		if commission.Type == commissionTypeDeposit {
+			return commission.Value
 		}
-		return total + agreementCommission, insuranceCommission
 	}
+	return 0
 }
 
 func getCommissions(commissions []models.Commission) (
 	dailyCommission,
 	weekendCommission,
-	insuranceCommission,
 	agreementCommission int,
 	penaltyPercentCommission models.Commission) {
 	for _, commission := range commissions {
@@ -181,52 +1375,40 @@ func getCommissions(commissions []models.Commission) (
 		if commission.Type == commissionTypePenalty {
 			penaltyPercentCommission = commission
 		}
-		if commission.Type == commissionTypeInsurance {
-			insuranceCommission = commission.Value
-		}
 	}
-	return dailyCommission, weekendCommission, insuranceCommission, agreementCommission, penaltyPercentCommission
+	return dailyCommission, weekendCommission, agreementCommission, penaltyPercentCommission
 }
 
 func calculateCheckouts(
 	rent models.AutoRent,
 	releaseDate time.Time,
-	dailyCommission,
+	daily dailyPricing,
 	weekendCommission,
-	agreementCommission,
-	insuranceCommission int,
-	penaltyPercentCommission models.Commission) (int, int) {
-	daily := 0
-	total := 0
+	agreementCommission int,
+	penaltyPercentCommission models.Commission) checkoutBreakdown {
+	var breakdown checkoutBreakdown
 	rent.EndDate = rent.EndDate.AddDate(0, 0, 1)
 	if penaltyPercentCommission.MinThreshold != 0 {
 		complete, left := calculateDays(
 			rent.StartDate, rent.EndDate,
 			releaseDate, penaltyPercentCommission.MinThreshold)
-		_, weekEnd := calculateWeekends(rent.StartDate, complete)
-		if dailyCommission != 0 {
-			daily = calculateDailyCommission(complete, dailyCommission)
-			total += daily
-		}
-		if weekendCommission != 0 {
-			weekendComm := calculateWeekendCommission(weekEnd, dailyCommission, weekendCommission)
-			total += weekendComm
-		}
+		breakdown.priceRentDays(daily, rent.StartDate, complete, weekendCommission)
+		breakdown.Agreement = agreementCommission
 		if left == 0 {
-			return total + agreementCommission, insuranceCommission
+			return breakdown
 		} else {
 			t := rent.StartDate.AddDate(0, 0, penaltyPercentCommission.MinThreshold-1)
 			t = t.AddDate(0, 0, 2)
-			penaltyCostBeforeCommission := 0
-			_, weekEnd = calculateWeekends(t, left)
-			penaltyCostBeforeCommission += calculateDailyCommission(left, dailyCommission)
-			penaltyCostBeforeCommission += calculateWeekendCommission(weekEnd, dailyCommission, weekendCommission)
-			finPenalty := calculatePenaltyCommission(
+			rates, weekendRates := daily.priceDays(t, left)
+			penaltyCostBeforeCommission := rates + calculateWeekendCommission(weekendRates, weekendCommission)
+			breakdown.PenaltyDays = left
+			breakdown.PenaltyPercent = penaltyPercentCommission.Value
+			breakdown.Penalty = calculatePenaltyCommission(
 				penaltyCostBeforeCommission, penaltyPercentCommission.Value)
-			return total + finPenalty + agreementCommission, insuranceCommission
+			return breakdown
 		}
 	}
-	return 0, 0
+	return checkoutBreakdown{}
 }
 
 func calculateDays(startDate time.Time, endDate time.Time, releaseDate time.Time, threshold int) (completeDays int, daysLeft int) {
@@ -264,12 +1446,9 @@ func calculateWeekends(startDate time.Time, numDays int) (workDay, weekendDays i
 	return workDay, weekendDays
 }
 
-func calculateDailyCommission(completeDays int, dailyCommission int) int {
-	return completeDays * dailyCommission
-}
-
-func calculateWeekendCommission(completeDays int, dailyCommission int, weekendCommission int) int {
-	return completeDays * dailyCommission * weekendCommission / 100
+// calculateWeekendCommission is the surcharge on the summed rates of the weekend days.
+func calculateWeekendCommission(weekendRates int, weekendCommission int) int {
+	return weekendRates * weekendCommission / 100
 }
 
 func calculatePenaltyCommission(total int, percent int) int {
@@ -1,9 +1,14 @@
 package service
 
 import (
+	"car-rental/internal/config"
+	"car-rental/internal/events"
+	"car-rental/internal/logging"
+	"car-rental/internal/loyalty"
 	"car-rental/internal/models"
 	"car-rental/internal/repository"
-	"car-rental/internal/utils"
+	"context"
+	"errors"
 	"gorm.io/gorm"
 	"testing"
 	"time"
@@ -12,8 +17,9 @@ import (
 func TestGetAvailableAutoByType(t *testing.T) {
 	db, svc, err := setupRentServiceTests()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
+	ctx := context.Background()
 	db.Create(&models.AutoType{
 		ID: "TestGetAvailableAutoByType",
 	},
@@ -33,7 +39,7 @@ func TestGetAvailableAutoByType(t *testing.T) {
 		Availability: true,
 	})
 
-	auto, err := svc.GetAvailableAutoByType("TestGetAvailableAutoByType")
+	auto, err := svc.GetAvailableAutoByType(ctx, "TestGetAvailableAutoByType")
 	if err != nil {
 		t.Error(err)
 	}
@@ -46,11 +52,70 @@ func TestGetAvailableAutoByType(t *testing.T) {
 	db.Delete(&models.AutoType{}, "id = ?", "TestGetAvailableAutoByType2")
 }
 
-func TestBindAuto(t *testing.T) {
+func TestListAutos(t *testing.T) {
 	db, svc, err := setupRentServiceTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	db.Create(&models.AutoType{ID: "TestListAutos"})
+	db.Create(&models.Commission{AutoType: "TestListAutos", Type: commissionTypeDaily, Value: 70})
+	db.Create(&models.Auto{ID: "TestListAutos1", Type: "TestListAutos", Availability: true, Location: "north",
+		Make: "Volvo", Model: "XC40", Year: 2023, Seats: 5, FuelType: models.FuelTypeElectric,
+		Transmission: models.TransmissionAutomatic, Features: []string{"tow-bar", "navigation"}})
+	db.Create(&models.Auto{ID: "TestListAutos2", Type: "TestListAutos", Availability: true, Location: "south"})
+	db.Create(&models.Auto{ID: "TestListAutos3", Type: "TestListAutos", Availability: false, Location: "north"})
+
+	autos, next, err := svc.ListAutos(ctx, repository.AutoQuery{Type: "TestListAutos", SortBy: repository.AutoSortById, Limit: 2})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(autos) != 2 || next == nil || autos[0].DailyPrice != 70 {
+		t.Fatalf("want first page of 2 autos priced 70 with a cursor, got %v %v", autos, next)
+	}
+	autos, next, err = svc.ListAutos(ctx, repository.AutoQuery{
+		Type: "TestListAutos", SortBy: repository.AutoSortById, Limit: 2, After: next})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(autos) != 1 || next != nil || autos[0].ID != "TestListAutos3" {
+		t.Errorf("want last page with TestListAutos3, got %v %v", autos, next)
+	}
+	autos, _, err = svc.ListAutos(ctx, repository.AutoQuery{
+		Type: "TestListAutos", Status: models.AutoStatusAvailable, Location: "north", SortBy: repository.AutoSortById})
 	if err != nil {
 		t.Error(err)
 	}
+	if len(autos) != 1 || autos[0].ID != "TestListAutos1" {
+		t.Errorf("want only TestListAutos1, got %v", autos)
+	}
+	autos, _, err = svc.ListAutos(ctx, repository.AutoQuery{Type: "TestListAutos", Make: "volvo", MinYear: 2020,
+		MinSeats: 5, FuelType: models.FuelTypeElectric, Features: []string{"navigation", "tow-bar"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(autos) != 1 || autos[0].ID != "TestListAutos1" || autos[0].Model != "XC40" {
+		t.Errorf("want only TestListAutos1 by attributes, got %v", autos)
+	}
+	maxPrice := 60
+	autos, _, err = svc.ListAutos(ctx, repository.AutoQuery{Type: "TestListAutos", MaxPrice: &maxPrice})
+	if err != nil {
+		t.Error(err)
+	}
+	if len(autos) != 0 {
+		t.Errorf("want no autos cheaper than %d, got %v", maxPrice, autos)
+	}
+	db.Delete(&models.Commission{}, "auto_type = ?", "TestListAutos")
+	db.Delete(&models.Auto{}, "type = ?", "TestListAutos")
+	db.Delete(&models.AutoType{}, "id = ?", "TestListAutos")
+}
+
+func TestBindAuto(t *testing.T) {
+	db, svc, err := setupRentServiceTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
 	db.Create(&models.AutoType{
 		ID: "TestBindAuto",
 	},
@@ -59,25 +124,245 @@ func TestBindAuto(t *testing.T) {
 		ID:   "TestBindAuto",
 		Type: "TestBindAuto",
 	})
-	err = svc.BindAuto("TestBindAuto", 10)
+	_, err = svc.BindAuto(ctx, "TestBindAuto", 10, "TestBindAutoClient", RentalOptions{})
 	if err != nil {
 		t.Error(err)
 	}
-	_, err = svc.ReleaseAuto("TestBindAuto", time.Now())
+	rent, err := svc.GetRentByAuto(ctx, "TestBindAuto")
+	if err != nil {
+		t.Error(err)
+	}
+	if rent.ClientID != "TestBindAutoClient" {
+		t.Errorf("want client %s, got %s", "TestBindAutoClient", rent.ClientID)
+	}
+	_, err = svc.ReleaseAuto(ctx, "TestBindAuto", time.Now())
 	if err != nil {
 		t.Error(err)
 	}
+	db.Delete(&models.AutoRent{}, "auto_id = ?", "TestBindAuto")
 	db.Delete(&models.Auto{}, "type = ?", "TestBindAuto")
 	db.Delete(&models.AutoType{}, "id = ?", "TestBindAuto")
 }
 
-func TestReleaseAuto(t *testing.T) {
+func TestBindAutoCancelled(t *testing.T) {
 	db, svc, err := setupRentServiceTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&models.AutoType{ID: "TestBindAutoCancelled"})
+	db.Create(&models.Auto{ID: "TestBindAutoCancelled", Type: "TestBindAutoCancelled", Availability: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = svc.BindAuto(ctx, "TestBindAutoCancelled", 3, "TestBindAutoCancelledClient", RentalOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled, got %v", err)
+	}
+	if _, err = svc.GetRentByAuto(context.Background(), "TestBindAutoCancelled"); err == nil {
+		t.Error("a cancelled bind must not create a rent")
+	}
+	var auto models.Auto
+	db.First(&auto, "id = ?", "TestBindAutoCancelled")
+	if !auto.Availability {
+		t.Error("a cancelled bind must leave the auto available")
+	}
+	db.Delete(&models.AutoRent{}, "auto_id = ?", "TestBindAutoCancelled")
+	db.Delete(&models.Auto{}, "type = ?", "TestBindAutoCancelled")
+	db.Delete(&models.AutoType{}, "id = ?", "TestBindAutoCancelled")
+}
+
+// cancellingRepositories stand in for the database, failing like the driver does once ctx is
+// cancelled. The threshold lookup cancels, as a client disconnecting in the middle of a bind.
+type cancellingRepositories struct {
+	repository.AutoRepository
+	repository.RentalRepository
+	cancel   context.CancelFunc
+	bindings int
+}
+
+func (c *cancellingRepositories) GetRentByAuto(ctx context.Context, _ string) (models.AutoRent, error) {
+	return models.AutoRent{}, gorm.ErrRecordNotFound
+}
+
+func (c *cancellingRepositories) GetAutoById(ctx context.Context, autoId string) (models.Auto, error) {
+	return models.Auto{ID: autoId, Type: "standard", Availability: true}, ctx.Err()
+}
+
+func (c *cancellingRepositories) GetThresholdsByAutoType(ctx context.Context, _ string) (models.RentThreshold, error) {
+	c.cancel()
+	return models.RentThreshold{}, ctx.Err()
+}
+
+func (c *cancellingRepositories) BindRent(ctx context.Context, _ models.AutoRent, _ int) (models.AutoRent, error) {
+	c.bindings++
+	return models.AutoRent{}, ctx.Err()
+}
+
+func (c *cancellingRepositories) BindAuto(ctx context.Context, _ string) error {
+	c.bindings++
+	return ctx.Err()
+}
+
+func TestBindAutoStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repositories := &cancellingRepositories{cancel: cancel}
+	svc := NewRentalServiceImpl(repositories, repositories, nil)
+
+	_, err := svc.BindAuto(ctx, "MINI", 3, "client", RentalOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled, got %v", err)
+	}
+	if repositories.bindings != 0 {
+		t.Errorf("want nothing written after the cancellation, got %d writes", repositories.bindings)
+	}
+}
+
+// eventRepositories keep one auto in memory and store the events only when the transaction commits.
+type eventRepositories struct {
+	repository.AutoRepository
+	repository.RentalRepository
+	repository.OutboxRepository
+	bindErr   error
+	pending   []models.OutboxEvent
+	committed []models.OutboxEvent
+}
+
+func (e *eventRepositories) GetRentByAuto(ctx context.Context, _ string) (models.AutoRent, error) {
+	return models.AutoRent{}, gorm.ErrRecordNotFound
+}
+
+func (e *eventRepositories) GetAutoById(ctx context.Context, autoId string) (models.Auto, error) {
+	return models.Auto{ID: autoId, Type: "standard", Availability: true}, nil
+}
+
+func (e *eventRepositories) GetThresholdsByAutoType(ctx context.Context, _ string) (models.RentThreshold, error) {
+	return models.RentThreshold{}, nil
+}
+
+func (e *eventRepositories) BindRent(ctx context.Context, rent models.AutoRent, days int) (models.AutoRent, error) {
+	rent.ID = 7
+	return rent, nil
+}
+
+func (e *eventRepositories) BindAuto(ctx context.Context, _ string) error {
+	return e.bindErr
+}
+
+func (e *eventRepositories) AddEvents(ctx context.Context, events []models.OutboxEvent) error {
+	e.pending = append(e.pending, events...)
+	return nil
+}
+
+func (e *eventRepositories) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	e.pending = nil
+	if err := fn(ctx); err != nil {
+		return err
+	}
+	e.committed = append(e.committed, e.pending...)
+	return nil
+}
+
+func TestBindAutoRecordsEvents(t *testing.T) {
+	ctx := context.Background()
+	repositories := &eventRepositories{}
+	svc := NewRentalServiceImpl(repositories, repositories, nil, WithEvents(repositories, repositories))
+
+	if _, err := svc.BindAuto(ctx, "MINI", 3, "client", RentalOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(repositories.committed) != 2 {
+		t.Fatalf("want the rental started and the auto status changed, got %v", repositories.committed)
+	}
+	started := events.RentalStarted{}
+	if err := events.FromOutbox(repositories.committed[0]).Decode(&started); err != nil {
+		t.Fatal(err)
+	}
+	if started.RentID != 7 || started.AutoID != "MINI" || started.ClientID != "client" || started.Days != 3 {
+		t.Errorf("unexpected payload %+v", started)
+	}
+	if repositories.committed[1].Type != events.TypeAutoStatusChanged || repositories.committed[1].AggregateID != "MINI" {
+		t.Errorf("unexpected event %+v", repositories.committed[1])
+	}
+
+	// a failed bind rolls back its events with the rent
+	repositories.committed = nil
+	repositories.bindErr = errors.New("connection reset by peer")
+	if _, err := svc.BindAuto(ctx, "MINI", 3, "client", RentalOptions{}); err == nil {
+		t.Fatal("want the bind to fail")
+	}
+	if len(repositories.committed) != 0 {
+		t.Errorf("want no events of a failed bind, got %v", repositories.committed)
+	}
+}
+
+func TestReturnRent(t *testing.T) {
+	db, svc, err := setupRentServiceTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	db.Create(&models.AutoType{ID: "TestReturnRent"})
+	db.Create(&models.Auto{ID: "TestReturnRent", Type: "TestReturnRent"})
+	db.Create(&models.Commission{AutoType: "TestReturnRent", Type: commissionTypeDaily, Value: 100})
+
+	rent, err := svc.BindAuto(ctx, "TestReturnRent", 3, "TestReturnRentClient", RentalOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+	returned, err := svc.ReturnRent(ctx, rent.ID, RentalReturn{
+		ReturnDate: rent.StartDate,
+		Odometer:   1200,
+		Notes:      "scratch on the left door",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if returned.Checkout != 100 {
+		t.Errorf("want %d, got %d", 100, returned.Checkout)
+	}
+	stored, err := svc.GetRent(ctx, rent.ID)
 	if err != nil {
 		t.Error(err)
 	}
+	if stored.Status != models.RentStatusClosed || stored.Odometer != 1200 || stored.Checkout != 100 {
+		t.Errorf("rent is not closed with return details: %+v", stored)
+	}
+	_, err = svc.ReturnRent(ctx, rent.ID, RentalReturn{ReturnDate: rent.StartDate})
+	if err == nil || err.Error() != AlreadyClosedError {
+		t.Errorf("want %s, got %v", AlreadyClosedError, err)
+	}
+	// the auto can be rented again once returned
+	rent, err = svc.BindAuto(ctx, "TestReturnRent", 3, "TestReturnRentClient", RentalOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+	_, err = svc.ReturnRent(ctx, rent.ID, RentalReturn{ReturnDate: rent.StartDate})
+	if err != nil {
+		t.Error(err)
+	}
+	// the status changes went to the outbox with the rents
+	var statusChanges int64
+	db.Model(&models.OutboxEvent{}).
+		Where("type = ? AND aggregate_id = ?", events.TypeAutoStatusChanged, "TestReturnRent").Count(&statusChanges)
+	if statusChanges != 4 {
+		t.Errorf("want 4 auto status changes, got %d", statusChanges)
+	}
+	db.Delete(&models.OutboxEvent{}, "payload->>'auto_id' = ?", "TestReturnRent")
+	db.Delete(&models.AutoRent{}, "auto_id = ?", "TestReturnRent")
+	db.Delete(&models.Commission{}, "auto_type = ?", "TestReturnRent")
+	db.Delete(&models.Auto{}, "type = ?", "TestReturnRent")
+	db.Delete(&models.AutoType{}, "id = ?", "TestReturnRent")
+}
+
+func TestReleaseAuto(t *testing.T) {
+	db, svc, err := setupRentServiceTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
 	// release unexisting auto
-	_, err = svc.ReleaseAuto("TESTAUTO", time.Now())
+	_, err = svc.ReleaseAuto(ctx, "TESTAUTO", time.Now())
 	if err.Error() != "record not found" {
 		t.Error("expected error, got nil")
 	}
@@ -115,7 +400,7 @@ func TestReleaseAuto(t *testing.T) {
 			Type:         "test",
 			Availability: false,
 		})
-		db.Create(models.AutoRent{
+		db.Create(&models.AutoRent{
 			AutoID:    "TESTAUTO1",
 			StartDate: time.Date(2023, time.September, 22, 1, 2, 3, 4, time.UTC),
 			EndDate:   testday.AddDate(0, 0, 3),
@@ -123,7 +408,7 @@ func TestReleaseAuto(t *testing.T) {
 		// 6 working + 4 we + penalty for 3 working + agreement
 		// 2000 + 80 + 30 + 200
 		want := 2000 + (4 * 200 * 20 / 100) + 30 + 200
-		commission, err1 := svc.ReleaseAuto("TESTAUTO1", testday)
+		commission, err1 := svc.ReleaseAuto(ctx, "TESTAUTO1", testday)
 		if err1 != nil {
 			t.Error(err1)
 		}
@@ -141,14 +426,14 @@ func TestReleaseAuto(t *testing.T) {
 		Type:         "test",
 		Availability: false,
 	})
-	db.Create(models.AutoRent{
+	db.Create(&models.AutoRent{
 		AutoID:    "TESTAUTO2",
 		StartDate: time.Date(2023, time.October, 29, 1, 2, 3, 4, time.UTC),
 		EndDate:   time.Date(2023, time.November, 11, 1, 2, 3, 4, time.UTC),
 	})
 	// 10 working + 3 we + 1 * penalty + agreement
 	want := 2600 + 120 + 10 + 200
-	commission, err1 := svc.ReleaseAuto("TESTAUTO2", testday)
+	commission, err1 := svc.ReleaseAuto(ctx, "TESTAUTO2", testday)
 	if err1 != nil {
 		t.Error(err1)
 	}
@@ -163,14 +448,14 @@ func TestReleaseAuto(t *testing.T) {
 			Type:         "test",
 			Availability: false,
 		})
-		db.Create(models.AutoRent{
+		db.Create(&models.AutoRent{
 			AutoID:    "TESTAUTO3",
 			StartDate: testday.AddDate(0, 0, -5),
 			EndDate:   testday.AddDate(0, 0, 7),
 		})
 		//
 		want := 2000 + (4 * 200 * 20 / 100) + (2*200*5)/100 + 200
-		commission, err1 = svc.ReleaseAuto("TESTAUTO3", testday)
+		commission, err1 = svc.ReleaseAuto(ctx, "TESTAUTO3", testday)
 		if err1 != nil {
 			t.Error(err1)
 		}
@@ -184,14 +469,14 @@ func TestReleaseAuto(t *testing.T) {
 				Type:         "test",
 				Availability: false,
 			})
-			db.Create(models.AutoRent{
+			db.Create(&models.AutoRent{
 				AutoID:    "TESTAUTO4",
 				StartDate: testday,
 				EndDate:   testday.AddDate(0, 0, 10),
 			})
 			//
 			want := (10 * 200) + 80 + 200
-			commission, err1 = svc.ReleaseAuto("TESTAUTO4", testday)
+			commission, err1 = svc.ReleaseAuto(ctx, "TESTAUTO4", testday)
 			if err1 != nil {
 				t.Error(err1)
 			}
@@ -202,28 +487,34 @@ func TestReleaseAuto(t *testing.T) {
 
 	}
 	db.Delete(&models.Commission{}, "auto_type = ?", "test")
+	db.Delete(&models.AutoRent{}, "auto_id IN ?", []string{"TESTAUTO1", "TESTAUTO2", "TESTAUTO3", "TESTAUTO4"})
 	db.Delete(&models.Auto{}, "type = ?", "test")
 	db.Delete(&models.AutoType{}, "id = ?", "test")
 }
 
 func setupRentServiceTests() (*gorm.DB, *RentalServiceImpl, error) {
-	dsn := utils.GetDsnFromEnv()
-	db, err := models.ConnectDatabase(dsn)
+	cfg, err := config.NewLoader(nil).Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := models.ConnectDatabase(cfg.Database.ConnectionString(), logging.Discard())
 	if err != nil {
 		return nil, nil, err
 	}
 	ar := repository.NewAutoRepositoryImpl(db)
-	cr := repository.NewCommissionRepositoryImpl(db)
-	rr := repository.NewRentalRepositoryImpl(db)
-	svc := NewRentalServiceImpl(ar, rr, cr)
+	cr := repository.NewCommissionRepositoryImpl(db, logging.Discard())
+	rr := repository.NewRentalRepositoryImpl(db, logging.Discard())
+	svc := NewRentalServiceImpl(ar, rr, cr,
+		WithEvents(repository.NewGormTransactor(db), repository.NewOutboxRepositoryImpl(db)))
 	return db, svc, nil
 }
 
 func TestGetCurrentCommission(t *testing.T) {
 	db, svc, err := setupRentServiceTests()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
+	ctx := context.Background()
 	db.Create(&models.AutoType{
 		ID: "TestGetCurrentCommission",
 	})
@@ -243,50 +534,47 @@ func TestGetCurrentCommission(t *testing.T) {
 		Type:     commissionTypeAgreement,
 		Value:    200,
 	})
-	db.Create(&models.Commission{
-		AutoType: "TestGetCurrentCommission",
-		Type:     "insurance",
-		Value:    200,
-	})
 	{
 		testday := time.Date(2023, time.November, 15, 1, 2, 3, 4, time.UTC)
-		db.Create(models.AutoRent{
-			AutoID:    "TestGetCurrentCommission",
-			StartDate: testday.AddDate(0, 0, -10),
-			EndDate:   testday.AddDate(0, 0, 7),
+		db.Create(&models.AutoRent{
+			AutoID:              "TestGetCurrentCommission",
+			StartDate:           testday.AddDate(0, 0, -10),
+			EndDate:             testday.AddDate(0, 0, 7),
+			Insurance:           "basic",
+			InsuranceDailyPrice: 20,
 		})
 
 		want := (10 * 200) + (3 * 200 * 20 / 100) + 200
-		comm, ins, err := svc.GetCurrentCommission("TestGetCurrentCommission", testday)
+		comm, ins, err := svc.GetCurrentCommission(ctx, "TestGetCurrentCommission", testday)
 		if err != nil {
 			t.Error(err)
 		}
-		if comm != want {
-			t.Errorf("want %d, got %d", want, comm)
+		if comm.Net != want || comm.Gross != want {
+			t.Errorf("want %d, got %+v", want, comm)
 		}
-		if ins != 200 {
-			t.Errorf("want %d, got %d", 200, ins)
+		if ins.Net != 200 {
+			t.Errorf("want %d, got %+v", 200, ins)
 		}
 	}
 	{
 		testday := time.Date(2023, time.September, 15, 1, 2, 3, 4, time.UTC)
 		db.Create(&models.Auto{ID: "TestGetCurrentCommission1", Type: "TestGetCurrentCommission"})
-		db.Create(models.AutoRent{
+		db.Create(&models.AutoRent{
 			AutoID:    "TestGetCurrentCommission1",
 			StartDate: testday,
 			EndDate:   testday.AddDate(0, 0, 11),
 		})
 
 		want := 1*200 + 200
-		comm, ins, err := svc.GetCurrentCommission("TestGetCurrentCommission1", testday)
+		comm, ins, err := svc.GetCurrentCommission(ctx, "TestGetCurrentCommission1", testday)
 		if err != nil {
 			t.Error(err)
 		}
-		if comm != want {
-			t.Errorf("want %d, got %d", want, comm)
+		if comm.Net != want || comm.Gross != want {
+			t.Errorf("want %d, got %+v", want, comm)
 		}
-		if ins != 200 {
-			t.Errorf("want %d, got %d", 200, ins)
+		if ins.Net != 200 {
+			t.Errorf("want %d, got %+v", 200, ins)
 		}
 	}
 	db.Delete(&models.AutoRent{}, "auto_id = ?", "TestGetCurrentCommission")
@@ -297,3 +585,300 @@ func TestGetCurrentCommission(t *testing.T) {
 	db.Delete(&models.Auto{}, "type = ?", "TestGetCurrentCommission")
 	db.Delete(&models.AutoType{}, "id = ?", "TestGetCurrentCommission")
 }
+
+func TestQuote(t *testing.T) {
+	db, svc, err := setupRentServiceTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	db.Create(&models.AutoType{ID: "TestQuote"})
+	db.Create(&models.Auto{ID: "TestQuote", Type: "TestQuote", Availability: true})
+	db.Create(&models.Commission{AutoType: "TestQuote", Type: commissionTypeDaily, Value: 100})
+	db.Create(&models.Commission{AutoType: "TestQuote", Type: commissionTypeWeekend, Value: 20})
+	db.Create(&models.RentThreshold{AutoType: "TestQuote", MinThreshold: 1, MaxThreshold: 10})
+
+	friday := time.Date(2023, time.November, 17, 0, 0, 0, 0, time.UTC)
+	checkout, _, err := svc.Quote(ctx, "TestQuote", 3, friday, friday.AddDate(0, 0, 2), RentalOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+	if want := 3*100 + 2*100*20/100; checkout != want {
+		t.Errorf("want %d, got %d", want, checkout)
+	}
+	_, _, err = svc.Quote(ctx, "TestQuote", 11, friday, friday.AddDate(0, 0, 10), RentalOptions{})
+	if _, ok := err.(ThresholdError); !ok {
+		t.Errorf("want threshold error, got %v", err)
+	}
+	// quoting doesn't bind the auto
+	if _, err = svc.GetRentByAuto(ctx, "TestQuote"); err == nil || err.Error() != NotFoundError {
+		t.Errorf("want %s, got %v", NotFoundError, err)
+	}
+	db.Delete(&models.RentThreshold{}, "auto_type = ?", "TestQuote")
+	db.Delete(&models.Commission{}, "auto_type = ?", "TestQuote")
+	db.Delete(&models.Auto{}, "type = ?", "TestQuote")
+	db.Delete(&models.AutoType{}, "id = ?", "TestQuote")
+}
+
+func TestRetireAuto(t *testing.T) {
+	db, svc, err := setupRentServiceTests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	db.Create(&models.AutoType{ID: "TestRetireAuto"})
+	db.Create(&models.Auto{ID: "TestRetireAuto", Type: "TestRetireAuto", Availability: true})
+
+	rent, err := svc.BindAuto(ctx, "TestRetireAuto", 3, "TestRetireAutoClient", RentalOptions{})
+	if err != nil {
+		t.Error(err)
+	}
+	if err = svc.RetireAuto(ctx, "TestRetireAuto"); err == nil || err.Error() != AlreadyRentedError {
+		t.Errorf("want %s, got %v", AlreadyRentedError, err)
+	}
+	if _, err = svc.ReturnRent(ctx, rent.ID, RentalReturn{ReturnDate: rent.StartDate}); err != nil {
+		t.Error(err)
+	}
+	if err = svc.RetireAuto(ctx, "TestRetireAuto"); err != nil {
+		t.Error(err)
+	}
+	if _, err = svc.BindAuto(ctx, "TestRetireAuto", 3, "TestRetireAutoClient", RentalOptions{}); err == nil || err.Error() != NotFoundError {
+		t.Errorf("want %s, got %v", NotFoundError, err)
+	}
+	if _, err = svc.GetAvailableAutoByType(ctx, "TestRetireAuto"); err == nil || err.Error() != NotFoundError {
+		t.Errorf("retired auto is still available: %v", err)
+	}
+	db.Delete(&models.AutoRent{}, "auto_id = ?", "TestRetireAuto")
+	db.Delete(&models.Auto{}, "type = ?", "TestRetireAuto")
+	db.Delete(&models.AutoType{}, "id = ?", "TestRetireAuto")
+}
+
+func TestPenaltyApplied(t *testing.T) {
+	start := time.Date(2023, time.October, 15, 0, 0, 0, 0, time.UTC)
+	commissions := []models.Commission{
+		{Type: commissionTypeDaily, Value: 200},
+		{Type: commissionTypePenalty, Value: 50, MinThreshold: 10},
+	}
+	tests := []struct {
+		days     int
+		returned time.Time
+		want     bool
+	}{
+		{10, start, false},
+		{15, start, true},
+		{15, start.AddDate(0, 0, 15), false},
+	}
+	for _, tt := range tests {
+		rent := models.AutoRent{StartDate: start, EndDate: start.AddDate(0, 0, tt.days)}
+		if got := penaltyApplied(rent, commissions, tt.returned); got != tt.want {
+			t.Errorf("%d days returned %s: want %v, got %v", tt.days, tt.returned.Format(time.DateOnly), tt.want, got)
+		}
+	}
+	if penaltyApplied(models.AutoRent{StartDate: start, EndDate: start.AddDate(0, 0, 15)}, commissions[:1], start) {
+		t.Error("no penalty without a penalty commission")
+	}
+}
+
+func TestCheckReturnDate(t *testing.T) {
+	rent := models.AutoRent{StartDate: time.Date(2023, time.October, 15, 9, 30, 0, 0, time.UTC)}
+	now := time.Date(2023, time.October, 20, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		returned time.Time
+		want     string
+	}{
+		{time.Date(2023, time.October, 15, 0, 0, 0, 0, time.UTC), ""},
+		{time.Date(2023, time.October, 20, 23, 0, 0, 0, time.UTC), ""},
+		{time.Date(2023, time.October, 14, 0, 0, 0, 0, time.UTC), "return date is before the rent started"},
+		{time.Date(2023, time.October, 21, 0, 0, 0, 0, time.UTC), "return date is after today"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if err := checkReturnDate(rent, tt.returned, now); err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("returned %s: want %q, got %q", tt.returned.Format(time.DateOnly), tt.want, got)
+		}
+	}
+}
+
+func TestCommissionBreakdown(t *testing.T) {
+	commissions := []models.Commission{
+		{Type: commissionTypeDaily, Value: 200},
+		{Type: commissionTypeWeekend, Value: 20},
+		{Type: commissionTypeAgreement, Value: 200},
+		{Type: commissionTypePenalty, Value: 5, MinThreshold: 10},
+	}
+	day := func(month time.Month, day int) time.Time { return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		rent     models.AutoRent
+		returned time.Time
+		want     checkoutBreakdown
+	}{
+		// the cases of TestReleaseAuto, 10 full days with 4 on weekends and 3 days of penalty
+		{models.AutoRent{StartDate: day(time.September, 22), EndDate: day(time.October, 4)}, day(time.October, 1),
+			checkoutBreakdown{Days: 10, DailyRate: 200, Daily: 2000, WeekendDays: 4, WeekendPercent: 20, Weekend: 160,
+				PenaltyDays: 3, PenaltyPercent: 5, Penalty: 30, Agreement: 200}},
+		{models.AutoRent{StartDate: day(time.October, 29), EndDate: day(time.November, 11)}, day(time.November, 10),
+			checkoutBreakdown{Days: 13, DailyRate: 200, Daily: 2600, WeekendDays: 3, WeekendPercent: 20, Weekend: 120,
+				PenaltyDays: 1, PenaltyPercent: 5, Penalty: 10, Agreement: 200}},
+		{models.AutoRent{StartDate: day(time.November, 10), EndDate: day(time.November, 22)}, day(time.November, 15),
+			checkoutBreakdown{Days: 10, DailyRate: 200, Daily: 2000, WeekendDays: 4, WeekendPercent: 20, Weekend: 160,
+				PenaltyDays: 2, PenaltyPercent: 5, Penalty: 20, Agreement: 200}},
+	}
+	for _, tt := range tests {
+		if got := commissionBreakdown(tt.rent, commissions, nil, tt.returned, true); got != tt.want {
+			t.Errorf("rent %s returned %s: want %+v, got %+v", tt.rent.StartDate.Format(time.DateOnly),
+				tt.returned.Format(time.DateOnly), tt.want, got)
+		}
+	}
+}
+
+func TestSeasonalBreakdown(t *testing.T) {
+	commissions := []models.Commission{
+		{Type: commissionTypeDaily, Value: 200},
+		{Type: commissionTypeWeekend, Value: 20},
+		{Type: commissionTypePenalty, Value: 5, MinThreshold: 10},
+	}
+	day := func(month time.Month, day int) time.Time { return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC) }
+	seasons := []models.Season{
+		{Name: "autumn", StartDate: day(time.September, 25), EndDate: day(time.October, 15), Percent: 150},
+		// the season starting last wins
+		{Name: "holiday", StartDate: day(time.September, 30), EndDate: day(time.September, 30), Percent: 200},
+	}
+	// the first case of TestCommissionBreakdown bound at 110% demand: 220 a day, 330 in autumn and 440 on holiday,
+	// the penalty days are in autumn
+	rent := models.AutoRent{StartDate: day(time.September, 22), EndDate: day(time.October, 4), DemandPercent: 110}
+	got := commissionBreakdown(rent, commissions, seasons, day(time.October, 1), true)
+	want := checkoutBreakdown{Days: 10, DailyRate: 200, Daily: 3*220 + 6*330 + 440, WeekendDays: 4,
+		WeekendPercent: 20, Weekend: (2*220 + 440 + 330) * 20 / 100, PenaltyDays: 3, PenaltyPercent: 5,
+		Penalty: 3 * 330 * 5 / 100}
+	if got != want {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+	lines := checkoutLines("MINI", got, nil)
+	if lines[0].Amount != 2000 || lines[1].Description != "Seasonal and demand price adjustment" ||
+		lines[1].Amount != got.Daily-2000 {
+		t.Errorf("want the rent at the daily rate and the adjustment, got %+v", lines)
+	}
+}
+
+func TestApplyDiscounts(t *testing.T) {
+	// the first case of TestCommissionBreakdown, 2160 of rent days
+	breakdown := checkoutBreakdown{Days: 10, DailyRate: 200, Daily: 2000, WeekendDays: 4, WeekendPercent: 20,
+		Weekend: 160, PenaltyDays: 3, PenaltyPercent: 5, Penalty: 30, Agreement: 200}
+	tiers := []models.DiscountTier{{MinDays: 7, Percent: 10}, {MinDays: 30, Percent: 25}}
+	tests := []struct {
+		bookedDays int
+		promo      *models.PromoCode
+		tier       int
+		promoOff   int
+	}{
+		{5, nil, 0, 0},
+		{12, nil, 216, 0},
+		{30, nil, 540, 0},
+		// the promo percent is taken off what the tier leaves
+		{12, &models.PromoCode{Code: "SUMMER", Kind: models.PromoKindPercent, Value: 15}, 216, 291},
+		{5, &models.PromoCode{Code: "WELCOME", Kind: models.PromoKindFixed, Value: 500}, 0, 500},
+		// a fixed promo takes no more than the rent days
+		{12, &models.PromoCode{Code: "FREE", Kind: models.PromoKindFixed, Value: 5000}, 216, 1944},
+	}
+	for _, tt := range tests {
+		got := applyDiscounts(breakdown, tt.bookedDays, tiers, tt.promo)
+		if got.TierDiscount != tt.tier || got.PromoDiscount != tt.promoOff ||
+			got.Total() != breakdown.Total()-tt.tier-tt.promoOff {
+			t.Errorf("%d days with %+v: want %d and %d off, got %+v", tt.bookedDays, tt.promo, tt.tier, tt.promoOff, got)
+		}
+	}
+	lines := checkoutLines("MINI", applyDiscounts(breakdown, 12, tiers,
+		&models.PromoCode{Code: "SUMMER", Kind: models.PromoKindPercent, Value: 15}), nil)
+	if len(lines) != 6 || lines[4].Description != "Long rental discount, 10% from 7 days" || lines[4].Amount != -216 ||
+		lines[5].Description != "Promo code SUMMER, 15%" || lines[5].CommissionType != commissionTypeDiscount {
+		t.Errorf("want the discount lines, got %+v", lines)
+	}
+}
+
+func TestApplyLoyalty(t *testing.T) {
+	// the first case of TestCommissionBreakdown with the 10% tier of 12 days, 1944 of rent days left
+	breakdown := applyDiscounts(checkoutBreakdown{Days: 10, DailyRate: 200, Daily: 2000, WeekendDays: 4,
+		WeekendPercent: 20, Weekend: 160, PenaltyDays: 3, PenaltyPercent: 5, Penalty: 30, Agreement: 200},
+		12, []models.DiscountTier{{MinDays: 7, Percent: 10}}, nil)
+	gold := loyalty.Tier{Name: "gold", MinPoints: 5000, Waived: []string{"agreement", "penalty"}}
+	tests := []struct {
+		tier       loyalty.Tier
+		redeemed   int
+		pointValue int
+		waived     int
+		used       int
+		pointsOff  int
+	}{
+		{loyalty.Tier{}, 0, 1, 0, 0, 0},
+		{loyalty.Tier{Name: "silver", MinPoints: 1000, Waived: []string{"agreement"}}, 0, 1, 200, 0, 0},
+		{gold, 500, 1, 230, 500, 500},
+		// only the points covering the rent days are used, the last one in part
+		{loyalty.Tier{}, 500, 5, 0, 389, 1944},
+	}
+	for _, tt := range tests {
+		got := applyLoyalty(breakdown, tt.tier, tt.redeemed, tt.pointValue)
+		if got.WaivedAgreement+got.WaivedPenalty != tt.waived || got.PointsUsed != tt.used ||
+			got.PointsDiscount != tt.pointsOff || got.Total() != breakdown.Total()-tt.waived-tt.pointsOff {
+			t.Errorf("%s tier with %d points: want %d waived and %d points for %d off, got %+v",
+				tt.tier.Name, tt.redeemed, tt.waived, tt.used, tt.pointsOff, got)
+		}
+	}
+	got := applyLoyalty(breakdown, gold, 500, 1)
+	amounts := got.Amounts()
+	if amounts[commissionTypeAgreement] != 0 || amounts[commissionTypePenalty] != 0 ||
+		amounts[commissionTypeDiscount] != -716 {
+		t.Errorf("want the agreement and penalty waived and 716 off, got %v", amounts)
+	}
+	total := 0
+	for _, line := range checkoutLines("MINI", got, nil) {
+		total += line.Amount
+	}
+	if total != got.Total() {
+		t.Errorf("want the lines adding up to %d, got %d", got.Total(), total)
+	}
+}
+
+func TestApplyInsurance(t *testing.T) {
+	// the first case of TestCommissionBreakdown, 2390 for 10 days
+	breakdown := checkoutBreakdown{Days: 10, DailyRate: 200, Daily: 2000, WeekendDays: 4, WeekendPercent: 20,
+		Weekend: 160, PenaltyDays: 3, PenaltyPercent: 5, Penalty: 30, Agreement: 200}
+	tests := []struct {
+		rent      models.AutoRent
+		insurance int
+		damage    int
+	}{
+		{models.AutoRent{}, 0, 0},
+		// without a package all the damage is charged
+		{models.AutoRent{Damage: 1500}, 0, 1500},
+		{models.AutoRent{Insurance: "basic", InsuranceDailyPrice: 15, InsuranceExcess: 1000, Damage: 1500}, 150, 1000},
+		{models.AutoRent{Insurance: "full", InsuranceDailyPrice: 25, InsuranceExcess: 300, Damage: 200}, 250, 200},
+		{models.AutoRent{Insurance: "zero", InsuranceDailyPrice: 40, Damage: 1500}, 400, 0},
+		// the basic package migrated from the insurance commission is charged once per rent
+		{models.AutoRent{Insurance: "basic", InsuranceRentalPrice: 133, Damage: 1500}, 133, 0},
+		{models.AutoRent{Insurance: "full", InsuranceDailyPrice: 25, InsuranceRentalPrice: 50}, 300, 0},
+	}
+	for _, tt := range tests {
+		got := applyInsurance(breakdown, tt.rent)
+		if got.Insurance != tt.insurance || got.DamageCharged != tt.damage ||
+			got.Total() != breakdown.Total()+tt.insurance+tt.damage {
+			t.Errorf("%q insurance with %d damage: want %d and %d charged, got %+v",
+				tt.rent.Insurance, tt.rent.Damage, tt.insurance, tt.damage, got)
+		}
+		total := 0
+		for _, line := range checkoutLines("MINI", got, nil) {
+			total += line.Amount
+		}
+		if total != got.Total() {
+			t.Errorf("want the lines adding up to %d, got %d", got.Total(), total)
+		}
+	}
+	// the insurance isn't discounted
+	got := applyDiscounts(applyInsurance(breakdown, tests[2].rent), 12, []models.DiscountTier{{MinDays: 7, Percent: 10}},
+		&models.PromoCode{Code: "AUTUMN", Kind: models.PromoKindFixed, Value: 5000})
+	if want := 230 + 150 + 1000; got.Total() != want {
+		t.Errorf("want %d, got %d", want, got.Total())
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	attrAutoID   = attribute.Key("auto.id")
+	attrAutoType = attribute.Key("auto.type")
+	attrRentID   = attribute.Key("rent.id")
+	attrRentDays = attribute.Key("rent.days")
+	attrCheckout = attribute.Key("rent.checkout")
+	attrPenalty  = attribute.Key("rent.penalty")
+)
+
+// startSpan starts the span of a service method, named like RentalService.BindAuto.
+func (a RentalServiceImpl) startSpan(
+	ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return a.tracer.Start(ctx, "RentalService."+method, trace.WithAttributes(attributes...))
+}
+
+// endSpan records the error the method returns and ends the span. Errors answering the request,
+// like a missing rent or days out of the threshold, don't mark the span failed.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !expectedError(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+func expectedError(err error) bool {
+	var thresholdError ThresholdError
+	if errors.As(err, &thresholdError) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return true
+	}
+	switch err.Error() {
+	case NotFoundError, AlreadyRentedError, AlreadyClosedError, RetiredError:
+		return true
+	}
+	return false
+}
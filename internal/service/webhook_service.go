@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+)
+
+// WebhookService manages the webhook subscriptions of partners. Every method is scoped to the owner,
+// subscriptions of other owners aren't found. An empty owner, used for admins, reaches every subscription.
+type WebhookService interface {
+	// CreateSubscription generates the signing secret of the subscription
+	CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (
+		models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, owner string) ([]models.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, owner string, id int) (models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, owner string, id int) (models.WebhookSubscription, error)
+	ListDeliveries(ctx context.Context, owner string, query repository.DeliveryQuery) (
+		[]models.WebhookDelivery, error)
+	// RedeliverDelivery queues a dead delivery again with fresh attempts
+	RedeliverDelivery(ctx context.Context, owner string, subscriptionId int, deliveryId int64) (
+		models.WebhookDelivery, error)
+}
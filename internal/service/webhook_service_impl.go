@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"car-rental/internal/webhooks"
+	"gorm.io/gorm"
+)
+
+const NotDeadError = "delivery is not dead"
+
+type WebhookServiceImpl struct {
+	webhookRepository repository.WebhookRepository
+	logger            *slog.Logger
+}
+
+// NewWebhookServiceImpl logs the subscriptions created and deleted and the deliveries retried.
+func NewWebhookServiceImpl(webhookRepository repository.WebhookRepository, logger *slog.Logger) *WebhookServiceImpl {
+	return &WebhookServiceImpl{webhookRepository: webhookRepository, logger: logger}
+}
+
+func (w WebhookServiceImpl) CreateSubscription(ctx context.Context, subscription models.WebhookSubscription) (
+	models.WebhookSubscription, error) {
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	subscription.Secret = secret
+	subscription, err = w.webhookRepository.CreateSubscription(ctx, subscription)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	w.logger.InfoContext(ctx, "webhook subscription created", slog.Int("subscription_id", subscription.ID),
+		slog.String("owner", subscription.Owner), slog.String("url", subscription.URL),
+		slog.Any("event_types", []string(subscription.EventTypes)))
+	return subscription, nil
+}
+
+func (w WebhookServiceImpl) ListSubscriptions(ctx context.Context, owner string) (
+	[]models.WebhookSubscription, error) {
+	return w.webhookRepository.FindSubscriptions(ctx, owner)
+}
+
+func (w WebhookServiceImpl) GetSubscription(ctx context.Context, owner string, id int) (
+	models.WebhookSubscription, error) {
+	subscription, err := w.webhookRepository.GetSubscription(ctx, id)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	if owner != "" && subscription.Owner != owner {
+		return models.WebhookSubscription{}, gorm.ErrRecordNotFound
+	}
+	return subscription, nil
+}
+
+func (w WebhookServiceImpl) DeleteSubscription(ctx context.Context, owner string, id int) (
+	models.WebhookSubscription, error) {
+	subscription, err := w.GetSubscription(ctx, owner, id)
+	if err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	if err = w.webhookRepository.DeleteSubscription(ctx, id); err != nil {
+		return models.WebhookSubscription{}, err
+	}
+	w.logger.InfoContext(ctx, "webhook subscription deleted", slog.Int("subscription_id", id),
+		slog.String("owner", subscription.Owner))
+	return subscription, nil
+}
+
+func (w WebhookServiceImpl) ListDeliveries(ctx context.Context, owner string, query repository.DeliveryQuery) (
+	[]models.WebhookDelivery, error) {
+	if _, err := w.GetSubscription(ctx, owner, query.SubscriptionID); err != nil {
+		return nil, err
+	}
+	return w.webhookRepository.FindDeliveries(ctx, query)
+}
+
+func (w WebhookServiceImpl) RedeliverDelivery(
+	ctx context.Context, owner string, subscriptionId int, deliveryId int64) (models.WebhookDelivery, error) {
+	if _, err := w.GetSubscription(ctx, owner, subscriptionId); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery, err := w.webhookRepository.GetDelivery(ctx, deliveryId)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if delivery.SubscriptionID != subscriptionId {
+		return models.WebhookDelivery{}, gorm.ErrRecordNotFound
+	}
+	if delivery.Status != models.DeliveryStatusDead {
+		return models.WebhookDelivery{}, errors.New(NotDeadError)
+	}
+	delivery.Status = models.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err = w.webhookRepository.UpdateDelivery(ctx, delivery); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	w.logger.InfoContext(ctx, "webhook delivery retried", slog.Int("subscription_id", subscriptionId),
+		slog.Int64("delivery_id", deliveryId))
+	return delivery, nil
+}
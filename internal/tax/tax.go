@@ -0,0 +1,100 @@
+// Package tax applies the tax rates of the jurisdiction an auto is rented in to the commissions charged.
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Any matches every jurisdiction or commission type in a Rate.
+const Any = "*"
+
+// Rate is the percentage charged on a commission type in a jurisdiction, the location of the auto.
+type Rate struct {
+	Jurisdiction   string
+	CommissionType string
+	Percent        float64
+}
+
+// ParseRate reads a jurisdiction:commission_type:percent entry, like Berlin:insurance:0 or *:daily:19.
+func ParseRate(entry string) (Rate, error) {
+	parts := strings.Split(entry, ":")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return Rate{}, errors.New("tax rates must be jurisdiction:commission_type:percent")
+	}
+	percent, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return Rate{}, fmt.Errorf("tax rate %q is not a number", parts[2])
+	}
+	if percent < 0 || percent >= 100 {
+		return Rate{}, fmt.Errorf("tax rate %v must be a percentage from 0 to below 100", percent)
+	}
+	return Rate{Jurisdiction: parts[0], CommissionType: parts[1], Percent: percent}, nil
+}
+
+// Rates looks up the percentage of a commission type in a jurisdiction. The zero value taxes nothing.
+type Rates struct {
+	// Default applies where no rate matches
+	Default float64
+	rates   map[[2]string]float64
+}
+
+func NewRates(defaultPercent float64, rates ...Rate) Rates {
+	r := Rates{Default: defaultPercent, rates: map[[2]string]float64{}}
+	for _, rate := range rates {
+		r.rates[[2]string{rate.Jurisdiction, rate.CommissionType}] = rate.Percent
+	}
+	return r
+}
+
+// Percent prefers the rate of the jurisdiction and commission type, then of the jurisdiction,
+// then of the commission type in any jurisdiction.
+func (r Rates) Percent(jurisdiction string, commissionType string) float64 {
+	for _, key := range [][2]string{
+		{jurisdiction, commissionType},
+		{jurisdiction, Any},
+		{Any, commissionType},
+		{Any, Any},
+	} {
+		if percent, ok := r.rates[key]; ok {
+			return percent
+		}
+	}
+	return r.Default
+}
+
+// Totals are the net amount, the tax on it and their sum.
+type Totals struct {
+	Net   int
+	Tax   int
+	Gross int
+}
+
+func (t Totals) Add(other Totals) Totals {
+	return Totals{Net: t.Net + other.Net, Tax: t.Tax + other.Tax, Gross: t.Gross + other.Gross}
+}
+
+// Apply taxes the amounts per commission type in the jurisdiction. Like on invoices,
+// the amounts are summed per rate and the tax of each rate is rounded once.
+func (r Rates) Apply(jurisdiction string, amounts map[string]int) Totals {
+	bases := map[float64]int{}
+	for commissionType, amount := range amounts {
+		bases[r.Percent(jurisdiction, commissionType)] += amount
+	}
+	var totals Totals
+	for percent, base := range bases {
+		totals.Net += base
+		totals.Tax += Amount(base, percent)
+	}
+	totals.Gross = totals.Net + totals.Tax
+	return totals
+}
+
+// Amount is the tax on the base, rounded half away from zero so a credit note reverses
+// the tax of its invoice exactly.
+func Amount(base int, percent float64) int {
+	return int(math.Round(float64(base) * percent / 100))
+}
@@ -0,0 +1,50 @@
+package tax
+
+import "testing"
+
+func TestPercent(t *testing.T) {
+	rates := NewRates(5,
+		Rate{Jurisdiction: "Berlin", CommissionType: Any, Percent: 19},
+		Rate{Jurisdiction: "Berlin", CommissionType: "insurance", Percent: 0},
+		Rate{Jurisdiction: Any, CommissionType: "agreement", Percent: 7},
+	)
+	for _, test := range []struct {
+		jurisdiction   string
+		commissionType string
+		want           float64
+	}{
+		{"Berlin", "daily", 19},
+		{"Berlin", "insurance", 0},
+		{"Berlin", "agreement", 19},
+		{"Vienna", "agreement", 7},
+		{"Vienna", "daily", 5},
+	} {
+		if got := rates.Percent(test.jurisdiction, test.commissionType); got != test.want {
+			t.Errorf("%s %s: want %v, got %v", test.jurisdiction, test.commissionType, test.want, got)
+		}
+	}
+	if got := (Rates{}).Percent("Berlin", "daily"); got != 0 {
+		t.Errorf("want no tax without rates, got %v", got)
+	}
+}
+
+func TestApply(t *testing.T) {
+	rates := NewRates(19, Rate{Jurisdiction: Any, CommissionType: "insurance", Percent: 0})
+	// 2190 at 19% is 416.1, rounded once instead of per commission type
+	totals := rates.Apply("Berlin", map[string]int{"daily": 2000, "weekend": 160, "penalty": 30, "insurance": 200})
+	if totals != (Totals{Net: 2390, Tax: 416, Gross: 2806}) {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	rate, err := ParseRate("Berlin:insurance:0")
+	if err != nil || rate != (Rate{Jurisdiction: "Berlin", CommissionType: "insurance", Percent: 0}) {
+		t.Errorf("unexpected rate %+v, %v", rate, err)
+	}
+	for _, entry := range []string{"Berlin:19", ":daily:19", "Berlin:daily:much", "Berlin:daily:100"} {
+		if _, err := ParseRate(entry); err == nil {
+			t.Errorf("want %q rejected", entry)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GormPlugin starts a span for every query the gorm callbacks run, a child of the span in the
+// statement's context, named by operation and table, e.g. "query auto_rent".
+type GormPlugin struct {
+	tracer trace.Tracer
+}
+
+func NewGormPlugin(tracer trace.Tracer) GormPlugin {
+	return GormPlugin{tracer: tracer}
+}
+
+func (p GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (p GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", p.start("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", end),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", p.start("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", end),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", p.start("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", end),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", p.start("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", end),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", p.start("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", end),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", p.start("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", end),
+	)
+}
+
+func (p GormPlugin) start(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		table := db.Statement.Table
+		name := strings.TrimSpace(operation + " " + table)
+		ctx, span := p.tracer.Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperationKey.String(operation),
+				semconv.DBSQLTableKey.String(table)))
+		db.Statement.Context = ctx
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func end(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	if span.IsRecording() {
+		span.SetAttributes(
+			semconv.DBStatementKey.String(db.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", db.Statement.RowsAffected))
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(db.Error)
+			span.SetStatus(codes.Error, db.Error.Error())
+		}
+	}
+	span.End()
+}
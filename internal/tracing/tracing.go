@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	ServiceName = "car-rental"
+	// InstrumentationName names the tracer of every layer, spans tell the layers apart by name
+	InstrumentationName = "car-rental"
+
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Options selects where spans go.
+type Options struct {
+	// Exporter is none, stdout or otlp
+	Exporter string
+	// OTLPEndpoint is host:port of an OTLP/HTTP receiver, e.g. a local collector at localhost:4318
+	OTLPEndpoint string
+	OTLPInsecure bool
+	// SampleRatio is the share of new traces recorded, requests continuing a trace follow its decision
+	SampleRatio float64
+	// Stdout receives the spans of the stdout exporter
+	Stdout io.Writer
+}
+
+// Provider hands out the tracer and flushes the spans on shutdown.
+type Provider struct {
+	trace.TracerProvider
+	shutdown func(context.Context) error
+}
+
+// NewProvider builds the tracer provider of the options and installs the W3C trace context
+// propagator, so traces continue across services. With the none exporter spans are not recorded.
+func NewProvider(ctx context.Context, options Options) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch options.Exporter {
+	case ExporterNone, "":
+		return &Provider{
+			TracerProvider: noop.NewTracerProvider(),
+			shutdown:       func(context.Context) error { return nil },
+		}, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(options.Stdout))
+	case ExporterOTLP:
+		clientOptions := []otlptracehttp.Option{otlptracehttp.WithEndpoint(options.OTLPEndpoint)}
+		if options.OTLPInsecure {
+			clientOptions = append(clientOptions, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOptions...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", options.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", options.Exporter, err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+	return &Provider{TracerProvider: provider, shutdown: provider.Shutdown}, nil
+}
+
+// Tracer is the tracer every layer of the service starts its spans with.
+func (p *Provider) Tracer() trace.Tracer {
+	return p.TracerProvider.Tracer(InstrumentationName)
+}
+
+// Shutdown exports the spans still buffered.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.shutdown(ctx)
+}
+
+// NoopTracer records nothing, for callers that aren't given a tracer.
+func NoopTracer() trace.Tracer {
+	return noop.NewTracerProvider().Tracer(InstrumentationName)
+}
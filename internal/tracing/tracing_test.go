@@ -0,0 +1,57 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"car-rental/internal/models"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestGormPlugin(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(InstrumentationName)
+	// dry run builds the statements without a database
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "postgres://localhost:1/none"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Use(NewGormPlugin(tracer)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := tracer.Start(context.Background(), "RentalService.GetAutoTypes")
+	var autoTypes []models.AutoType
+	db.WithContext(ctx).Where("id = ?", "standard").Find(&autoTypes)
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("want the query and its parent, got %d spans", len(spans))
+	}
+	query := spans[0]
+	if query.Name() != "query auto_type" {
+		t.Errorf("want the span named by operation and table, got %q", query.Name())
+	}
+	if query.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("want the query a child of the span in the context")
+	}
+	attributes := map[string]string{}
+	for _, attribute := range query.Attributes() {
+		attributes[string(attribute.Key)] = attribute.Value.Emit()
+	}
+	if attributes["db.system"] != "postgresql" || attributes["db.sql.table"] != "auto_type" ||
+		attributes["db.statement"] == "" {
+		t.Errorf("want the database, table and statement recorded, got %v", attributes)
+	}
+}
+
+func TestNewProviderRejectsUnknownExporter(t *testing.T) {
+	if _, err := NewProvider(context.Background(), Options{Exporter: "jaeger"}); err == nil {
+		t.Error("want an unknown exporter rejected")
+	}
+}
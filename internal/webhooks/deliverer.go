@@ -0,0 +1,174 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	maxRetryDelay = 6 * time.Hour
+	// maxErrorLength keeps the start of a failure in the delivery log
+	maxErrorLength = 512
+)
+
+type DelivererOptions struct {
+	// Interval is how often due deliveries are looked up
+	Interval  time.Duration
+	BatchSize int
+	// MaxAttempts is how often a delivery is tried before it is dead
+	MaxAttempts int
+	// RetryDelay is the delay of the first retry, doubling with every further one
+	RetryDelay time.Duration
+	// Timeout is how long a partner gets to respond
+	Timeout time.Duration
+}
+
+// Deliverer POSTs the signed deliveries to the subscription URLs, retrying failures with exponential backoff.
+// Deliveries failing MaxAttempts times are dead, the dead-letter list partners retry from.
+type Deliverer struct {
+	repository repository.WebhookRepository
+	client     *http.Client
+	options    DelivererOptions
+	logger     *slog.Logger
+	// now is replaced in tests
+	now func() time.Time
+}
+
+func NewDeliverer(repository repository.WebhookRepository, options DelivererOptions, logger *slog.Logger) *Deliverer {
+	return &Deliverer{
+		repository: repository,
+		client:     &http.Client{Timeout: options.Timeout},
+		options:    options,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Run delivers until ctx is cancelled. A delivery interrupted by shutdown is sent again once its claim expires.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+	for {
+		handled, err := d.DeliverDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "delivering webhooks failed", slog.Any("error", err))
+		}
+		// a full batch means more deliveries are due
+		if err == nil && handled == d.options.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue sends a batch of the due deliveries and returns how many were attempted.
+func (d *Deliverer) DeliverDue(ctx context.Context) (int, error) {
+	now := d.now()
+	// the claim outlasts sending the whole batch, each attempt is bounded by the timeout
+	leaseUntil := now.Add(d.options.Timeout * time.Duration(d.options.BatchSize+1))
+	deliveries, err := d.repository.ClaimDueDeliveries(ctx, now, leaseUntil, d.options.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	subscriptions := map[int]models.WebhookSubscription{}
+	for i, delivery := range deliveries {
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = d.repository.GetSubscription(ctx, delivery.SubscriptionID)
+			// deleted since, its deliveries are gone with it
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			if err != nil {
+				return i, err
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+		d.attempt(ctx, &delivery, subscription)
+		if err = d.repository.UpdateDelivery(ctx, delivery); err != nil {
+			return i, err
+		}
+	}
+	return len(deliveries), nil
+}
+
+func (d *Deliverer) attempt(ctx context.Context, delivery *models.WebhookDelivery, subscription models.WebhookSubscription) {
+	statusCode, err := d.send(ctx, *delivery, subscription)
+	now := d.now()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	attrs := []any{slog.Int64("delivery_id", delivery.ID), slog.Int("subscription_id", subscription.ID),
+		slog.String("event_type", delivery.EventType), slog.Int("attempt", delivery.Attempts)}
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= d.options.MaxAttempts:
+		delivery.Status = models.DeliveryStatusDead
+		delivery.LastError = truncate(err.Error())
+		d.logger.WarnContext(ctx, "webhook delivery is dead", append(attrs, slog.Any("error", err))...)
+	default:
+		delay := retryDelay(d.options.RetryDelay, delivery.Attempts-1)
+		delivery.NextAttemptAt = now.Add(delay)
+		delivery.LastError = truncate(err.Error())
+		d.logger.InfoContext(ctx, "webhook delivery failed",
+			append(attrs, slog.Duration("retry_in", delay), slog.Any("error", err))...)
+	}
+}
+
+func (d *Deliverer) send(
+	ctx context.Context, delivery models.WebhookDelivery, subscription models.WebhookSubscription) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "car-rental-webhooks")
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Event-ID", strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set("X-Event-Type", delivery.EventType)
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, d.now(), body))
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("responded %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// retryDelay doubles the first delay with every failed attempt, up to six hours.
+func retryDelay(first time.Duration, attempts int) time.Duration {
+	delay := first
+	for i := 0; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+func truncate(message string) string {
+	if len(message) <= maxErrorLength {
+		return message
+	}
+	return message[:maxErrorLength]
+}
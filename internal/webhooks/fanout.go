@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"car-rental/internal/events"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+)
+
+// Fanout is the sink queuing a delivery of every event for each subscription to its type. Published by
+// the outbox dispatcher, the deliveries are stored in the transaction that marks the event published.
+type Fanout struct {
+	repository repository.WebhookRepository
+}
+
+func NewFanout(repository repository.WebhookRepository) *Fanout {
+	return &Fanout{repository: repository}
+}
+
+func (f *Fanout) Name() string {
+	return "webhooks"
+}
+
+func (f *Fanout) Publish(ctx context.Context, event events.Event) error {
+	subscriptions, err := f.repository.SubscriptionsForEvent(ctx, event.Type)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	// rental events name their client, subscriptions of one client skip the other events
+	var subject struct {
+		ClientID string `json:"client_id"`
+	}
+	_ = json.Unmarshal(event.Data, &subject)
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if subscription.ClientID != "" && subscription.ClientID != subject.ClientID {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(body),
+			Status:         models.DeliveryStatusPending,
+			NextAttemptAt:  now,
+		})
+	}
+	return f.repository.AddDeliveries(ctx, deliveries)
+}
@@ -0,0 +1,201 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"car-rental/internal/events"
+	"car-rental/internal/logging"
+	"car-rental/internal/models"
+	"car-rental/internal/repository"
+	"gorm.io/gorm"
+)
+
+// memoryWebhooks keeps subscriptions and deliveries in memory.
+type memoryWebhooks struct {
+	repository.WebhookRepository
+	subscriptions []models.WebhookSubscription
+	deliveries    []models.WebhookDelivery
+}
+
+func (m *memoryWebhooks) GetSubscription(ctx context.Context, id int) (models.WebhookSubscription, error) {
+	for _, subscription := range m.subscriptions {
+		if subscription.ID == id {
+			return subscription, nil
+		}
+	}
+	return models.WebhookSubscription{}, gorm.ErrRecordNotFound
+}
+
+func (m *memoryWebhooks) SubscriptionsForEvent(ctx context.Context, eventType string) (
+	[]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	for _, subscription := range m.subscriptions {
+		for _, subscribed := range subscription.EventTypes {
+			if subscribed == eventType {
+				subscriptions = append(subscriptions, subscription)
+			}
+		}
+	}
+	return subscriptions, nil
+}
+
+func (m *memoryWebhooks) AddDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		delivery.ID = int64(len(m.deliveries) + 1)
+		m.deliveries = append(m.deliveries, delivery)
+	}
+	return nil
+}
+
+func (m *memoryWebhooks) ClaimDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) (
+	[]models.WebhookDelivery, error) {
+	var due []models.WebhookDelivery
+	for i, delivery := range m.deliveries {
+		if delivery.Status == models.DeliveryStatusPending && !delivery.NextAttemptAt.After(now) && len(due) < limit {
+			m.deliveries[i].NextAttemptAt = leaseUntil
+			due = append(due, m.deliveries[i])
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryWebhooks) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	m.deliveries[delivery.ID-1] = delivery
+	return nil
+}
+
+func TestSignature(t *testing.T) {
+	now := time.Date(2023, 10, 15, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"type":"rental.started"}`)
+	header := Sign("whsec_test", now, body)
+	if err := Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Errorf("want valid signature, got %v", err)
+	}
+	for name, err := range map[string]error{
+		"other secret": Verify("whsec_other", header, body, 5*time.Minute, now),
+		"changed body": Verify("whsec_test", header, []byte(`{"type":"rental.closed"}`), 5*time.Minute, now),
+		"replayed":     Verify("whsec_test", header, body, 5*time.Minute, now.Add(time.Hour)),
+		"no timestamp": Verify("whsec_test", "v1=00", body, 5*time.Minute, now),
+	} {
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: want ErrInvalidSignature, got %v", name, err)
+		}
+	}
+}
+
+func TestFanout(t *testing.T) {
+	webhooks := &memoryWebhooks{subscriptions: []models.WebhookSubscription{
+		{ID: 1, EventTypes: []string{events.TypeRentalStarted}},
+		{ID: 2, EventTypes: []string{events.TypeRentalStarted}, ClientID: "client-2"},
+		{ID: 3, EventTypes: []string{events.TypeRentalClosed}},
+	}}
+	record, err := events.NewOutboxEvent(events.RentalStarted{RentID: 7, ClientID: "client-1"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.ID = 5
+	if err = NewFanout(webhooks).Publish(context.Background(), events.FromOutbox(record)); err != nil {
+		t.Fatal(err)
+	}
+	// the subscription of client-2 and the one to closed rentals skip the event
+	if len(webhooks.deliveries) != 1 || webhooks.deliveries[0].SubscriptionID != 1 ||
+		webhooks.deliveries[0].EventID != 5 || webhooks.deliveries[0].Status != models.DeliveryStatusPending {
+		t.Errorf("want one pending delivery to subscription 1, got %+v", webhooks.deliveries)
+	}
+}
+
+func TestDeliverDue(t *testing.T) {
+	now := time.Date(2023, 10, 15, 12, 0, 0, 0, time.UTC)
+	failures := 1
+	var verified []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify("whsec_test", r.Header.Get(SignatureHeader), body, 5*time.Minute, now); err != nil {
+			t.Errorf("want verifiable signature, got %v", err)
+		}
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		verified = append(verified, r.Header.Get("X-Event-Type"))
+	}))
+	defer receiver.Close()
+	webhooks := &memoryWebhooks{
+		subscriptions: []models.WebhookSubscription{{ID: 1, URL: receiver.URL, Secret: "whsec_test"}},
+		deliveries: []models.WebhookDelivery{{ID: 1, SubscriptionID: 1, EventID: 5,
+			EventType: events.TypeRentalStarted, Payload: `{"id":5}`, Status: models.DeliveryStatusPending,
+			NextAttemptAt: now}},
+	}
+	deliverer := NewDeliverer(webhooks, DelivererOptions{BatchSize: 10, MaxAttempts: 3, RetryDelay: time.Minute,
+		Timeout: time.Second}, logging.Discard())
+	deliverer.now = func() time.Time { return now }
+
+	if _, err := deliverer.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	delivery := webhooks.deliveries[0]
+	if delivery.Status != models.DeliveryStatusPending || delivery.Attempts != 1 || delivery.LastStatusCode != 503 ||
+		!delivery.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("want a retry in a minute after the 503, got %+v", delivery)
+	}
+	// not due yet
+	if handled, _ := deliverer.DeliverDue(context.Background()); handled != 0 {
+		t.Fatalf("want nothing delivered before the retry is due, got %d", handled)
+	}
+	now = now.Add(time.Minute)
+	if _, err := deliverer.DeliverDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	delivery = webhooks.deliveries[0]
+	if delivery.Status != models.DeliveryStatusDelivered || delivery.Attempts != 2 || delivery.DeliveredAt == nil ||
+		len(verified) != 1 || verified[0] != events.TypeRentalStarted {
+		t.Errorf("want delivered on the second attempt, got %+v", delivery)
+	}
+}
+
+func TestDeliverDueDeadLetters(t *testing.T) {
+	now := time.Date(2023, 10, 15, 12, 0, 0, 0, time.UTC)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+	webhooks := &memoryWebhooks{
+		subscriptions: []models.WebhookSubscription{{ID: 1, URL: receiver.URL, Secret: "whsec_test"}},
+		deliveries: []models.WebhookDelivery{{ID: 1, SubscriptionID: 1, EventID: 5, Payload: `{}`,
+			Status: models.DeliveryStatusPending, NextAttemptAt: now}},
+	}
+	deliverer := NewDeliverer(webhooks, DelivererOptions{BatchSize: 10, MaxAttempts: 3, RetryDelay: time.Minute,
+		Timeout: time.Second}, logging.Discard())
+	deliverer.now = func() time.Time { return now }
+	for i := 0; i < 5; i++ {
+		if _, err := deliverer.DeliverDue(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Hour)
+	}
+	delivery := webhooks.deliveries[0]
+	if delivery.Status != models.DeliveryStatusDead || delivery.Attempts != 3 || delivery.LastStatusCode != 500 ||
+		delivery.LastError == "" {
+		t.Errorf("want dead after 3 attempts, got %+v", delivery)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		0:  30 * time.Second,
+		1:  time.Minute,
+		4:  8 * time.Minute,
+		20: maxRetryDelay,
+	} {
+		if got := retryDelay(30*time.Second, attempts); got != want {
+			t.Errorf("retryDelay after %d attempts: want %s, got %s", attempts, want, got)
+		}
+	}
+}